@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/middleware"
+)
+
+// attachSubprotocol 伺服器回應的子協定；用戶端以 ["tty", "bearer.<token>"] 連線時選用此項。
+const attachSubprotocol = "tty"
+
+// 子協定由 attachProtocolHeader 決定，Upgrader 不設定 Subprotocols
+var upgrader = websocket.Upgrader{
+	// 驗證由 JWT 負責，與 CORS 設定一致不限制來源
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// attachProtocolHeader 選擇回應的子協定：有 tty 時選用 tty，只帶 bearer.<token> 時原樣回應，
+// 否則瀏覽器會因伺服器未選用任何子協定而中止握手。
+func attachProtocolHeader(r *http.Request) http.Header {
+	var bearer string
+	for _, p := range websocket.Subprotocols(r) {
+		if p == attachSubprotocol {
+			return http.Header{"Sec-Websocket-Protocol": {p}}
+		}
+		if bearer == "" && strings.HasPrefix(p, middleware.WebSocketTokenProtocolPrefix) {
+			bearer = p
+		}
+	}
+	if bearer == "" {
+		return nil
+	}
+	return http.Header{"Sec-Websocket-Protocol": {bearer}}
+}
+
+// attachControl 用戶端以文字訊息送出的控制指令。
+type attachControl struct {
+	Type string `json:"type"` // resize|stdin
+	Cols uint   `json:"cols"`
+	Rows uint   `json:"rows"`
+	Data string `json:"data"`
+}
+
+// AttachContainer 將連線升級為 WebSocket，並橋接至容器內的 TTY exec。
+//
+//   - 二進位訊息：原樣寫入 stdin
+//   - 文字訊息：JSON 控制指令，{"type":"resize","cols":80,"rows":24} 或 {"type":"stdin","data":"ls\n"}
+//   - 伺服器以二進位訊息送出終端輸出；程序結束時以 normal closure 關閉連線
+//
+// 超過 ATTACH_IDLE_TIMEOUT（預設 10m）雙向皆無資料時自動斷線。
+func AttachContainer(c *gin.Context) {
+	id := c.Param("id")
+	opts := containers.AttachOptions{Cmd: c.QueryArray("cmd")}
+	if v, err := strconv.ParseUint(c.Query("cols"), 10, 32); err == nil {
+		opts.Cols = uint(v)
+	}
+	if v, err := strconv.ParseUint(c.Query("rows"), 10, 32); err == nil {
+		opts.Rows = uint(v)
+	}
+	// 先建立 session，錯誤時仍可回傳一般的 HTTP 狀態碼
+	sess, err := Svc.Attach(c.Request.Context(), id, opts)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer sess.Close()
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, attachProtocolHeader(c.Request))
+	if err != nil {
+		// Upgrade 失敗時已回應錯誤
+		return
+	}
+	defer conn.Close()
+
+	var writeMu sync.Mutex
+	closeWith := func(code int, text string) {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(time.Second))
+		_ = conn.Close()
+		_ = sess.Close()
+	}
+	timeout := attachIdleTimeout()
+	idle := time.AfterFunc(timeout, func() { closeWith(websocket.CloseGoingAway, "idle timeout") })
+	defer idle.Stop()
+	touch := func() { idle.Reset(timeout) }
+
+	// 終端輸出 -> WebSocket
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := sess.Read(buf)
+			if n > 0 {
+				touch()
+				writeMu.Lock()
+				werr := conn.WriteMessage(websocket.BinaryMessage, buf[:n])
+				writeMu.Unlock()
+				if werr != nil {
+					_ = sess.Close()
+					return
+				}
+			}
+			if err != nil {
+				closeWith(websocket.CloseNormalClosure, "process exited")
+				return
+			}
+		}
+	}()
+
+	// WebSocket -> stdin / 控制指令
+	for {
+		mt, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		touch()
+		switch mt {
+		case websocket.BinaryMessage:
+			if _, err := sess.Write(data); err != nil {
+				return
+			}
+		case websocket.TextMessage:
+			var ctl attachControl
+			if err := json.Unmarshal(data, &ctl); err != nil {
+				continue
+			}
+			switch ctl.Type {
+			case "resize":
+				if ctl.Cols > 0 && ctl.Rows > 0 {
+					_ = sess.Resize(ctl.Cols, ctl.Rows)
+				}
+			case "stdin":
+				if _, err := sess.Write([]byte(ctl.Data)); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+func attachIdleTimeout() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("ATTACH_IDLE_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return 10 * time.Minute
+}
@@ -1,30 +1,48 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"container-manager/internal/containers"
+	"container-manager/internal/middleware"
 	"container-manager/internal/storage"
 )
 
 var Svc = containers.NewService()
 
 type createContainerDTO struct {
-	Name         string            `json:"name" binding:"omitempty"`
-	Image        string            `json:"image" binding:"required"`
-	Mounts       map[string]string `json:"mounts" binding:"omitempty"`       // hostDir -> containerDir
-	ContainerDir string            `json:"containerDir" binding:"omitempty"` // 簡化：單一掛載點時使用
+	Name         string                     `json:"name" binding:"omitempty"`
+	Image        string                     `json:"image"`                            // 與 build 擇一
+	Mounts       map[string]string          `json:"mounts" binding:"omitempty"`       // hostDir -> containerDir
+	ContainerDir string                     `json:"containerDir" binding:"omitempty"` // 簡化：單一掛載點時使用
+	Resources    containers.ResourceLimits  `json:"resources"`
+	Security     containers.SecurityOptions `json:"security"`
+	Env          map[string]string          `json:"env"`
+	Entrypoint   []string                   `json:"entrypoint"`
+	Cmd          []string                   `json:"cmd"` // 與 entrypoint 皆省略時容器維持常駐
+	WorkingDir   string                     `json:"workingDir"`
+	Labels       map[string]string          `json:"labels"`
+	Hostname     string                     `json:"hostname"`
+	Ports        []containers.PortMapping   `json:"ports"`
+	Network      containers.NetworkOptions  `json:"network"`
+	Build        *buildDTO                  `json:"build"` // 先以上傳資料夾的 Dockerfile 建置映像
 }
 
 func CreateContainer(c *gin.Context) {
 	var dto createContainerDTO
 	if err := c.ShouldBindJSON(&dto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, invalidArgument(err))
 		return
 	}
 	// Convert container paths to host paths for mounts (similar to RunJob)
@@ -34,7 +52,7 @@ func CreateContainer(c *gin.Context) {
 		if !filepath.IsAbs(hostDir) {
 			abs, err := filepath.Abs(hostDir)
 			if err != nil {
-				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hostDir in mounts"})
+				respondError(c, invalidArgument(errors.New("invalid hostDir in mounts")))
 				return
 			}
 			hostDir = abs
@@ -61,27 +79,133 @@ func CreateContainer(c *gin.Context) {
 		}
 		convertedMounts[hostDir] = containerDir
 	}
+	build, err := dto.Build.createOptions(dto.Image)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
 	opts := containers.CreateOptions{
-		Name:   dto.Name,
-		Image:  dto.Image,
-		Mounts: convertedMounts,
+		Name:       dto.Name,
+		Image:      dto.Image,
+		Mounts:     convertedMounts,
+		Resources:  dto.Resources,
+		Security:   dto.Security,
+		Env:        dto.Env,
+		Entrypoint: dto.Entrypoint,
+		Cmd:        dto.Cmd,
+		WorkingDir: dto.WorkingDir,
+		Labels:     dto.Labels,
+		Hostname:   dto.Hostname,
+		Ports:      dto.Ports,
+		Network:    dto.Network,
+		Build:      build,
 	}
-	res, err := Svc.Create(opts)
+	if build != nil {
+		opts.Image = build.Tag
+	}
+	res, err := Svc.Create(c.Request.Context(), opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, res)
 }
 
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+var errInvalidLimit = invalidArgument(fmt.Errorf("limit must be between 1 and %d", maxPageLimit))
+
+// ListContainers 依 status/image/name 篩選容器，並以 limit/offset 分頁。
+func ListContainers(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultPageLimit)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		respondError(c, errInvalidLimit)
+		return
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		respondError(c, invalidArgument(errors.New("offset must be a non-negative integer")))
+		return
+	}
+	filter := containers.ListFilter{
+		Status: c.Query("status"),
+		Image:  c.Query("image"),
+		Name:   c.Query("name"),
+	}
+	items, total, err := Svc.List(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "limit": limit, "offset": offset})
+}
+
+// ContainerHistory 從資料庫列出容器紀錄（含已刪除），以游標分頁。
+func ContainerHistory(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultPageLimit)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		respondError(c, errInvalidLimit)
+		return
+	}
+	filter := storage.ContainerFilter{
+		Status:     c.Query("status"),
+		Image:      c.Query("image"),
+		NamePrefix: c.Query("name"),
+		Cursor:     c.Query("cursor"),
+		Limit:      limit,
+	}
+	switch c.DefaultQuery("order", "desc") {
+	case "asc":
+		filter.Ascending = true
+	case "desc":
+	default:
+		respondError(c, invalidArgument(errors.New("order must be asc or desc")))
+		return
+	}
+	for key, dst := range map[string]*int64{"createdAfter": &filter.CreatedAfter, "createdBefore": &filter.CreatedBefore} {
+		if v := c.Query(key); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				respondError(c, invalidArgument(errors.New(key+" must be a unix timestamp")))
+				return
+			}
+			*dst = n
+		}
+	}
+	items, next, total, err := Svc.History(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "nextCursor": next})
+}
+
+func GetContainer(c *gin.Context) {
+	id := c.Param("id")
+	res, err := Svc.Inspect(c.Request.Context(), id)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// queryInt 讀取整數查詢參數，未提供時回傳預設值。
+func queryInt(c *gin.Context, key string, def int) (int, error) {
+	v := c.Query(key)
+	if v == "" {
+		return def, nil
+	}
+	return strconv.Atoi(v)
+}
+
 func StartContainer(c *gin.Context) {
 	id := c.Param("id")
-	if err := Svc.Start(id); err != nil {
-		status := http.StatusInternalServerError
-		if err == containers.ErrNotFound {
-			status = http.StatusNotFound
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+	if err := Svc.Start(c.Request.Context(), id); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -89,12 +213,8 @@ func StartContainer(c *gin.Context) {
 
 func StopContainer(c *gin.Context) {
 	id := c.Param("id")
-	if err := Svc.Stop(id); err != nil {
-		status := http.StatusInternalServerError
-		if err == containers.ErrNotFound {
-			status = http.StatusNotFound
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+	if err := Svc.Stop(c.Request.Context(), id); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -102,12 +222,8 @@ func StopContainer(c *gin.Context) {
 
 func DeleteContainer(c *gin.Context) {
 	id := c.Param("id")
-	if err := Svc.Delete(id); err != nil {
-		status := http.StatusInternalServerError
-		if err == containers.ErrNotFound {
-			status = http.StatusNotFound
-		}
-		c.JSON(status, gin.H{"error": err.Error()})
+	if err := Svc.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
@@ -115,28 +231,81 @@ func DeleteContainer(c *gin.Context) {
 
 // ---- Job API ----
 type runJobDTO struct {
-	Image        string   `json:"image"`                           // 可省略，將自動偵測
-	HostDir      string   `json:"hostDir" binding:"required"`      // 來自 /v1/uploads 回傳的 dir
-	ContainerDir string   `json:"containerDir" binding:"required"` // 例如 /workspace
-	Cmd          []string `json:"cmd"`                             // 可省略，將自動偵測
+	Image        string                     `json:"image"`                           // 可省略，將自動偵測
+	HostDir      string                     `json:"hostDir" binding:"required"`      // 來自 /v1/uploads 回傳的 dir
+	ContainerDir string                     `json:"containerDir" binding:"required"` // 例如 /workspace
+	Cmd          []string                   `json:"cmd"`                             // 可省略，將自動偵測
+	Env          map[string]string          `json:"env"`
+	Network      string                     `json:"network"` // 預設 none
+	Resources    containers.ResourceLimits  `json:"resources"`
+	Security     containers.SecurityOptions `json:"security"`
+	Timeout      int                        `json:"timeoutSeconds" binding:"min=0"`     // 0 使用伺服器預設值，不可超過 JOB_MAX_TIMEOUT
+	GracePeriod  int                        `json:"gracePeriodSeconds" binding:"min=0"` // 逾時後到強制終止的秒數
+	Retry        *retryDTO                  `json:"retry"`
+	Build        *buildDTO                  `json:"build"` // 先建置映像，與 image 擇一；偵測到 Dockerfile 時自動建置
 }
 
-func RunJob(c *gin.Context) {
-	var dto runJobDTO
-	if err := c.ShouldBindJSON(&dto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+// jobRequestDTO POST /v1/jobs 的請求；帶 matrix 時展開為多個排隊的子作業。
+type jobRequestDTO struct {
+	runJobDTO
+	Matrix      map[string][]json.RawMessage `json:"matrix"`                      // 軸名稱 -> 字串、數字或布林值
+	MaxParallel int                          `json:"maxParallel" binding:"min=0"` // 同時執行的子作業數，0 代表不限制
+}
+
+// retryDTO 作業失敗時的重試設定，時間以秒為單位。
+type retryDTO struct {
+	MaxAttempts    int     `json:"maxAttempts" binding:"min=0"`
+	InitialBackoff float64 `json:"initialBackoffSeconds" binding:"min=0"`
+	MaxBackoff     float64 `json:"maxBackoffSeconds" binding:"min=0"`
+	Multiplier     float64 `json:"multiplier"`
+	ExitCodes      []int64 `json:"exitCodes"`
+	OOMKilled      bool    `json:"oomKilled"`
+	ProviderError  bool    `json:"providerError"`
+}
+
+func (r *retryDTO) policy() *containers.RetryPolicy {
+	if r == nil {
+		return nil
+	}
+	return &containers.RetryPolicy{
+		MaxAttempts:    r.MaxAttempts,
+		InitialBackoff: time.Duration(r.InitialBackoff * float64(time.Second)),
+		MaxBackoff:     time.Duration(r.MaxBackoff * float64(time.Second)),
+		Multiplier:     r.Multiplier,
+		ExitCodes:      r.ExitCodes,
+		OOMKilled:      r.OOMKilled,
+		ProviderError:  r.ProviderError,
+	}
+}
+
+func newRetryDTO(p *containers.RetryPolicy) *retryDTO {
+	if p == nil {
+		return nil
+	}
+	return &retryDTO{
+		MaxAttempts:    p.MaxAttempts,
+		InitialBackoff: p.InitialBackoff.Seconds(),
+		MaxBackoff:     p.MaxBackoff.Seconds(),
+		Multiplier:     p.Multiplier,
+		ExitCodes:      p.ExitCodes,
+		OOMKilled:      p.OOMKilled,
+		ProviderError:  p.ProviderError,
 	}
+}
+
+// jobOptions 將請求轉為 JobOptions：hostDir 轉為宿主機路徑，未指定的映像與指令自動偵測。
+func (dto runJobDTO) jobOptions() (containers.JobOptions, error) {
 	// Ensure absolute host path for Docker bind mount
 	hostDir := dto.HostDir
 	if !filepath.IsAbs(hostDir) {
 		abs, err := filepath.Abs(hostDir)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid hostDir"})
-			return
+			return containers.JobOptions{}, invalidArgument(errors.New("invalid hostDir"))
 		}
 		hostDir = abs
 	}
+	// 偵測與建置讀取本服務看到的路徑
+	localDir := hostDir
 	// 若服務在容器中運行，hostDir 目前是容器內路徑，需轉換為宿主機路徑讓 Docker 進行 bind mount。
 	// 使用 DATA_DIR 與 HOST_DATA_DIR 的對應做轉換。
 	dataDir := os.Getenv("DATA_DIR")
@@ -147,8 +316,7 @@ func RunJob(c *gin.Context) {
 	hostDataDir := os.Getenv("HOST_DATA_DIR")
 	if hostDataDir == "" {
 		// 明確要求透過環境變數提供宿主機絕對路徑，避免在容器內推測失敗
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "HOST_DATA_DIR not set"})
-		return
+		return containers.JobOptions{}, errors.New("HOST_DATA_DIR not set")
 	}
 	if strings.HasPrefix(hostDir, absDataDir) {
 		rel := strings.TrimPrefix(hostDir, absDataDir)
@@ -168,22 +336,86 @@ func RunJob(c *gin.Context) {
 
 	image := strings.TrimSpace(dto.Image)
 	cmd := dto.Cmd
-	if len(cmd) == 0 || image == "" {
-		autoImg, autoCmd := detectProgram(hostDir, dto.ContainerDir)
-		if image == "" {
-			image = autoImg
+	build, err := dto.Build.jobOptions(image, localDir)
+	if err != nil {
+		return containers.JobOptions{}, err
+	}
+	if build == nil && (len(cmd) == 0 || image == "") {
+		m := Detectors.Detect(localDir, dto.ContainerDir)
+		if image == "" && m.Build {
+			if build, err = (&buildDTO{Dockerfile: m.File}).jobOptions("", localDir); err != nil {
+				return containers.JobOptions{}, err
+			}
+		} else if image == "" {
+			image = m.Image
 		}
 		if len(cmd) == 0 {
-			cmd = autoCmd
+			cmd = m.Cmd
 		}
 	}
+	if build != nil {
+		image = build.Tag
+	}
+
+	return containers.JobOptions{
+		Image:        image,
+		HostDir:      hostDir,
+		ContainerDir: dto.ContainerDir,
+		Cmd:          cmd,
+		Env:          dto.Env,
+		Network:      dto.Network,
+		Resources:    dto.Resources,
+		Security:     dto.Security,
+		Timeout:      time.Duration(dto.Timeout) * time.Second,
+		GracePeriod:  time.Duration(dto.GracePeriod) * time.Second,
+		Retry:        dto.Retry.policy(),
+		Build:        build,
+	}, nil
+}
 
-	code, logs, err := Svc.RunJob(containers.JobOptions{Image: image, HostDir: hostDir, ContainerDir: dto.ContainerDir, Cmd: cmd})
+func RunJob(c *gin.Context) {
+	var dto jobRequestDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		respondError(c, invalidArgument(err))
+		return
+	}
+	opts, err := dto.jobOptions()
 	if err != nil {
-		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"exitCode": code, "logs": logs})
+	if dto.Matrix != nil {
+		runMatrix(c, dto, opts)
+		return
+	}
+	rec := storage.JobRecord{
+		Submitter:    middleware.Subject(c),
+		Image:        opts.Image,
+		Cmd:          opts.Cmd,
+		HostDir:      opts.HostDir,
+		ContainerDir: opts.ContainerDir,
+	}
+	ctx := c.Request.Context()
+	if c.Query("async") == "true" {
+		// 排隊前先驗證參數與上限，錯誤立即回報而不是留下失敗的紀錄
+		if err := Svc.PrepareJob(&opts); err != nil {
+			respondError(c, err)
+			return
+		}
+		id, err := Jobs.Submit(ctx, rec, opts)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"id": id, "status": storage.JobPending})
+		return
+	}
+	id, res, err := Jobs.Run(ctx, rec, opts)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"id": id, "exitCode": res.ExitCode, "logs": res.Logs, "status": res.Status})
 }
 
 // ---- Exec in container ----
@@ -195,51 +427,40 @@ func ExecInContainer(c *gin.Context) {
 	id := c.Param("id")
 	var dto execDTO
 	if err := c.ShouldBindJSON(&dto); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, invalidArgument(err))
+		return
+	}
+	if c.Query("async") == "true" {
+		taskID, err := Tasks.Submit(c.Request.Context(), id, dto.Cmd)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"taskId": taskID, "status": storage.TaskPending})
 		return
 	}
 	// record task
 	db, _ := storage.OpenDefault()
 	_ = storage.Migrate(db)
 	taskRepo := storage.NewTaskRepository(db)
-	taskID, _ := taskRepo.Insert(id, dto.Cmd)
-	// run
-	code, logs, err := Svc.Exec(id, dto.Cmd)
+	ctx := c.Request.Context()
+	taskID, _ := taskRepo.Insert(ctx, id, dto.Cmd)
+	// run；用戶端斷線時 ctx 取消，exec 隨之中止
+	res, err := Svc.Exec(ctx, id, dto.Cmd)
 	status := storage.TaskSucceeded
-	if err != nil || code != 0 {
+	if err != nil || res.ExitCode != 0 {
 		status = storage.TaskFailed
 	}
-	_ = taskRepo.UpdateResult(taskID, status, code, logs)
+	_ = taskRepo.UpdateResult(context.WithoutCancel(ctx), taskID, status, res.ExitCode, res.Stdout, res.Stderr)
+	// logs 為 stdout 與 stderr 的合併內容，保留給既有用戶端
+	body := gin.H{"exitCode": res.ExitCode, "stdout": res.Stdout, "stderr": res.Stderr, "truncated": res.Truncated, "logs": res.Stdout + res.Stderr}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "exitCode": code, "logs": logs})
+		// 保留已取得的輸出，並附上與其他錯誤相同的欄位
+		e := newErrorBody(c, err)
+		body["code"], body["message"], body["requestId"] = e.Code, e.Message, e.RequestID
+		c.JSON(errorStatus[e.Code], body)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"exitCode": code, "logs": logs, "taskId": taskID})
-}
-
-// detectProgram 依序檢查可執行檔與常見腳本，回傳對應的映像與命令。
-// 優先序：binary(app 可執行) > run.sh > app.py > app.go
-func detectProgram(hostDir, containerDir string) (string, []string) {
-	// binary
-	appPath := filepath.Join(hostDir, "app")
-	if fi, err := os.Stat(appPath); err == nil && fi.Mode()&0o111 != 0 {
-		return "alpine:3.20", []string{"sh", "-c", "chmod +x " + filepath.Join(containerDir, "app") + " && " + filepath.Join(containerDir, "app")}
-	}
-	// run.sh
-	shPath := filepath.Join(hostDir, "run.sh")
-	if _, err := os.Stat(shPath); err == nil {
-		return "alpine:3.20", []string{"sh", "-c", "chmod +x " + filepath.Join(containerDir, "run.sh") + " && " + filepath.Join(containerDir, "run.sh")}
-	}
-	// app.py
-	pyPath := filepath.Join(hostDir, "app.py")
-	if _, err := os.Stat(pyPath); err == nil {
-		return "python:3.11-slim", []string{"python", filepath.Join(containerDir, "app.py")}
-	}
-	// app.go
-	goPath := filepath.Join(hostDir, "app.go")
-	if _, err := os.Stat(goPath); err == nil {
-		return "golang:1.21-alpine", []string{"sh", "-c", "cd " + containerDir + " && go run app.go"}
-	}
-	// fallback: 列目錄
-	return "alpine:3.20", []string{"sh", "-c", "ls -la " + containerDir}
+	body["taskId"] = taskID
+	c.JSON(http.StatusOK, body)
 }
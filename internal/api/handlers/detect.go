@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/detect"
+)
+
+// Detectors 未指定 image 或 cmd 的作業用來推斷要執行的內容；server.Run 依 DETECTORS_FILE 載入設定檔。
+var Detectors = detect.Default()
+
+type detectView struct {
+	Image        string                    `json:"image"`
+	Cmd          []string                  `json:"cmd"`
+	HostDir      string                    `json:"hostDir"`
+	ContainerDir string                    `json:"containerDir"`
+	Network      string                    `json:"network"`
+	Timeout      int64                     `json:"timeoutSeconds"`
+	Resources    containers.ResourceLimits `json:"resources"`
+	// Build 執行前建置映像的參數；image 為建置的 tag
+	Build *containers.BuildOptions `json:"build,omitempty"`
+	// Detector 決定 image 或 cmd 的規則；兩者皆由請求指定時為空
+	Detector *detect.Match `json:"detector,omitempty"`
+	// Candidates 資料夾中所有符合的規則，依優先序
+	Candidates []detect.Match `json:"candidates"`
+}
+
+// DetectJob 以與 POST /v1/jobs 相同的方式解析請求並套用上限，回傳將執行的映像與命令，不建立作業。
+func DetectJob(c *gin.Context) {
+	var dto runJobDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		respondError(c, invalidArgument(err))
+		return
+	}
+	opts, err := dto.jobOptions()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if err := Svc.PrepareJob(&opts); err != nil {
+		respondError(c, err)
+		return
+	}
+	// 與 jobOptions 相同，偵測讀取本服務看到的路徑而非轉換後的宿主機路徑
+	localDir, _ := filepath.Abs(dto.HostDir)
+	v := detectView{
+		Image:        opts.Image,
+		Cmd:          opts.Cmd,
+		HostDir:      opts.HostDir,
+		ContainerDir: opts.ContainerDir,
+		Network:      opts.Network,
+		Timeout:      int64(opts.Timeout.Seconds()),
+		Resources:    opts.Resources,
+		Build:        opts.Build,
+		Candidates:   Detectors.Candidates(localDir, opts.ContainerDir),
+	}
+	if v.Network == "" {
+		v.Network = "none"
+	}
+	if v.Candidates == nil {
+		v.Candidates = []detect.Match{}
+	}
+	if dto.Build == nil && (strings.TrimSpace(dto.Image) == "" || len(dto.Cmd) == 0) {
+		m := Detectors.Detect(localDir, opts.ContainerDir)
+		v.Detector = &m
+	}
+	c.JSON(http.StatusOK, v)
+}
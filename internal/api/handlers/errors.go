@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/middleware"
+	"container-manager/internal/storage"
+	"container-manager/internal/tasks"
+)
+
+// 只在 API 層出現的錯誤分類。
+const (
+	codeUnavailable     containers.ErrorCode = "unavailable"
+	codeUnauthenticated containers.ErrorCode = "unauthenticated"
+)
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// errorStatus 各分類對應的 HTTP 狀態碼。
+var errorStatus = map[containers.ErrorCode]int{
+	containers.CodeNotFound:        http.StatusNotFound,
+	containers.CodeConflict:        http.StatusConflict,
+	containers.CodeInvalidArgument: http.StatusBadRequest,
+	containers.CodeUnsupported:     http.StatusNotImplemented,
+	containers.CodeImagePullFailed: http.StatusBadGateway,
+	containers.CodeImageNotFound:   http.StatusNotFound,
+	containers.CodeBuildFailed:     http.StatusUnprocessableEntity,
+	containers.CodeTimeout:         http.StatusGatewayTimeout,
+	containers.CodeQuotaExceeded:   http.StatusUnprocessableEntity,
+	containers.CodeInternal:        http.StatusInternalServerError,
+	codeUnavailable:                http.StatusServiceUnavailable,
+	codeUnauthenticated:            http.StatusUnauthorized,
+}
+
+// errorBody 所有 API 錯誤回應的格式。
+type errorBody struct {
+	Code      containers.ErrorCode `json:"code"`
+	Message   string               `json:"message"`
+	RequestID string               `json:"requestId"`
+}
+
+// errorCode 分類錯誤：先處理其他套件的 sentinel，其餘交給 containers.CodeOf。
+func errorCode(err error) containers.ErrorCode {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return containers.CodeNotFound
+	case errors.Is(err, storage.ErrInvalidCursor):
+		return containers.CodeInvalidArgument
+	case errors.Is(err, tasks.ErrQueueFull):
+		return codeUnavailable
+	case errors.Is(err, tasks.ErrFinished):
+		return containers.CodeConflict
+	case errors.Is(err, errInvalidCredentials):
+		return codeUnauthenticated
+	}
+	return containers.CodeOf(err)
+}
+
+func newErrorBody(c *gin.Context, err error) errorBody {
+	return errorBody{Code: errorCode(err), Message: err.Error(), RequestID: middleware.RequestIDFrom(c)}
+}
+
+// respondError 將錯誤對應到 HTTP 狀態碼並輸出 errorBody。
+func respondError(c *gin.Context, err error) {
+	body := newErrorBody(c, err)
+	c.AbortWithStatusJSON(errorStatus[body.Code], body)
+}
+
+// invalidArgument 將請求格式錯誤（綁定、查詢參數等）標記為 invalid_argument。
+func invalidArgument(err error) error {
+	return &containers.Error{Kind: containers.ErrInvalidOptions, Err: err}
+}
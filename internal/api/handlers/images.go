@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"container-manager/internal/containers"
+)
+
+// buildDTO 以上傳資料夾中的 Dockerfile 建置映像；映像名稱依資料夾產生（container-manager/<userId>:<batch>）。
+type buildDTO struct {
+	Dir        string            `json:"dir"`        // 來自 /v1/uploads 回傳的 dir；作業省略時使用 hostDir
+	Dockerfile string            `json:"dockerfile"` // 相對於 dir，預設 Dockerfile
+	BuildArgs  map[string]string `json:"buildArgs"`
+	Target     string            `json:"target"`
+	NoCache    bool              `json:"noCache"`
+	CacheFrom  []string          `json:"cacheFrom"`
+	Pull       bool              `json:"pull"`
+}
+
+// options 將請求轉為 BuildOptions；dir 須為 DATA_DIR 下的資料夾且包含 Dockerfile。
+func (b *buildDTO) options(dir string) (containers.BuildOptions, error) {
+	ctxDir, err := uploadDir(dir)
+	if err != nil {
+		return containers.BuildOptions{}, err
+	}
+	opts := containers.BuildOptions{
+		ContextDir: ctxDir,
+		Dockerfile: b.Dockerfile,
+		BuildArgs:  b.BuildArgs,
+		Target:     b.Target,
+		NoCache:    b.NoCache,
+		CacheFrom:  b.CacheFrom,
+		Pull:       b.Pull,
+	}
+	if err := opts.Validate(); err != nil {
+		return containers.BuildOptions{}, err
+	}
+	dockerfile := opts.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	if fi, err := os.Stat(filepath.Join(ctxDir, dockerfile)); err != nil || fi.IsDir() {
+		return containers.BuildOptions{}, invalidArgument(fmt.Errorf("%s not found in %s", dockerfile, dir))
+	}
+	return opts, nil
+}
+
+// jobOptions 回傳作業的建置參數；未要求建置時回傳 nil。
+func (b *buildDTO) jobOptions(image, hostDir string) (*containers.BuildOptions, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if image != "" {
+		return nil, invalidArgument(errors.New("image and build are mutually exclusive"))
+	}
+	dir := b.Dir
+	if dir == "" {
+		dir = hostDir
+	}
+	opts, err := b.options(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+// createOptions 回傳建立容器的建置參數；未要求建置時 image 為必填。
+func (b *buildDTO) createOptions(image string) (*containers.BuildOptions, error) {
+	switch {
+	case b == nil && strings.TrimSpace(image) == "":
+		return nil, invalidArgument(errors.New("image or build is required"))
+	case b == nil:
+		return nil, nil
+	case image != "":
+		return nil, invalidArgument(errors.New("image and build are mutually exclusive"))
+	case b.Dir == "":
+		return nil, invalidArgument(errors.New("build.dir is required"))
+	}
+	opts, err := b.options(b.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return &opts, nil
+}
+
+func newBuildDTO(o *containers.BuildOptions) *buildDTO {
+	if o == nil {
+		return nil
+	}
+	return &buildDTO{
+		Dir:        o.ContextDir,
+		Dockerfile: o.Dockerfile,
+		BuildArgs:  o.BuildArgs,
+		Target:     o.Target,
+		NoCache:    o.NoCache,
+		CacheFrom:  o.CacheFrom,
+		Pull:       o.Pull,
+	}
+}
+
+// uploadDir 回傳 DATA_DIR 下既有資料夾的絕對路徑；建置 context 只允許上傳的資料夾，避免打包伺服器上的其他檔案。
+func uploadDir(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", invalidArgument(errors.New("invalid build dir"))
+	}
+	dataDir := os.Getenv("DATA_DIR")
+	if dataDir == "" {
+		dataDir = "./data"
+	}
+	absDataDir, _ := filepath.Abs(dataDir)
+	rel, err := filepath.Rel(absDataDir, abs)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", invalidArgument(errors.New("build dir must be an upload directory under DATA_DIR"))
+	}
+	if fi, err := os.Stat(abs); err != nil || !fi.IsDir() {
+		return "", invalidArgument(fmt.Errorf("build dir %s does not exist", dir))
+	}
+	return abs, nil
+}
+
+// ndjsonStream 以 chunked NDJSON 輸出串流；第一行輸出時才送出 header，
+// 因此第一行之前的錯誤（例如 provider 不支援）仍以一般的錯誤回應回報。
+type ndjsonStream struct {
+	c       *gin.Context
+	enc     *json.Encoder
+	started bool
+}
+
+func newNDJSONStream(c *gin.Context) *ndjsonStream {
+	return &ndjsonStream{c: c, enc: json.NewEncoder(c.Writer)}
+}
+
+func (s *ndjsonStream) write(v any) {
+	if !s.started {
+		s.started = true
+		s.c.Header("Content-Type", "application/x-ndjson")
+		s.c.Header("X-Accel-Buffering", "no")
+		s.c.Status(http.StatusOK)
+	}
+	_ = s.enc.Encode(v)
+	s.c.Writer.Flush()
+}
+
+// finish 輸出最後一行：成功時為 result；開始輸出後才失敗時為錯誤（用戶端已斷線時省略）。
+func (s *ndjsonStream) finish(result any, err error) {
+	switch {
+	case err != nil && !s.started:
+		respondError(s.c, err)
+	case err != nil:
+		if s.c.Request.Context().Err() == nil {
+			s.write(newErrorBody(s.c, err))
+		}
+	default:
+		s.write(result)
+	}
+}
+
+// BuildImage 以上傳資料夾建置映像，並以 chunked NDJSON 串流建置輸出（每行 {"stream": "..."}）；
+// 最後一行為建置結果 {"image": "...", "id": "..."}，開始輸出後才失敗時最後一行為錯誤。
+func BuildImage(c *gin.Context) {
+	var dto buildDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		respondError(c, invalidArgument(err))
+		return
+	}
+	if dto.Dir == "" {
+		respondError(c, invalidArgument(errors.New("dir is required")))
+		return
+	}
+	opts, err := dto.options(dto.Dir)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	stream := newNDJSONStream(c)
+	res, err := Svc.Build(c.Request.Context(), opts, func(line string) {
+		stream.write(gin.H{"stream": line})
+	})
+	stream.finish(res, err)
+}
+
+type pullImageDTO struct {
+	Image string `json:"image" binding:"required"` // 省略 tag 時拉取 latest
+}
+
+// PullImage 拉取映像並以 chunked NDJSON 串流進度（每行一個 PullProgress）；最後一行為映像資訊。
+// registry 上沒有此映像時回傳 404 image_not_found，其他拉取失敗回傳 502 image_pull_failed。
+func PullImage(c *gin.Context) {
+	var dto pullImageDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		respondError(c, invalidArgument(err))
+		return
+	}
+	stream := newNDJSONStream(c)
+	img, err := Svc.PullImage(c.Request.Context(), dto.Image, func(p containers.PullProgress) {
+		stream.write(p)
+	})
+	stream.finish(img, err)
+}
+
+// ListImages 列出本機映像；可用 reference（可含 *）、dangling=true 與 built=true 篩選。
+func ListImages(c *gin.Context) {
+	filter := containers.ImageFilter{
+		Reference: c.Query("reference"),
+		Dangling:  c.Query("dangling") == "true",
+		Built:     c.Query("built") == "true",
+	}
+	items, err := Svc.ListImages(c.Request.Context(), filter)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// imageRef 讀取路徑中的映像名稱或 ID；名稱可包含 /，因此路由使用 *ref。
+func imageRef(c *gin.Context) string {
+	return strings.TrimPrefix(c.Param("ref"), "/")
+}
+
+// GetImage 以名稱或 ID 查詢本機映像。
+func GetImage(c *gin.Context) {
+	img, err := Svc.InspectImage(c.Request.Context(), imageRef(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, img)
+}
+
+// DeleteImage 刪除本機映像；容器仍在使用時回傳 409，帶 force=true 時仍刪除。
+func DeleteImage(c *gin.Context) {
+	deleted, err := Svc.RemoveImage(c.Request.Context(), imageRef(c), c.Query("force") == "true")
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+type pruneImagesDTO struct {
+	All   bool   `json:"all"`   // 一併清除沒有容器使用的映像，預設只清除沒有 tag 的映像
+	Until string `json:"until"` // 只清除建立超過此時間的映像，例如 24h
+	Built bool   `json:"built"` // 只清除本服務建置的映像
+}
+
+// PruneImages 清除沒有容器使用的映像；請求內容可省略。
+func PruneImages(c *gin.Context) {
+	var dto pruneImagesDTO
+	if err := c.ShouldBindJSON(&dto); err != nil && !errors.Is(err, io.EOF) {
+		respondError(c, invalidArgument(err))
+		return
+	}
+	opts := containers.PruneImagesOptions{All: dto.All, Built: dto.Built}
+	if dto.Until != "" {
+		d, err := time.ParseDuration(dto.Until)
+		if err != nil {
+			respondError(c, invalidArgument(fmt.Errorf("invalid until: %w", err)))
+			return
+		}
+		opts.Until = d
+	}
+	report, err := Svc.PruneImages(c.Request.Context(), opts)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
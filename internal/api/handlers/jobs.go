@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/jobs"
+	"container-manager/internal/middleware"
+	"container-manager/internal/storage"
+)
+
+// Jobs 執行並記錄 /v1/jobs 的作業；作業透過 Svc 執行，測試替換 Svc 後同樣生效。
+// 排隊作業的 worker 由 server.Run 啟動。
+var Jobs = newJobManager()
+
+func newJobManager() *jobs.Manager {
+	db, _ := storage.OpenDefault()
+	_ = storage.Migrate(db)
+	return jobs.NewManager(storage.NewJobRepository(db), func(ctx context.Context, opts containers.JobOptions) (containers.JobResult, error) {
+		return Svc.RunJob(ctx, opts)
+	}, jobs.ConfigFromEnv())
+}
+
+type jobView struct {
+	ID           string   `json:"id"`
+	Submitter    string   `json:"submitter,omitempty"`
+	Image        string   `json:"image"`
+	Cmd          []string `json:"cmd"`
+	HostDir      string   `json:"hostDir"`
+	ContainerDir string   `json:"containerDir"`
+	Mode         string   `json:"mode"`
+	Status       string   `json:"status"`
+	ExitCode     int64    `json:"exitCode"`
+	Logs         string   `json:"logs"`
+	Error        string   `json:"error,omitempty"`
+	CreatedAt    int64    `json:"createdAt"`
+	StartedAt    int64    `json:"startedAt,omitempty"`
+	FinishedAt   int64    `json:"finishedAt,omitempty"`
+	// Attempts 每次執行的結果，僅查詢單一作業時提供
+	Attempts []attemptView `json:"attempts,omitempty"`
+	// Matrix 矩陣作業的子作業與彙整，僅查詢單一矩陣作業時提供
+	Matrix *matrixView `json:"matrix,omitempty"`
+}
+
+// matrixSpec 矩陣作業父紀錄的 options_json：軸、並行上限與替換前的作業參數。
+type matrixSpec struct {
+	Axes        jobs.Matrix `json:"axes"`
+	MaxParallel int         `json:"maxParallel"`
+	Job         runJobDTO   `json:"job"`
+}
+
+type matrixView struct {
+	Axes        jobs.Matrix `json:"axes"`
+	MaxParallel int         `json:"maxParallel"`
+	Total       int         `json:"total"`
+	// Counts 各狀態的子作業數；ExitCodes 已結束的子作業各 exit code 的次數
+	Counts    map[string]int    `json:"counts"`
+	ExitCodes map[string]int    `json:"exitCodes"`
+	Jobs      []matrixChildView `json:"jobs"`
+}
+
+type matrixChildView struct {
+	ID       string            `json:"id"`
+	Index    int               `json:"index"`
+	Values   map[string]string `json:"values"`
+	Status   string            `json:"status"`
+	ExitCode int64             `json:"exitCode"`
+	Error    string            `json:"error,omitempty"`
+}
+
+func newMatrixView(optionsJSON string, children []storage.MatrixChild) *matrixView {
+	var spec matrixSpec
+	_ = json.Unmarshal([]byte(optionsJSON), &spec)
+	v := &matrixView{
+		Axes:        spec.Axes,
+		MaxParallel: spec.MaxParallel,
+		Total:       len(children),
+		Counts:      map[string]int{},
+		ExitCodes:   map[string]int{},
+		Jobs:        make([]matrixChildView, 0, len(children)),
+	}
+	for _, c := range children {
+		v.Counts[string(c.Job.Status)]++
+		if c.Job.Status.Finished() {
+			v.ExitCodes[strconv.FormatInt(c.Job.ExitCode, 10)]++
+		}
+		v.Jobs = append(v.Jobs, matrixChildView{
+			ID:       c.Job.ID,
+			Index:    c.Index,
+			Values:   c.Values,
+			Status:   string(c.Job.Status),
+			ExitCode: c.Job.ExitCode,
+			Error:    c.Job.Error,
+		})
+	}
+	return v
+}
+
+// runMatrix 將作業依 matrix 展開為排隊的子作業，回傳 202 與父作業 ID；
+// 每個組合替換後先經 PrepareJob 驗證，任一組合無效時不建立任何作業。
+func runMatrix(c *gin.Context, dto jobRequestDTO, template containers.JobOptions) {
+	axes := make(jobs.Matrix, len(dto.Matrix))
+	for name, raw := range dto.Matrix {
+		values, err := axisValues(name, raw)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		axes[name] = values
+	}
+	combos, err := axes.Expand()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	children := make([]jobs.MatrixJob, 0, len(combos))
+	for _, values := range combos {
+		opts, err := jobs.Substitute(template, values)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if err := Svc.PrepareJob(&opts); err != nil {
+			respondError(c, err)
+			return
+		}
+		children = append(children, jobs.MatrixJob{Values: values, Options: opts})
+	}
+	spec, _ := json.Marshal(matrixSpec{Axes: axes, MaxParallel: dto.MaxParallel, Job: dto.runJobDTO})
+	parent := storage.JobRecord{
+		Submitter:    middleware.Subject(c),
+		Image:        template.Image,
+		Cmd:          template.Cmd,
+		HostDir:      template.HostDir,
+		ContainerDir: template.ContainerDir,
+		OptionsJSON:  string(spec),
+	}
+	id, ids, err := Jobs.SubmitMatrix(c.Request.Context(), parent, dto.MaxParallel, children)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	items := make([]gin.H, 0, len(ids))
+	for i, childID := range ids {
+		items = append(items, gin.H{"id": childID, "values": combos[i]})
+	}
+	c.JSON(http.StatusAccepted, gin.H{"id": id, "status": storage.JobRunning, "jobs": items})
+}
+
+// axisValues 將軸的值轉為字串：字串取其內容，數字與布林值維持原本的寫法（例如 1e-3 不會變成 0.001）。
+func axisValues(name string, raw []json.RawMessage) ([]string, error) {
+	out := make([]string, 0, len(raw))
+	for _, r := range raw {
+		var v any
+		if err := json.Unmarshal(r, &v); err != nil {
+			return nil, invalidArgument(err)
+		}
+		switch v := v.(type) {
+		case string:
+			out = append(out, v)
+		case float64, bool:
+			out = append(out, string(r))
+		default:
+			return nil, invalidArgument(fmt.Errorf("matrix axis %q: values must be strings, numbers or booleans", name))
+		}
+	}
+	return out, nil
+}
+
+type attemptView struct {
+	Attempt    int    `json:"attempt"`
+	Status     string `json:"status"`
+	ExitCode   int64  `json:"exitCode"`
+	OOMKilled  bool   `json:"oomKilled,omitempty"`
+	Logs       string `json:"logs"`
+	Error      string `json:"error,omitempty"`
+	StartedAt  int64  `json:"startedAt"`
+	FinishedAt int64  `json:"finishedAt,omitempty"`
+}
+
+func newAttemptView(a storage.JobAttempt) attemptView {
+	v := attemptView{
+		Attempt:   a.Attempt,
+		Status:    string(a.Status),
+		ExitCode:  a.ExitCode,
+		OOMKilled: a.OOMKilled,
+		Logs:      a.Logs,
+		Error:     a.Error,
+		StartedAt: a.StartedAt.Unix(),
+	}
+	if a.FinishedAt.Valid {
+		v.FinishedAt = a.FinishedAt.Time.Unix()
+	}
+	return v
+}
+
+func newJobView(j storage.JobRecord) jobView {
+	v := jobView{
+		ID:           j.ID,
+		Submitter:    j.Submitter,
+		Image:        j.Image,
+		Cmd:          j.Cmd,
+		HostDir:      j.HostDir,
+		ContainerDir: j.ContainerDir,
+		Mode:         string(j.Mode),
+		Status:       string(j.Status),
+		ExitCode:     j.ExitCode,
+		Logs:         j.Logs,
+		Error:        j.Error,
+		CreatedAt:    j.CreatedAt.Unix(),
+	}
+	if j.StartedAt.Valid {
+		v.StartedAt = j.StartedAt.Time.Unix()
+	}
+	if j.FinishedAt.Valid {
+		v.FinishedAt = j.FinishedAt.Time.Unix()
+	}
+	return v
+}
+
+// GetJob 回傳作業紀錄，含設定重試時的每次執行；矩陣作業另含子作業與彙整。
+func GetJob(c *gin.Context) {
+	ctx := c.Request.Context()
+	j, err := Jobs.Get(ctx, c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	attempts, err := Jobs.Attempts(ctx, j.ID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	v := newJobView(j)
+	for _, a := range attempts {
+		v.Attempts = append(v.Attempts, newAttemptView(a))
+	}
+	if j.Mode == storage.JobMatrix {
+		children, err := Jobs.MatrixChildren(ctx, j.ID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		v.Matrix = newMatrixView(j.OptionsJSON, children)
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// ListJobs 依建立時間由新到舊列出作業紀錄，可用 submitter/status/image/parent 篩選，以游標分頁。
+func ListJobs(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultPageLimit)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		respondError(c, errInvalidLimit)
+		return
+	}
+	list, next, err := Jobs.List(c.Request.Context(), storage.JobFilter{
+		Submitter: c.Query("submitter"),
+		Status:    c.Query("status"),
+		Image:     c.Query("image"),
+		Parent:    c.Query("parent"),
+		Cursor:    c.Query("cursor"),
+		Limit:     limit,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	items := make([]jobView, 0, len(list))
+	for _, j := range list {
+		items = append(items, newJobView(j))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "nextCursor": next})
+}
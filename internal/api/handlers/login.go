@@ -1,6 +1,7 @@
 package handlers
 
 import (
+    "errors"
     "net/http"
     "os"
     "time"
@@ -18,14 +19,14 @@ type loginDTO struct {
 func Login(c *gin.Context) {
     var dto loginDTO
     if err := c.ShouldBindJSON(&dto); err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        respondError(c, invalidArgument(err))
         return
     }
 
     user := getenv("AUTH_USER", "admin")
     pass := getenv("AUTH_PASS", "admin")
     if dto.Username != user || dto.Password != pass {
-        c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+        respondError(c, errInvalidCredentials)
         return
     }
 
@@ -38,7 +39,7 @@ func Login(c *gin.Context) {
     })
     s, err := token.SignedString([]byte(secret))
     if err != nil {
-        c.JSON(http.StatusInternalServerError, gin.H{"error": "token sign failed"})
+        respondError(c, errors.New("token sign failed"))
         return
     }
     c.JSON(http.StatusOK, gin.H{"token": s})
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"container-manager/internal/containers"
+)
+
+// ContainerLogs 串流容器日誌。
+// 預設以 chunked NDJSON 輸出（每行一個 LogLine）；
+// 帶 format=sse 或 Accept: text/event-stream 時改用 Server-Sent Events，事件名稱為 stdout/stderr。
+func ContainerLogs(c *gin.Context) {
+	id := c.Param("id")
+	opts, err := parseLogsOptions(c)
+	if err != nil {
+		respondError(c, invalidArgument(err))
+		return
+	}
+	ctx := c.Request.Context()
+	rd, err := Svc.Logs(ctx, id, opts)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// 用戶端斷線時關閉 reader，讓 follow 模式下阻塞中的 Next 返回
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = rd.Close()
+	}()
+
+	sse := c.Query("format") == "sse" || strings.Contains(c.GetHeader("Accept"), "text/event-stream")
+	if sse {
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	enc := json.NewEncoder(c.Writer)
+	for {
+		line, err := rd.Next()
+		if err != nil {
+			if ctx.Err() == nil && err != io.EOF {
+				if sse {
+					c.SSEvent("error", newErrorBody(c, err))
+				} else {
+					_ = enc.Encode(newErrorBody(c, err))
+				}
+				c.Writer.Flush()
+			}
+			return
+		}
+		if sse {
+			c.SSEvent(line.Stream, line)
+		} else if err := enc.Encode(line); err != nil {
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+func parseLogsOptions(c *gin.Context) (containers.LogsOptions, error) {
+	opts := containers.LogsOptions{
+		Follow:     c.Query("follow") == "true",
+		Tail:       -1,
+		Stdout:     c.DefaultQuery("stdout", "true") == "true",
+		Stderr:     c.DefaultQuery("stderr", "true") == "true",
+		Timestamps: c.Query("timestamps") == "true",
+	}
+	if v := c.Query("tail"); v != "" && v != "all" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return opts, errors.New("tail must be a non-negative integer or all")
+		}
+		opts.Tail = n
+	}
+	var err error
+	if opts.Since, err = parseTimeParam(c.Query("since")); err != nil {
+		return opts, errors.New("since must be RFC3339 or unix seconds")
+	}
+	if opts.Until, err = parseTimeParam(c.Query("until")); err != nil {
+		return opts, errors.New("until must be RFC3339 or unix seconds")
+	}
+	if !opts.Stdout && !opts.Stderr {
+		return opts, errors.New("at least one of stdout or stderr must be true")
+	}
+	return opts, nil
+}
+
+// parseTimeParam 接受 RFC3339 或 unix 秒；空字串回傳零值。
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339Nano, v)
+}
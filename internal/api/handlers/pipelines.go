@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/middleware"
+	"container-manager/internal/pipelines"
+	"container-manager/internal/storage"
+)
+
+// Pipelines 執行並記錄 /v1/pipelines 的管線；每個步驟透過 Jobs 執行並留下作業紀錄。
+// 中斷執行的檢查由 server.Run 啟動。
+var Pipelines = newPipelineRunner()
+
+func newPipelineRunner() *pipelines.Runner {
+	db, _ := storage.OpenDefault()
+	_ = storage.Migrate(db)
+	return pipelines.NewRunner(storage.NewPipelineRepository(db), func(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error) {
+		return Jobs.Run(ctx, rec, opts)
+	})
+}
+
+type pipelineDTO struct {
+	Name         string            `json:"name"`
+	HostDir      string            `json:"hostDir" binding:"required"`      // 來自 /v1/uploads 回傳的 dir，所有步驟共用
+	ContainerDir string            `json:"containerDir" binding:"required"` // 例如 /workspace
+	OnFailure    string            `json:"onFailure"`                       // fail-fast|continue，預設 fail-fast
+	Steps        []pipelineStepDTO `json:"steps" binding:"required,min=1,dive"`
+}
+
+// pipelineStepDTO 與 runJobDTO 相同，但掛載的資料夾由管線指定。
+type pipelineStepDTO struct {
+	Name        string                     `json:"name" binding:"required"`
+	DependsOn   []string                   `json:"dependsOn"`
+	Image       string                     `json:"image"` // 可省略，將自動偵測
+	Build       *buildDTO                  `json:"build"` // 與 image 擇一；dir 省略時使用管線的 hostDir
+	Cmd         []string                   `json:"cmd"`   // 可省略，將自動偵測
+	Env         map[string]string          `json:"env"`
+	Network     string                     `json:"network"`
+	Resources   containers.ResourceLimits  `json:"resources"`
+	Security    containers.SecurityOptions `json:"security"`
+	Timeout     int                        `json:"timeoutSeconds" binding:"min=0"`
+	GracePeriod int                        `json:"gracePeriodSeconds" binding:"min=0"`
+	Retry       *retryDTO                  `json:"retry"`
+}
+
+// steps 將每個步驟轉為作業參數並以 PrepareJob 檢查；與排程相同，存放未套用預設值的版本。
+func (dto pipelineDTO) steps() ([]pipelines.Step, error) {
+	out := make([]pipelines.Step, 0, len(dto.Steps))
+	for _, s := range dto.Steps {
+		opts, err := runJobDTO{
+			Image:        s.Image,
+			Build:        s.Build,
+			HostDir:      dto.HostDir,
+			ContainerDir: dto.ContainerDir,
+			Cmd:          s.Cmd,
+			Env:          s.Env,
+			Network:      s.Network,
+			Resources:    s.Resources,
+			Security:     s.Security,
+			Timeout:      s.Timeout,
+			GracePeriod:  s.GracePeriod,
+			Retry:        s.Retry,
+		}.jobOptions()
+		if err != nil {
+			return nil, err
+		}
+		check := opts
+		if err := Svc.PrepareJob(&check); err != nil {
+			return nil, err
+		}
+		out = append(out, pipelines.Step{Name: s.Name, DependsOn: s.DependsOn, Options: opts})
+	}
+	return out, nil
+}
+
+type pipelineView struct {
+	ID           string `json:"id"`
+	Name         string `json:"name,omitempty"`
+	Submitter    string `json:"submitter,omitempty"`
+	HostDir      string `json:"hostDir"`
+	ContainerDir string `json:"containerDir"`
+	OnFailure    string `json:"onFailure"`
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+	CreatedAt    int64  `json:"createdAt"`
+	StartedAt    int64  `json:"startedAt,omitempty"`
+	FinishedAt   int64  `json:"finishedAt,omitempty"`
+	// Steps 各步驟的狀態，僅查詢單一管線時提供
+	Steps []pipelineStepView `json:"steps,omitempty"`
+}
+
+type pipelineStepView struct {
+	Name       string   `json:"name"`
+	DependsOn  []string `json:"dependsOn,omitempty"`
+	Image      string   `json:"image"`
+	Cmd        []string `json:"cmd"`
+	Status     string   `json:"status"`
+	JobID      string   `json:"jobId,omitempty"` // 日誌見 /v1/jobs/{jobId}
+	ExitCode   int64    `json:"exitCode"`
+	Error      string   `json:"error,omitempty"`
+	StartedAt  int64    `json:"startedAt,omitempty"`
+	FinishedAt int64    `json:"finishedAt,omitempty"`
+}
+
+func newPipelineView(p storage.PipelineRun) pipelineView {
+	v := pipelineView{
+		ID:           p.ID,
+		Name:         p.Name,
+		Submitter:    p.Submitter,
+		HostDir:      p.HostDir,
+		ContainerDir: p.ContainerDir,
+		OnFailure:    p.OnFailure,
+		Status:       string(p.Status),
+		Error:        p.Error,
+		CreatedAt:    p.CreatedAt.Unix(),
+	}
+	if p.StartedAt.Valid {
+		v.StartedAt = p.StartedAt.Time.Unix()
+	}
+	if p.FinishedAt.Valid {
+		v.FinishedAt = p.FinishedAt.Time.Unix()
+	}
+	for _, s := range p.Steps {
+		v.Steps = append(v.Steps, newPipelineStepView(s))
+	}
+	return v
+}
+
+func newPipelineStepView(s storage.PipelineStep) pipelineStepView {
+	var opts containers.JobOptions
+	_ = json.Unmarshal([]byte(s.OptionsJSON), &opts)
+	v := pipelineStepView{
+		Name:      s.Name,
+		DependsOn: s.DependsOn,
+		Image:     opts.Image,
+		Cmd:       opts.Cmd,
+		Status:    string(s.Status),
+		JobID:     s.JobID,
+		ExitCode:  s.ExitCode,
+		Error:     s.Error,
+	}
+	if s.StartedAt.Valid {
+		v.StartedAt = s.StartedAt.Time.Unix()
+	}
+	if s.FinishedAt.Valid {
+		v.FinishedAt = s.FinishedAt.Time.Unix()
+	}
+	return v
+}
+
+// RunPipeline 驗證管線後在背景執行，回傳 202 與執行 ID；以 GET /v1/pipelines/:id 查詢進度。
+func RunPipeline(c *gin.Context) {
+	var dto pipelineDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		respondError(c, invalidArgument(err))
+		return
+	}
+	steps, err := dto.steps()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	id, err := Pipelines.Submit(c.Request.Context(), storage.PipelineRun{
+		Name:         dto.Name,
+		Submitter:    middleware.Subject(c),
+		HostDir:      steps[0].Options.HostDir, // 已轉換為宿主機路徑
+		ContainerDir: dto.ContainerDir,
+		OnFailure:    dto.OnFailure,
+	}, steps)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"id": id, "status": storage.PipelineRunning})
+}
+
+func GetPipeline(c *gin.Context) {
+	p, err := Pipelines.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newPipelineView(p))
+}
+
+// ListPipelines 依建立時間由新到舊列出管線執行，可用 submitter/status 篩選，以游標分頁。
+func ListPipelines(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultPageLimit)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		respondError(c, errInvalidLimit)
+		return
+	}
+	list, next, err := Pipelines.List(c.Request.Context(), storage.PipelineFilter{
+		Submitter: c.Query("submitter"),
+		Status:    c.Query("status"),
+		Cursor:    c.Query("cursor"),
+		Limit:     limit,
+	})
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	items := make([]pipelineView, 0, len(list))
+	for _, p := range list {
+		items = append(items, newPipelineView(p))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "nextCursor": next})
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/middleware"
+	"container-manager/internal/schedules"
+	"container-manager/internal/storage"
+)
+
+// Schedules 管理並觸發 /v1/schedules 的排程；作業透過 Jobs.RunLimited 執行並記錄，
+// 與排隊作業共用 JOB_MAX_RUNNING 與 JOB_MAX_PER_USER 的上限。排程迴圈由 server.Run 啟動。
+var Schedules = newScheduler()
+
+func newScheduler() *schedules.Scheduler {
+	db, _ := storage.OpenDefault()
+	_ = storage.Migrate(db)
+	return schedules.NewScheduler(storage.NewScheduleRepository(db), func(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error) {
+		return Jobs.RunLimited(ctx, rec, opts)
+	})
+}
+
+type scheduleDTO struct {
+	Name     string    `json:"name"`
+	Cron     string    `json:"cron" binding:"required"` // 標準五欄位或 @hourly 等描述
+	Timezone string    `json:"timezone"`                // IANA 時區，預設 UTC
+	Overlap  string    `json:"overlap"`                 // skip|queue|replace，預設 skip
+	Enabled  *bool     `json:"enabled"`                 // 預設 true
+	Job      runJobDTO `json:"job"`
+}
+
+// record 將請求轉為排程紀錄；作業範本先以 PrepareJob 驗證，存放未套用預設值的版本，
+// 讓伺服器預設值的調整對之後的觸發生效。
+func (dto scheduleDTO) record() (storage.ScheduleRecord, error) {
+	opts, err := dto.Job.jobOptions()
+	if err != nil {
+		return storage.ScheduleRecord{}, err
+	}
+	check := opts
+	if err := Svc.PrepareJob(&check); err != nil {
+		return storage.ScheduleRecord{}, err
+	}
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return storage.ScheduleRecord{}, err
+	}
+	return storage.ScheduleRecord{
+		Name:        dto.Name,
+		CronExpr:    dto.Cron,
+		Timezone:    dto.Timezone,
+		Overlap:     dto.Overlap,
+		Enabled:     dto.Enabled == nil || *dto.Enabled,
+		OptionsJSON: string(b),
+	}, nil
+}
+
+type scheduleView struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Cron      string    `json:"cron"`
+	Timezone  string    `json:"timezone"`
+	Overlap   string    `json:"overlap"`
+	Enabled   bool      `json:"enabled"`
+	Submitter string    `json:"submitter,omitempty"`
+	Job       runJobDTO `json:"job"`
+	CreatedAt int64     `json:"createdAt"`
+	UpdatedAt int64     `json:"updatedAt"`
+	NextRunAt int64     `json:"nextRunAt,omitempty"`
+	LastRunAt int64     `json:"lastRunAt,omitempty"`
+}
+
+func newScheduleView(s storage.ScheduleRecord) scheduleView {
+	var opts containers.JobOptions
+	_ = json.Unmarshal([]byte(s.OptionsJSON), &opts)
+	v := scheduleView{
+		ID:        s.ID,
+		Name:      s.Name,
+		Cron:      s.CronExpr,
+		Timezone:  s.Timezone,
+		Overlap:   s.Overlap,
+		Enabled:   s.Enabled,
+		Submitter: s.Submitter,
+		Job: runJobDTO{
+			Image:        opts.Image,
+			HostDir:      opts.HostDir,
+			ContainerDir: opts.ContainerDir,
+			Cmd:          opts.Cmd,
+			Env:          opts.Env,
+			Network:      opts.Network,
+			Resources:    opts.Resources,
+			Security:     opts.Security,
+			Timeout:      int(opts.Timeout / time.Second),
+			GracePeriod:  int(opts.GracePeriod / time.Second),
+			Retry:        newRetryDTO(opts.Retry),
+			Build:        newBuildDTO(opts.Build),
+		},
+		CreatedAt: s.CreatedAt.Unix(),
+		UpdatedAt: s.UpdatedAt.Unix(),
+	}
+	if s.NextRunAt.Valid {
+		v.NextRunAt = s.NextRunAt.Time.Unix()
+	}
+	if s.LastRunAt.Valid {
+		v.LastRunAt = s.LastRunAt.Time.Unix()
+	}
+	return v
+}
+
+type scheduleRunView struct {
+	ID          string `json:"id"`
+	JobID       string `json:"jobId,omitempty"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+	ScheduledAt int64  `json:"scheduledAt"`
+	StartedAt   int64  `json:"startedAt,omitempty"`
+	FinishedAt  int64  `json:"finishedAt,omitempty"`
+}
+
+func newScheduleRunView(r storage.ScheduleRun) scheduleRunView {
+	v := scheduleRunView{
+		ID:          r.ID,
+		JobID:       r.JobID,
+		Status:      string(r.Status),
+		Error:       r.Error,
+		ScheduledAt: r.ScheduledAt.Unix(),
+	}
+	if r.StartedAt.Valid {
+		v.StartedAt = r.StartedAt.Time.Unix()
+	}
+	if r.FinishedAt.Valid {
+		v.FinishedAt = r.FinishedAt.Time.Unix()
+	}
+	return v
+}
+
+func CreateSchedule(c *gin.Context) {
+	var dto scheduleDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		respondError(c, invalidArgument(err))
+		return
+	}
+	rec, err := dto.record()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	rec.Submitter = middleware.Subject(c)
+	s, err := Schedules.Create(c.Request.Context(), rec)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, newScheduleView(s))
+}
+
+// UpdateSchedule 以請求內容整體覆寫排程。
+func UpdateSchedule(c *gin.Context) {
+	var dto scheduleDTO
+	if err := c.ShouldBindJSON(&dto); err != nil {
+		respondError(c, invalidArgument(err))
+		return
+	}
+	rec, err := dto.record()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	rec.ID = c.Param("id")
+	s, err := Schedules.Update(c.Request.Context(), rec)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newScheduleView(s))
+}
+
+func GetSchedule(c *gin.Context) {
+	s, err := Schedules.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newScheduleView(s))
+}
+
+func ListSchedules(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultPageLimit)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		respondError(c, errInvalidLimit)
+		return
+	}
+	list, err := Schedules.List(c.Request.Context(), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	items := make([]scheduleView, 0, len(list))
+	for _, s := range list {
+		items = append(items, newScheduleView(s))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// DeleteSchedule 刪除排程；已觸發且仍在執行的作業會執行完畢。
+func DeleteSchedule(c *gin.Context) {
+	if err := Schedules.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListScheduleRuns 依排定時間由新到舊列出排程的觸發紀錄。
+func ListScheduleRuns(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultPageLimit)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		respondError(c, errInvalidLimit)
+		return
+	}
+	runs, err := Schedules.Runs(c.Request.Context(), c.Param("id"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	items := make([]scheduleRunView, 0, len(runs))
+	for _, r := range runs {
+		items = append(items, newScheduleRunView(r))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/storage"
+	"container-manager/internal/tasks"
+)
+
+// Tasks 執行非同步 exec；exec 透過 Svc 進行，測試替換 Svc 後同樣生效。
+var Tasks = newTaskManager()
+
+func newTaskManager() *tasks.Manager {
+	db, _ := storage.OpenDefault()
+	_ = storage.Migrate(db)
+	return tasks.NewManagerFromEnv(storage.NewTaskRepository(db), func(ctx context.Context, id string, cmd []string) (containers.ExecResult, error) {
+		return Svc.Exec(ctx, id, cmd)
+	})
+}
+
+type taskView struct {
+	ID          string   `json:"id"`
+	ContainerID string   `json:"containerId"`
+	Cmd         []string `json:"cmd"`
+	Status      string   `json:"status"`
+	ExitCode    int      `json:"exitCode"`
+	Stdout      string   `json:"stdout"`
+	Stderr      string   `json:"stderr"`
+	CreatedAt   int64    `json:"createdAt"`
+	FinishedAt  int64    `json:"finishedAt,omitempty"`
+}
+
+func newTaskView(t storage.ContainerTask) taskView {
+	v := taskView{
+		ID:          t.ID,
+		ContainerID: t.ContainerID,
+		Cmd:         t.Cmd(),
+		Status:      string(t.Status),
+		ExitCode:    t.ExitCode,
+		Stdout:      t.Stdout,
+		Stderr:      t.Stderr,
+		CreatedAt:   t.CreatedAt.Unix(),
+	}
+	if t.FinishedAt.Valid {
+		v.FinishedAt = t.FinishedAt.Time.Unix()
+	}
+	return v
+}
+
+func GetTask(c *gin.Context) {
+	t, err := Tasks.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, newTaskView(t))
+}
+
+// ListTasks 列出任務，可用 containerId 篩選。
+func ListTasks(c *gin.Context) {
+	limit, err := queryInt(c, "limit", defaultPageLimit)
+	if err != nil || limit <= 0 || limit > maxPageLimit {
+		respondError(c, errInvalidLimit)
+		return
+	}
+	list, err := Tasks.List(c.Request.Context(), c.Query("containerId"), limit)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	items := make([]taskView, 0, len(list))
+	for _, t := range list {
+		items = append(items, newTaskView(t))
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func CancelTask(c *gin.Context) {
+	if err := Tasks.Cancel(c.Request.Context(), c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
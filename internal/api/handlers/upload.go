@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -31,19 +32,19 @@ func Upload(c *gin.Context) {
 	batch := time.Now().UTC().Format("20060102T150405Z")
 	destDir := filepath.Join(root, req.UserID, batch)
 	if err := os.MkdirAll(destDir, 0o755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
 	form, err := c.MultipartForm()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart form"})
+		respondError(c, invalidArgument(errors.New("invalid multipart form")))
 		return
 	}
 
 	files := form.File["files"]
 	if len(files) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "no files uploaded (field: files)"})
+		respondError(c, invalidArgument(errors.New("no files uploaded (field: files)")))
 		return
 	}
 
@@ -52,7 +53,7 @@ func Upload(c *gin.Context) {
 		name := filepath.Base(f.Filename)
 		path := filepath.Join(destDir, name)
 		if err := c.SaveUploadedFile(f, path); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("save %s failed: %v", name, err)})
+			respondError(c, fmt.Errorf("save %s failed: %w", name, err))
 			return
 		}
 		stored = append(stored, path)
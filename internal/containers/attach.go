@@ -0,0 +1,61 @@
+package containers
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// AttachOptions 開啟互動式 TTY exec 的參數。
+type AttachOptions struct {
+	Cmd  []string // 預設 /bin/sh
+	Cols uint
+	Rows uint
+}
+
+func (o AttachOptions) normalize() AttachOptions {
+	if len(o.Cmd) == 0 {
+		o.Cmd = []string{"/bin/sh"}
+	}
+	return o
+}
+
+// TTYSession 為一個互動式 TTY exec 連線：Read 讀取終端輸出，Write 寫入 stdin。
+type TTYSession interface {
+	io.ReadWriteCloser
+	Resize(cols, rows uint) error
+}
+
+// Attacher 可選介面：支援以 TTY 模式互動執行命令。
+type Attacher interface {
+	Attach(ctx context.Context, id string, opts AttachOptions) (TTYSession, error)
+}
+
+// echoSession 將寫入的 stdin 原樣回傳為輸出（供 MockProvider 使用）。
+type echoSession struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+
+	mu         sync.Mutex
+	cols, rows uint
+}
+
+func newEchoSession(cols, rows uint) *echoSession {
+	r, w := io.Pipe()
+	return &echoSession{r: r, w: w, cols: cols, rows: rows}
+}
+
+func (s *echoSession) Read(p []byte) (int, error)  { return s.r.Read(p) }
+func (s *echoSession) Write(p []byte) (int, error) { return s.w.Write(p) }
+
+func (s *echoSession) Close() error {
+	_ = s.w.Close()
+	return s.r.Close()
+}
+
+func (s *echoSession) Resize(cols, rows uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cols, s.rows = cols, rows
+	return nil
+}
@@ -0,0 +1,102 @@
+package containers
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BuildRepository 建置映像的 repository 前綴；以此開頭的映像只存在本機，建立容器或作業時不拉取。
+const BuildRepository = "container-manager"
+
+// BuildOptions 以資料夾中的 Dockerfile 建置映像的參數。
+type BuildOptions struct {
+	ContextDir string            `json:"contextDir"`           // 建置 context，本服務可讀取的路徑
+	Dockerfile string            `json:"dockerfile,omitempty"` // 相對於 ContextDir，預設 Dockerfile
+	Tag        string            `json:"tag"`                  // 空字串時由 BuildTag 依 ContextDir 產生
+	BuildArgs  map[string]string `json:"buildArgs,omitempty"`
+	Target     string            `json:"target,omitempty"`    // 多階段建置的目標階段
+	NoCache    bool              `json:"noCache,omitempty"`   // 不使用快取的層
+	CacheFrom  []string          `json:"cacheFrom,omitempty"` // 可作為快取來源的映像
+	Pull       bool              `json:"pull,omitempty"`      // 總是拉取基底映像的最新版本
+}
+
+// BuildResult 建置完成的映像。
+type BuildResult struct {
+	Image string `json:"image"` // 即 BuildOptions.Tag
+	ID    string `json:"id"`
+}
+
+// ImageBuilder 可選介面：支援以 Dockerfile 建置映像。建置輸出逐行傳給 progress；
+// Dockerfile 中的步驟失敗時回傳可比對 ErrBuildFailed 的錯誤。
+type ImageBuilder interface {
+	BuildImage(ctx context.Context, opts BuildOptions, progress func(line string)) (BuildResult, error)
+}
+
+func (o BuildOptions) dockerfile() string {
+	if o.Dockerfile == "" {
+		return "Dockerfile"
+	}
+	return o.Dockerfile
+}
+
+// Validate 檢查建置參數；Tag 為空時補上 BuildTag 產生的名稱。
+func (o *BuildOptions) Validate() error {
+	if o.ContextDir == "" {
+		return fmt.Errorf("%w: build context directory is required", ErrInvalidOptions)
+	}
+	if !fs.ValidPath(filepath.ToSlash(o.dockerfile())) {
+		return fmt.Errorf("%w: dockerfile must be a relative path inside the build context", ErrInvalidOptions)
+	}
+	for k := range o.BuildArgs {
+		if k == "" || strings.ContainsAny(k, "= ") {
+			return fmt.Errorf("%w: invalid build arg name %q", ErrInvalidOptions, k)
+		}
+	}
+	if o.Target != "" && !buildStage.MatchString(o.Target) {
+		return fmt.Errorf("%w: invalid build target %q", ErrInvalidOptions, o.Target)
+	}
+	if o.Tag == "" {
+		o.Tag = BuildTag(o.ContextDir, o.Target)
+	}
+	return nil
+}
+
+// maxTagLength Docker tag 的長度上限。
+const maxTagLength = 128
+
+var (
+	buildStage  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+	invalidName = regexp.MustCompile(`[^a-z0-9]+`)
+	invalidTag  = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+)
+
+// BuildTag 依上傳資料夾（DATA_DIR/<userId>/<batch>）產生映像名稱 container-manager/<userId>:<batch>，
+// 指定 target 時 tag 另加上 -<target>；不合法的字元以 - 取代。
+func BuildTag(contextDir, target string) string {
+	dir := filepath.Clean(contextDir)
+	user := strings.Trim(invalidName.ReplaceAllString(strings.ToLower(filepath.Base(filepath.Dir(dir))), "-"), "-")
+	if user == "" {
+		user = "anonymous"
+	}
+	tag := filepath.Base(dir)
+	if target != "" {
+		tag += "-" + target
+	}
+	tag = strings.TrimLeft(invalidTag.ReplaceAllString(tag, "-"), ".-")
+	if tag == "" {
+		tag = "latest"
+	}
+	if len(tag) > maxTagLength {
+		tag = tag[:maxTagLength]
+	}
+	return BuildRepository + "/" + user + ":" + tag
+}
+
+// builtImage 判斷映像是否為本服務建置（只存在本機）。
+func builtImage(ref string) bool {
+	return strings.HasPrefix(ref, BuildRepository+"/")
+}
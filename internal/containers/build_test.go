@@ -0,0 +1,46 @@
+package containers
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBuildTag(t *testing.T) {
+	cases := []struct {
+		dir, target, want string
+	}{
+		{"/data/u123/20250101T000000Z", "", "container-manager/u123:20250101T000000Z"},
+		{"/data/u123/20250101T000000Z/", "runtime", "container-manager/u123:20250101T000000Z-runtime"},
+		{"/data/User_A/batch 1", "", "container-manager/user-a:batch-1"},
+		{"/data/__/.x", "", "container-manager/anonymous:x"},
+		{"/data/u1/" + strings.Repeat("a", 200), "", "container-manager/u1:" + strings.Repeat("a", maxTagLength)},
+	}
+	for _, tc := range cases {
+		if got := BuildTag(tc.dir, tc.target); got != tc.want {
+			t.Errorf("BuildTag(%q, %q) = %q, want %q", tc.dir, tc.target, got, tc.want)
+		}
+	}
+}
+
+func TestBuildOptions_Validate(t *testing.T) {
+	o := BuildOptions{ContextDir: "/data/u1/b1", Target: "test"}
+	if err := o.Validate(); err != nil || o.Tag != "container-manager/u1:b1-test" {
+		t.Fatalf("validate: %+v err=%v", o, err)
+	}
+	o = BuildOptions{ContextDir: "/data/u1/b1", Tag: "custom:1"}
+	if err := o.Validate(); err != nil || o.Tag != "custom:1" {
+		t.Fatalf("explicit tag: %+v err=%v", o, err)
+	}
+	for name, bad := range map[string]BuildOptions{
+		"context":    {},
+		"dockerfile": {ContextDir: "/d", Dockerfile: "../Dockerfile"},
+		"absolute":   {ContextDir: "/d", Dockerfile: "/etc/Dockerfile"},
+		"build arg":  {ContextDir: "/d", BuildArgs: map[string]string{"A=B": "1"}},
+		"target":     {ContextDir: "/d", Target: "-x"},
+	} {
+		if err := bad.Validate(); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("%s: got %v, want ErrInvalidOptions", name, err)
+		}
+	}
+}
@@ -1,97 +1,451 @@
 package containers
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/build"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+	"github.com/moby/go-archive"
+	"github.com/moby/patternmatcher/ignorefile"
 )
 
-type DockerProvider struct{ cli *client.Client }
+// managedLabel 標記由本服務建立的容器與建置的映像；List 只會列出帶有此標籤的容器。
+const managedLabel = "container-manager.managed"
+
+type DockerProvider struct {
+	cli             *client.Client
+	execOutputLimit int // 每個輸出串流保留的最大位元組數，由 EXEC_OUTPUT_LIMIT 設定
+}
 
 func NewDockerProvider() *DockerProvider {
 	cli, _ := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	return &DockerProvider{cli: cli}
+	return &DockerProvider{cli: cli, execOutputLimit: execOutputLimitFromEnv()}
 }
 
-func (d *DockerProvider) Create(opts CreateOptions) (Container, error) {
-	ctx := context.Background()
+func (d *DockerProvider) Create(ctx context.Context, opts CreateOptions) (Container, error) {
 
-	if rc, err := d.cli.ImagePull(ctx, opts.Image, image.PullOptions{}); err == nil {
-		// Drain and close to avoid leaking the connection; ignore content
-		_, _ = io.Copy(io.Discard, rc)
-		_ = rc.Close()
+	if err := d.ensureImage(ctx, opts.Image); err != nil {
+		return Container{}, err
 	}
 
 	name := opts.Name
-	// Use a long-running command to keep container running for exec
-	// Different images may have different default commands, so we use a universal one
+	config, hostConfig, netConfig := createConfig(opts)
+	resp, err := d.cli.ContainerCreate(ctx, config, hostConfig, netConfig, nil, name)
+	if err != nil {
+		return Container{}, dockerError(err)
+	}
+
+	c := Container{
+		ID:        resp.ID,
+		Name:      name,
+		Image:     opts.Image,
+		CreatedAt: time.Now().Unix(),
+		Status:    "created",
+	}
+	return c, nil
+}
+
+// createConfig 將 CreateOptions 轉成 Docker 的 Config、HostConfig 與 NetworkingConfig。
+func createConfig(opts CreateOptions) (*container.Config, *container.HostConfig, *network.NetworkingConfig) {
+	labels := map[string]string{}
+	for k, v := range opts.Labels {
+		labels[k] = v
+	}
+	labels[managedLabel] = "true"
 	config := &container.Config{
-		Image: opts.Image,
-		Cmd:   []string{"tail", "-f", "/dev/null"}, // Keep container running
+		Image:      opts.Image,
+		Env:        envList(opts.Env),
+		Entrypoint: opts.Entrypoint,
+		Cmd:        opts.Cmd,
+		WorkingDir: opts.WorkingDir,
+		Hostname:   opts.Hostname,
+		Labels:     labels,
 	}
+	if len(opts.Entrypoint) == 0 && len(opts.Cmd) == 0 {
+		// Use a long-running command to keep container running for exec
+		// Different images may have different default commands, so we use a universal one
+		config.Cmd = []string{"tail", "-f", "/dev/null"}
+	}
+
+	hostConfig := &container.HostConfig{}
+	applyLimits(opts.Resources, opts.Security, config, hostConfig)
 
 	// Build mounts if provided
-	var hostConfig *container.HostConfig
-	if len(opts.Mounts) > 0 || opts.ContainerDir != "" {
-		mounts := []mount.Mount{}
-		if opts.ContainerDir != "" {
-			// Single mount: use ContainerDir as target, need hostDir from caller
-			// This is a simplified case - caller should provide both hostDir and containerDir
-		}
-		for hostDir, containerDir := range opts.Mounts {
-			mounts = append(mounts, mount.Mount{
-				Type:   mount.TypeBind,
-				Source: hostDir,
-				Target: containerDir,
+	for hostDir, containerDir := range opts.Mounts {
+		hostConfig.Mounts = append(hostConfig.Mounts, mount.Mount{
+			Type:   mount.TypeBind,
+			Source: hostDir,
+			Target: containerDir,
+		})
+	}
+
+	netConfig := applyNetwork(opts.Ports, opts.Network, config, hostConfig)
+	return config, hostConfig, netConfig
+}
+
+// applyNetwork 設定連接埠發佈、網路模式與 DNS；自訂網路的別名透過 NetworkingConfig 指定。
+func applyNetwork(ports []PortMapping, n NetworkOptions, config *container.Config, hostConfig *container.HostConfig) *network.NetworkingConfig {
+	if len(ports) > 0 {
+		config.ExposedPorts = nat.PortSet{}
+		hostConfig.PortBindings = nat.PortMap{}
+	}
+	for _, p := range ports {
+		port := nat.Port(strconv.Itoa(p.ContainerPort) + "/" + p.protocol())
+		config.ExposedPorts[port] = struct{}{}
+		binding := nat.PortBinding{HostIP: p.HostIP}
+		if p.HostPort > 0 {
+			binding.HostPort = strconv.Itoa(p.HostPort)
+		}
+		hostConfig.PortBindings[port] = append(hostConfig.PortBindings[port], binding)
+	}
+	hostConfig.DNS = n.DNS
+	hostConfig.DNSSearch = n.DNSSearch
+	hostConfig.DNSOptions = n.DNSOptions
+	if n.Network == "" {
+		return nil
+	}
+	hostConfig.NetworkMode = container.NetworkMode(n.Network)
+	if len(n.Aliases) == 0 {
+		return nil
+	}
+	return &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			n.Network: {Aliases: n.Aliases},
+		},
+	}
+}
+
+// dockerPorts 將 Docker 的 PortMap 轉成已分配的連接埠列表，略過未發佈者。
+func dockerPorts(pm nat.PortMap) []PortMapping {
+	var out []PortMapping
+	for port, bindings := range pm {
+		for _, b := range bindings {
+			hostPort, err := strconv.Atoi(b.HostPort)
+			if err != nil {
+				continue
+			}
+			out = append(out, PortMapping{
+				ContainerPort: port.Int(),
+				HostPort:      hostPort,
+				HostIP:        b.HostIP,
+				Protocol:      port.Proto(),
 			})
 		}
-		if len(mounts) > 0 {
-			hostConfig = &container.HostConfig{
-				Mounts: mounts,
+	}
+	sortPorts(out)
+	return out
+}
+
+// Start 啟動容器；Docker 對執行中的容器回傳 304，此處改以 *StateError 回報。
+func (d *DockerProvider) Start(ctx context.Context, id string) error {
+	info, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return dockerError(err)
+	}
+	if info.State != nil && info.State.Running {
+		return &StateError{ID: id, Op: "start", State: "running"}
+	}
+	return dockerError(d.cli.ContainerStart(ctx, id, container.StartOptions{}))
+}
+
+// Stop 停止執行中的容器；其他狀態回傳 *StateError。
+func (d *DockerProvider) Stop(ctx context.Context, id string) error {
+	info, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return dockerError(err)
+	}
+	if info.State == nil || !info.State.Running {
+		state := "stopped"
+		if info.State != nil {
+			state = dockerStatus(string(info.State.Status))
+		}
+		return &StateError{ID: id, Op: "stop", State: state}
+	}
+	timeout := 10 * time.Second
+	seconds := int(timeout.Seconds())
+	return dockerError(d.cli.ContainerStop(ctx, id, container.StopOptions{Timeout: &seconds}))
+}
+
+func (d *DockerProvider) Delete(ctx context.Context, id string) error {
+	return dockerError(d.cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true}))
+}
+
+// dockerError 依 Docker errdefs 將錯誤分類包裝成 *Error；無法分類者原樣回傳。
+func dockerError(err error) error {
+	var kind error
+	switch {
+	case err == nil:
+		return nil
+	case cerrdefs.IsNotFound(err):
+		kind = ErrNotFound
+	case cerrdefs.IsConflict(err):
+		kind = ErrConflict
+	case cerrdefs.IsInvalidArgument(err):
+		kind = ErrInvalidOptions
+	case cerrdefs.IsNotImplemented(err):
+		kind = ErrNotSupported
+	case cerrdefs.IsDeadlineExceeded(err):
+		kind = ErrTimeout
+	case cerrdefs.IsResourceExhausted(err):
+		kind = ErrLimitExceeded
+	default:
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// ensureImage 建立容器前拉取映像。拉取失敗但本機已有映像時照常使用，讓離線環境仍可使用快取的映像；
+// 本服務建置的映像只存在本機，不拉取，以免被 registry 上同名的映像取代。
+func (d *DockerProvider) ensureImage(ctx context.Context, ref string) error {
+	if builtImage(ref) {
+		return nil
+	}
+	err := d.pullImage(ctx, ref, nil)
+	if err == nil {
+		return nil
+	}
+	if _, ierr := d.cli.ImageInspect(ctx, ref); ierr == nil {
+		return nil
+	}
+	return pullError(ref, err)
+}
+
+// pullImage 拉取映像並讀完進度串流；串流中回報的錯誤同樣回傳。progress 可為 nil。
+func (d *DockerProvider) pullImage(ctx context.Context, ref string, progress func(PullProgress)) error {
+	rc, err := d.cli.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	stop := context.AfterFunc(ctx, func() { _ = rc.Close() })
+	defer stop()
+	dec := json.NewDecoder(rc)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if progress != nil && msg.Status != "" {
+			p := PullProgress{ID: msg.ID, Status: msg.Status}
+			if msg.Progress != nil {
+				p.Current, p.Total = msg.Progress.Current, msg.Progress.Total
 			}
+			progress(p)
 		}
 	}
+}
 
-	resp, err := d.cli.ContainerCreate(ctx, config, hostConfig, nil, nil, name)
+// List 列出本服務建立的容器；status 交由 Docker 篩選，其餘條件在本地比對。
+func (d *DockerProvider) List(ctx context.Context, filter ListFilter) ([]Container, error) {
+	args := filters.NewArgs(filters.Arg("label", managedLabel+"=true"))
+	switch filter.Status {
+	case "":
+	case "stopped":
+		args.Add("status", "exited")
+		args.Add("status", "dead")
+	default:
+		args.Add("status", filter.Status)
+	}
+	list, err := d.cli.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
 	if err != nil {
-		return Container{}, err
+		return nil, err
+	}
+	out := make([]Container, 0, len(list))
+	for _, s := range list {
+		name := ""
+		if len(s.Names) > 0 {
+			name = strings.TrimPrefix(s.Names[0], "/")
+		}
+		c := Container{
+			ID:        s.ID,
+			Name:      name,
+			Image:     s.Image,
+			CreatedAt: s.Created,
+			Status:    dockerStatus(string(s.State)),
+		}
+		for _, p := range s.Ports {
+			if p.PublicPort == 0 {
+				continue
+			}
+			c.Ports = append(c.Ports, PortMapping{
+				ContainerPort: int(p.PrivatePort),
+				HostPort:      int(p.PublicPort),
+				HostIP:        p.IP,
+				Protocol:      p.Type,
+			})
+		}
+		sortPorts(c.Ports)
+		if filter.Match(c) {
+			out = append(out, c)
+		}
 	}
+	return out, nil
+}
 
+// Inspect 查詢單一容器的目前狀態。
+func (d *DockerProvider) Inspect(ctx context.Context, id string) (Container, error) {
+	info, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return Container{}, dockerError(err)
+	}
 	c := Container{
-		ID:        resp.ID,
-		Name:      name,
-		Image:     opts.Image,
-		CreatedAt: time.Now().Unix(),
-		Status:    "created",
+		ID:   info.ID,
+		Name: strings.TrimPrefix(info.Name, "/"),
+	}
+	if info.Config != nil {
+		c.Image = info.Config.Image
+	}
+	if info.State != nil {
+		c.Status = dockerStatus(string(info.State.Status))
+	}
+	if info.NetworkSettings != nil {
+		c.Ports = dockerPorts(info.NetworkSettings.Ports)
+	}
+	if t, err := time.Parse(time.RFC3339Nano, info.Created); err == nil {
+		c.CreatedAt = t.Unix()
 	}
 	return c, nil
 }
 
-func (d *DockerProvider) Start(id string) error {
-	ctx := context.Background()
-	return d.cli.ContainerStart(ctx, id, container.StartOptions{})
+// Logs 讀取容器日誌並將 Docker 的多工串流解成帶標記的行。
+func (d *DockerProvider) Logs(ctx context.Context, id string, opts LogsOptions) (LogReader, error) {
+	opts = opts.normalize()
+	info, err := d.cli.ContainerInspect(ctx, id)
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	lo := container.LogsOptions{
+		ShowStdout: opts.Stdout,
+		ShowStderr: opts.Stderr,
+		Follow:     opts.Follow,
+		Timestamps: opts.Timestamps,
+		Tail:       "all",
+	}
+	if opts.Tail >= 0 {
+		lo.Tail = strconv.Itoa(opts.Tail)
+	}
+	if !opts.Since.IsZero() {
+		lo.Since = opts.Since.Format(time.RFC3339Nano)
+	}
+	if !opts.Until.IsZero() {
+		lo.Until = opts.Until.Format(time.RFC3339Nano)
+	}
+	rc, err := d.cli.ContainerLogs(ctx, id, lo)
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	tty := info.Config != nil && info.Config.Tty
+	return newMuxLogReader(rc, tty, opts.Timestamps), nil
 }
 
-func (d *DockerProvider) Stop(id string) error {
-	ctx := context.Background()
-	timeout := 10 * time.Second
-	seconds := int(timeout.Seconds())
-	return d.cli.ContainerStop(ctx, id, container.StopOptions{Timeout: &seconds})
+// Attach 以 Tty 模式建立 exec 並回傳可雙向讀寫的連線。
+func (d *DockerProvider) Attach(ctx context.Context, id string, opts AttachOptions) (TTYSession, error) {
+	opts = opts.normalize()
+	var size *[2]uint
+	if opts.Cols > 0 && opts.Rows > 0 {
+		size = &[2]uint{opts.Rows, opts.Cols}
+	}
+	execResp, err := d.cli.ContainerExecCreate(ctx, id, container.ExecOptions{
+		AttachStdin:  true,
+		AttachStdout: true,
+		AttachStderr: true,
+		Tty:          true,
+		ConsoleSize:  size,
+		Cmd:          opts.Cmd,
+	})
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	hijack, err := d.cli.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{Tty: true, ConsoleSize: size})
+	if err != nil {
+		return nil, err
+	}
+	return &dockerTTYSession{cli: d.cli, execID: execResp.ID, hijack: hijack}, nil
+}
+
+// dockerTTYSession 包裝 exec attach 的 hijacked 連線；TTY 模式下輸出不經多工。
+type dockerTTYSession struct {
+	cli    *client.Client
+	execID string
+	hijack types.HijackedResponse
+}
+
+func (s *dockerTTYSession) Read(p []byte) (int, error)  { return s.hijack.Reader.Read(p) }
+func (s *dockerTTYSession) Write(p []byte) (int, error) { return s.hijack.Conn.Write(p) }
+
+func (s *dockerTTYSession) Close() error {
+	s.hijack.Close()
+	return nil
 }
 
-func (d *DockerProvider) Delete(id string) error {
-	ctx := context.Background()
-	return d.cli.ContainerRemove(ctx, id, container.RemoveOptions{Force: true})
+func (s *dockerTTYSession) Resize(cols, rows uint) error {
+	return s.cli.ContainerExecResize(context.Background(), s.execID, container.ResizeOptions{Width: cols, Height: rows})
 }
 
-// Exec runs a command inside an existing container.
-func (d *DockerProvider) Exec(id string, cmd []string) (int, string, error) {
-	ctx := context.Background()
+// applyLimits 將資源限制與安全設定寫入 Docker 的 Config 與 HostConfig。
+func applyLimits(r ResourceLimits, sec SecurityOptions, config *container.Config, hostConfig *container.HostConfig) {
+	hostConfig.CPUQuota = r.CPUQuota
+	hostConfig.CPUPeriod = r.CPUPeriod
+	hostConfig.CPUShares = r.CPUShares
+	hostConfig.Memory = r.Memory
+	hostConfig.MemorySwap = r.MemorySwap
+	if r.PidsLimit > 0 {
+		pids := r.PidsLimit
+		hostConfig.PidsLimit = &pids
+	}
+	for _, u := range r.Ulimits {
+		hostConfig.Ulimits = append(hostConfig.Ulimits, &container.Ulimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+	hostConfig.ReadonlyRootfs = sec.ReadOnlyRootfs
+	hostConfig.CapDrop = sec.CapDrop
+	if sec.NoNewPrivileges {
+		hostConfig.SecurityOpt = append(hostConfig.SecurityOpt, "no-new-privileges:true")
+	}
+	config.User = sec.userSpec()
+}
+
+// dockerStatus 將 Docker 的容器狀態對應到本服務的狀態字串。
+func dockerStatus(state string) string {
+	switch state {
+	case "exited", "dead":
+		return "stopped"
+	default:
+		return state
+	}
+}
+
+// Exec runs a command inside an existing container and captures stdout/stderr
+// separately, each capped at the configured output limit.
+func (d *DockerProvider) Exec(ctx context.Context, id string, cmd []string) (ExecResult, error) {
 	// Use container.ExecOptions for v28 SDK
 	execResp, err := d.cli.ContainerExecCreate(ctx, id, container.ExecOptions{
 		AttachStdout: true,
@@ -100,69 +454,320 @@ func (d *DockerProvider) Exec(id string, cmd []string) (int, string, error) {
 		Tty:          false,
 	})
 	if err != nil {
-		return 0, "", err
+		if cerrdefs.IsConflict(err) {
+			// Docker 以 409 回應對未執行容器的 exec
+			return ExecResult{}, ErrNotRunning
+		}
+		return ExecResult{}, dockerError(err)
+	}
+	// Attach 會同時啟動 exec，讀到 EOF 代表程序已結束輸出
+	hijack, err := d.cli.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{Tty: false})
+	if err != nil {
+		return ExecResult{}, dockerError(err)
+	}
+	defer hijack.Close()
+	// ctx 取消時關閉連線，讓阻塞中的讀取返回
+	stop := context.AfterFunc(ctx, hijack.Close)
+	defer stop()
+	stdout := newCappedBuffer(d.execOutputLimit)
+	stderr := newCappedBuffer(d.execOutputLimit)
+	if _, err := stdcopy.StdCopy(stdout, stderr, hijack.Reader); err != nil {
+		if ctx.Err() != nil {
+			return ExecResult{}, ctx.Err()
+		}
+		return ExecResult{}, err
 	}
-	// Start exec (no attach; logs not captured in this minimal variant)
-	if err := d.cli.ContainerExecStart(ctx, execResp.ID, container.ExecStartOptions{Detach: false, Tty: false}); err != nil {
-		return 0, "", err
+	res := ExecResult{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.Truncated() || stderr.Truncated(),
 	}
 
-	// Poll for exit code
+	// 串流結束後 exit code 可能尚未更新，短暫輪詢
 	for {
 		inspectResp, err := d.cli.ContainerExecInspect(ctx, execResp.ID)
 		if err != nil {
-			return 0, "", err
+			return res, dockerError(err)
 		}
 		if !inspectResp.Running {
-			return inspectResp.ExitCode, "", nil
+			res.ExitCode = inspectResp.ExitCode
+			return res, nil
+		}
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
 }
 
 // RunJob 實作一次性作業：綁定 host 資料夾並執行命令，回傳退出碼與日誌。
-func (d *DockerProvider) RunJob(opts JobOptions) (int64, string, error) {
-	ctx := context.Background()
-	if rc, err := d.cli.ImagePull(ctx, opts.Image, image.PullOptions{}); err == nil {
-		_, _ = io.Copy(io.Discard, rc)
-		_ = rc.Close()
+// 超過 opts.Timeout 時先以 GracePeriod 停止容器，再回傳 JobTimedOut 與終止前的日誌。
+func (d *DockerProvider) RunJob(ctx context.Context, opts JobOptions) (JobResult, error) {
+	if err := d.ensureImage(ctx, opts.Image); err != nil {
+		return JobResult{}, err
 	}
 
-	resp, err := d.cli.ContainerCreate(ctx, &container.Config{
+	config := &container.Config{
 		Image:      opts.Image,
 		Cmd:        opts.Cmd,
+		Env:        envList(opts.Env),
 		WorkingDir: opts.ContainerDir,
 		Tty:        false,
-	}, &container.HostConfig{
-		Mounts: []mount.Mount{{Type: mount.TypeBind, Source: opts.HostDir, Target: opts.ContainerDir, ReadOnly: false}},
-	}, nil, nil, "")
+	}
+	hostConfig := &container.HostConfig{
+		Mounts:      []mount.Mount{{Type: mount.TypeBind, Source: opts.HostDir, Target: opts.ContainerDir, ReadOnly: false}},
+		NetworkMode: container.NetworkMode(opts.network()),
+	}
+	applyLimits(opts.Resources, opts.Security, config, hostConfig)
+	resp, err := d.cli.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
 	if err != nil {
-		return 0, "", err
+		return JobResult{}, dockerError(err)
 	}
 	id := resp.ID
 	defer func() { _ = d.cli.ContainerRemove(context.Background(), id, container.RemoveOptions{Force: true}) }()
 
 	if err := d.cli.ContainerStart(ctx, id, container.StartOptions{}); err != nil {
-		return 0, "", err
+		return JobResult{}, dockerError(err)
 	}
-	// 等待結束
-	statusCh, errCh := d.cli.ContainerWait(ctx, id, container.WaitConditionNotRunning)
+	// 等待結束；ctx 取消時由上方的 defer 強制移除容器
+	waitCtx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+	statusCh, errCh := d.cli.ContainerWait(waitCtx, id, container.WaitConditionNotRunning)
 	select {
 	case st := <-statusCh:
-		// 讀取輸出
-		logsRc, err := d.cli.ContainerLogs(ctx, id, container.LogsOptions{ShowStdout: true, ShowStderr: true, Follow: false})
-		if err != nil {
-			return st.StatusCode, "", nil
+		// daemon 無法取得結束狀態時 StatusCode 不可信，回報錯誤而非一般的結束
+		if st.Error != nil {
+			return JobResult{}, errors.New("docker: wait " + id + ": " + st.Error.Message)
+		}
+		res := exitedJob(st.StatusCode, d.jobLogs(ctx, id))
+		if info, err := d.cli.ContainerInspect(ctx, id); err == nil && info.State != nil {
+			res.OOMKilled = info.State.OOMKilled
 		}
-		defer logsRc.Close()
-		b, _ := io.ReadAll(logsRc)
-		return st.StatusCode, string(b), nil
+		return res, nil
 	case err := <-errCh:
-		return 0, "", err
-	case <-time.After(10 * time.Minute):
-		_ = d.cli.ContainerStop(ctx, id, container.StopOptions{})
-		return -1, "timeout", nil
+		if ctx.Err() != nil {
+			return JobResult{}, ctx.Err()
+		}
+		if waitCtx.Err() != context.DeadlineExceeded {
+			return JobResult{}, dockerError(err)
+		}
+	}
+	// 逾時：送出 SIGTERM，寬限期後由 daemon 強制終止
+	seconds := int(opts.gracePeriod().Seconds())
+	_ = d.cli.ContainerStop(ctx, id, container.StopOptions{Timeout: &seconds})
+	res := JobResult{ExitCode: -1, Logs: d.jobLogs(ctx, id), Status: JobTimedOut}
+	if info, err := d.cli.ContainerInspect(ctx, id); err == nil && info.State != nil {
+		res.ExitCode = int64(info.State.ExitCode)
+	}
+	return res, nil
+}
+
+// jobLogs 讀取已結束容器的 stdout 與 stderr；讀取失敗時回傳空字串。
+func (d *DockerProvider) jobLogs(ctx context.Context, id string) string {
+	rc, err := d.cli.ContainerLogs(ctx, id, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	var buf bytes.Buffer
+	_, _ = stdcopy.StdCopy(&buf, &buf, rc)
+	return buf.String()
+}
+
+// BuildImage 將 ContextDir 打包為 tar（排除 .dockerignore 列出的檔案）交給 daemon 建置，
+// 並將建置輸出逐行傳給 progress；ctx 取消時中止建置。
+func (d *DockerProvider) BuildImage(ctx context.Context, opts BuildOptions, progress func(line string)) (BuildResult, error) {
+	excludes, err := dockerignore(opts.ContextDir)
+	if err != nil {
+		return BuildResult{}, err
+	}
+	// 與 docker build 相同：Dockerfile 與 .dockerignore 即使被排除仍需送出
+	excludes = append(excludes, "!"+filepath.ToSlash(opts.dockerfile()), "!.dockerignore")
+	buildCtx, err := archive.TarWithOptions(opts.ContextDir, &archive.TarOptions{ExcludePatterns: excludes})
+	if err != nil {
+		return BuildResult{}, err
+	}
+	defer buildCtx.Close()
+	args := make(map[string]*string, len(opts.BuildArgs))
+	for k, v := range opts.BuildArgs {
+		args[k] = &v
+	}
+	resp, err := d.cli.ImageBuild(ctx, buildCtx, build.ImageBuildOptions{
+		Tags:        []string{opts.Tag},
+		Dockerfile:  filepath.ToSlash(opts.dockerfile()),
+		BuildArgs:   args,
+		Target:      opts.Target,
+		NoCache:     opts.NoCache,
+		CacheFrom:   opts.CacheFrom,
+		PullParent:  opts.Pull,
+		Remove:      true,
+		ForceRemove: true,
+		Labels:      map[string]string{managedLabel: "true"},
+	})
+	if err != nil {
+		return BuildResult{}, dockerError(err)
+	}
+	defer resp.Body.Close()
+	stop := context.AfterFunc(ctx, func() { _ = resp.Body.Close() })
+	defer stop()
+	res := BuildResult{Image: opts.Tag}
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg jsonmessage.JSONMessage
+		if err := dec.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return BuildResult{}, ctx.Err()
+			}
+			if errors.Is(err, io.EOF) {
+				return res, nil
+			}
+			return BuildResult{}, err
+		}
+		switch {
+		case msg.Error != nil:
+			return BuildResult{}, &Error{Kind: ErrBuildFailed, Op: "build " + opts.Tag, Err: msg.Error}
+		case msg.Aux != nil:
+			var aux struct{ ID string }
+			if json.Unmarshal(*msg.Aux, &aux) == nil && aux.ID != "" {
+				res.ID = aux.ID
+			}
+		case msg.Stream != "":
+			for _, l := range splitLogLines(msg.Stream) {
+				progress(l)
+			}
+		case msg.Status != "" && msg.Progress == nil:
+			// 拉取基底映像的狀態；略過進度條
+			progress(strings.TrimSpace(msg.ID + " " + msg.Status))
+		}
+	}
+}
+
+// dockerignore 讀取 context 根目錄的 .dockerignore；檔案不存在時回傳 nil。
+func dockerignore(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ignorefile.ReadAll(f)
+}
+
+// PullImage 拉取映像並逐則回報進度，完成後回傳本機的映像資訊。
+func (d *DockerProvider) PullImage(ctx context.Context, ref string, progress func(PullProgress)) (Image, error) {
+	if err := d.pullImage(ctx, ref, progress); err != nil {
+		return Image{}, pullError(ref, err)
+	}
+	return d.InspectImage(ctx, ref)
+}
+
+// ListImages 列出本機映像；條件交由 Docker 篩選。
+func (d *DockerProvider) ListImages(ctx context.Context, filter ImageFilter) ([]Image, error) {
+	args := filters.NewArgs()
+	if filter.Reference != "" {
+		args.Add("reference", filter.Reference)
+	}
+	if filter.Dangling {
+		args.Add("dangling", "true")
+	}
+	if filter.Built {
+		args.Add("label", managedLabel+"=true")
+	}
+	list, err := d.cli.ImageList(ctx, image.ListOptions{Filters: args})
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	out := make([]Image, 0, len(list))
+	for _, s := range list {
+		out = append(out, newImage(s.ID, s.RepoTags, s.RepoDigests, s.Size, s.Created, s.Labels))
+	}
+	return out, nil
+}
+
+// InspectImage 回傳映像的詳細資訊；ref 可為名稱或 ID。
+func (d *DockerProvider) InspectImage(ctx context.Context, ref string) (Image, error) {
+	resp, err := d.cli.ImageInspect(ctx, ref)
+	if err != nil {
+		return Image{}, dockerError(err)
+	}
+	var created int64
+	if t, err := time.Parse(time.RFC3339Nano, resp.Created); err == nil {
+		created = t.Unix()
+	}
+	var labels map[string]string
+	if resp.Config != nil {
+		labels = resp.Config.Labels
 	}
+	img := newImage(resp.ID, resp.RepoTags, resp.RepoDigests, resp.Size, created, labels)
+	img.Architecture = resp.Architecture
+	img.OS = resp.Os
+	if c := resp.Config; c != nil {
+		img.Entrypoint = c.Entrypoint
+		img.Cmd = c.Cmd
+		img.Env = c.Env
+		img.WorkingDir = c.WorkingDir
+		for p := range c.ExposedPorts {
+			img.ExposedPorts = append(img.ExposedPorts, p)
+		}
+		sort.Strings(img.ExposedPorts)
+	}
+	return img, nil
 }
 
-var _ JobRunner = (*DockerProvider)(nil)
+// RemoveImage 移除 ref 的 tag，沒有其他 tag 時刪除映像；容器仍使用映像時 Docker 回報衝突，force 時一併移除。
+func (d *DockerProvider) RemoveImage(ctx context.Context, ref string, force bool) ([]ImageDeleted, error) {
+	resp, err := d.cli.ImageRemove(ctx, ref, image.RemoveOptions{Force: force, PruneChildren: true})
+	if err != nil {
+		return nil, dockerError(err)
+	}
+	return imageDeleted(resp), nil
+}
+
+// PruneImages 清除未使用的映像。
+func (d *DockerProvider) PruneImages(ctx context.Context, opts PruneImagesOptions) (PruneReport, error) {
+	args := filters.NewArgs(filters.Arg("dangling", strconv.FormatBool(!opts.All)))
+	if opts.Until > 0 {
+		args.Add("until", opts.Until.String())
+	}
+	if opts.Built {
+		args.Add("label", managedLabel+"=true")
+	}
+	resp, err := d.cli.ImagesPrune(ctx, args)
+	if err != nil {
+		return PruneReport{}, dockerError(err)
+	}
+	return PruneReport{Deleted: imageDeleted(resp.ImagesDeleted), SpaceReclaimed: resp.SpaceReclaimed}, nil
+}
+
+func newImage(id string, tags, digests []string, size, created int64, labels map[string]string) Image {
+	// 沒有 tag 的映像 Docker 以 <none>:<none> 表示
+	var t []string
+	for _, tag := range tags {
+		if tag != "<none>:<none>" {
+			t = append(t, tag)
+		}
+	}
+	if t == nil {
+		t = []string{}
+	}
+	return Image{ID: id, Tags: t, Digests: digests, Size: size, CreatedAt: created, Labels: labels, Built: labels[managedLabel] == "true"}
+}
+
+func imageDeleted(resp []image.DeleteResponse) []ImageDeleted {
+	out := make([]ImageDeleted, 0, len(resp))
+	for _, r := range resp {
+		out = append(out, ImageDeleted{Untagged: r.Untagged, Deleted: r.Deleted})
+	}
+	return out
+}
+
+var (
+	_ JobRunner    = (*DockerProvider)(nil)
+	_ LogStreamer  = (*DockerProvider)(nil)
+	_ Attacher     = (*DockerProvider)(nil)
+	_ ImageBuilder = (*DockerProvider)(nil)
+	_ ImageManager = (*DockerProvider)(nil)
+)
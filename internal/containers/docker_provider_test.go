@@ -0,0 +1,91 @@
+package containers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/docker/go-connections/nat"
+)
+
+func TestCreateConfig_KeepAliveOnlyWithoutCommand(t *testing.T) {
+	config, _, _ := createConfig(CreateOptions{Image: "alpine:3.20"})
+	if !reflect.DeepEqual([]string(config.Cmd), []string{"tail", "-f", "/dev/null"}) {
+		t.Fatalf("expected keep-alive cmd, got %v", config.Cmd)
+	}
+
+	config, hostConfig, _ := createConfig(CreateOptions{
+		Image:      "nginx:1.27",
+		Env:        map[string]string{"B": "2", "A": "1"},
+		Entrypoint: []string{"nginx"},
+		Cmd:        []string{"-g", "daemon off;"},
+		WorkingDir: "/srv",
+		Labels:     map[string]string{"team": "web", managedLabel: "false"},
+		Hostname:   "web-1",
+		Resources:  ResourceLimits{Memory: 64 << 20, PidsLimit: 50},
+		Security:   SecurityOptions{User: "101", Group: "101", NoNewPrivileges: true},
+	})
+	if !reflect.DeepEqual(config.Env, []string{"A=1", "B=2"}) {
+		t.Fatalf("env: %v", config.Env)
+	}
+	if !reflect.DeepEqual([]string(config.Cmd), []string{"-g", "daemon off;"}) || config.Entrypoint[0] != "nginx" {
+		t.Fatalf("cmd/entrypoint: %v %v", config.Cmd, config.Entrypoint)
+	}
+	if config.WorkingDir != "/srv" || config.Hostname != "web-1" || config.User != "101:101" {
+		t.Fatalf("config: %+v", config)
+	}
+	if config.Labels["team"] != "web" || config.Labels[managedLabel] != "true" {
+		t.Fatalf("labels: %v", config.Labels)
+	}
+	if hostConfig.Memory != 64<<20 || *hostConfig.PidsLimit != 50 || hostConfig.SecurityOpt[0] != "no-new-privileges:true" {
+		t.Fatalf("hostConfig: %+v", hostConfig)
+	}
+
+	// 只指定 entrypoint 時不補 keep-alive
+	config, _, _ = createConfig(CreateOptions{Image: "alpine:3.20", Entrypoint: []string{"sleep", "60"}})
+	if len(config.Cmd) != 0 {
+		t.Fatalf("unexpected cmd %v", config.Cmd)
+	}
+}
+
+func TestCreateConfig_PortsAndNetwork(t *testing.T) {
+	config, hostConfig, netConfig := createConfig(CreateOptions{
+		Image: "nginx:1.27",
+		Ports: []PortMapping{
+			{ContainerPort: 80},
+			{ContainerPort: 53, HostPort: 5353, HostIP: "127.0.0.1", Protocol: "udp"},
+		},
+		Network: NetworkOptions{Network: "apps", Aliases: []string{"web"}, DNS: []string{"1.1.1.1"}, DNSSearch: []string{"svc.local"}},
+	})
+	if _, ok := config.ExposedPorts["80/tcp"]; !ok {
+		t.Fatalf("exposed ports: %v", config.ExposedPorts)
+	}
+	if b := hostConfig.PortBindings["80/tcp"]; len(b) != 1 || b[0].HostPort != "" {
+		t.Fatalf("expected auto-assigned binding, got %v", b)
+	}
+	if b := hostConfig.PortBindings["53/udp"]; len(b) != 1 || b[0].HostPort != "5353" || b[0].HostIP != "127.0.0.1" {
+		t.Fatalf("unexpected udp binding %v", b)
+	}
+	if hostConfig.NetworkMode != "apps" || hostConfig.DNS[0] != "1.1.1.1" || hostConfig.DNSSearch[0] != "svc.local" {
+		t.Fatalf("hostConfig: %+v", hostConfig)
+	}
+	if netConfig == nil || netConfig.EndpointsConfig["apps"].Aliases[0] != "web" {
+		t.Fatalf("netConfig: %+v", netConfig)
+	}
+
+	// 未指定網路時沿用 Docker 預設
+	_, hostConfig, netConfig = createConfig(CreateOptions{Image: "alpine:3.20"})
+	if hostConfig.NetworkMode != "" || netConfig != nil || hostConfig.PortBindings != nil {
+		t.Fatalf("unexpected defaults: %+v %+v", hostConfig, netConfig)
+	}
+}
+
+func TestDockerPorts_SkipsUnpublished(t *testing.T) {
+	got := dockerPorts(nat.PortMap{
+		"8080/tcp": {{HostIP: "0.0.0.0", HostPort: "32768"}, {HostIP: "::", HostPort: "32768"}},
+		"53/udp":   {{HostIP: "0.0.0.0", HostPort: "5353"}},
+		"9000/tcp": nil,
+	})
+	if len(got) != 3 || got[0].ContainerPort != 53 || got[0].Protocol != "udp" || got[1].HostPort != 32768 || got[1].HostIP != "0.0.0.0" {
+		t.Fatalf("unexpected ports %+v", got)
+	}
+}
@@ -0,0 +1,120 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrorCode 錯誤分類；API 依此決定 HTTP 狀態碼，並放在錯誤回應的 code 欄位。
+type ErrorCode string
+
+const (
+	CodeNotFound        ErrorCode = "not_found"
+	CodeConflict        ErrorCode = "conflict"
+	CodeInvalidArgument ErrorCode = "invalid_argument"
+	CodeUnsupported     ErrorCode = "unsupported"
+	CodeImagePullFailed ErrorCode = "image_pull_failed"
+	CodeImageNotFound   ErrorCode = "image_not_found"
+	CodeBuildFailed     ErrorCode = "build_failed"
+	CodeTimeout         ErrorCode = "timeout"
+	CodeQuotaExceeded   ErrorCode = "quota_exceeded"
+	CodeInternal        ErrorCode = "internal"
+)
+
+// kindError 帶分類的 sentinel。同一分類可有多個 sentinel（例如 ErrNotRunning 與
+// ErrInvalidState 皆屬 conflict）；generic 的 sentinel 可用 errors.Is 比對整個分類。
+type kindError struct {
+	code    ErrorCode
+	msg     string
+	generic bool
+}
+
+func (e *kindError) Error() string   { return e.msg }
+func (e *kindError) Code() ErrorCode { return e.code }
+
+func (e *kindError) Is(target error) bool {
+	t, ok := target.(*kindError)
+	return ok && t.generic && t.code == e.code
+}
+
+var (
+	ErrNotFound error = &kindError{code: CodeNotFound, msg: "container not found", generic: true}
+	// ErrConflict 與目前狀態衝突；errors.Is(err, ErrConflict) 對 ErrNotRunning、ErrInvalidState 也成立。
+	ErrConflict error = &kindError{code: CodeConflict, msg: "conflict", generic: true}
+	// ErrNotRunning 操作（例如 exec）需要容器在執行中。
+	ErrNotRunning error = &kindError{code: CodeConflict, msg: "container is not running"}
+	// ErrInvalidState 容器目前的狀態不允許此操作，例如啟動執行中的容器。
+	ErrInvalidState error = &kindError{code: CodeConflict, msg: "invalid container state"}
+	// ErrInvalidOptions 參數格式錯誤。
+	ErrInvalidOptions error = &kindError{code: CodeInvalidArgument, msg: "invalid options", generic: true}
+	ErrNotSupported   error = &kindError{code: CodeUnsupported, msg: "operation not supported by provider", generic: true}
+	// ErrImagePull 無法拉取映像（連線、認證或 registry 錯誤），且本機也沒有該映像。
+	ErrImagePull error = &kindError{code: CodeImagePullFailed, msg: "image pull failed", generic: true}
+	// ErrImageNotFound registry 上沒有此映像或 tag，通常是映像名稱打錯。
+	ErrImageNotFound error = &kindError{code: CodeImageNotFound, msg: "image not found in registry", generic: true}
+	// ErrBuildFailed Dockerfile 的步驟失敗或語法錯誤。
+	ErrBuildFailed error = &kindError{code: CodeBuildFailed, msg: "image build failed", generic: true}
+	ErrTimeout     error = &kindError{code: CodeTimeout, msg: "operation timed out", generic: true}
+	// ErrLimitExceeded 要求的資源超過伺服器設定的上限。
+	ErrLimitExceeded error = &kindError{code: CodeQuotaExceeded, msg: "resource limit exceeded", generic: true}
+)
+
+// Error 以分類 sentinel 包裝底層錯誤（例如 Docker errdefs）；
+// errors.Is 可同時比對 Kind 與原始錯誤。
+type Error struct {
+	Kind error  // ErrNotFound、ErrConflict 等 sentinel
+	Op   string // 可選：失敗的操作，例如 "pull alpine:3.20"
+	Err  error  // 原始錯誤
+}
+
+func (e *Error) Error() string {
+	msg := e.Kind.Error()
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if e.Op != "" {
+		return e.Op + ": " + msg
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() []error { return []error{e.Kind, e.Err} }
+
+// StateError 描述非法的生命週期轉換；errors.Is 可比對 ErrInvalidState 與 ErrConflict，
+// 需要執行中容器的操作（exec、attach）另可比對 ErrNotRunning。
+type StateError struct {
+	ID    string
+	Op    string // start|stop|exec|attach|...
+	State string // 容器目前狀態
+}
+
+func (e *StateError) Error() string {
+	return fmt.Sprintf("cannot %s container %s: container is %s", e.Op, e.ID, e.State)
+}
+
+func (e *StateError) Code() ErrorCode { return CodeConflict }
+
+func (e *StateError) Is(target error) bool {
+	switch target {
+	case ErrInvalidState, ErrConflict:
+		return true
+	case ErrNotRunning:
+		return e.State != "running"
+	}
+	return false
+}
+
+// CodeOf 回傳 err 的分類；context 逾時視為 CodeTimeout，無法分類者為 CodeInternal。
+func CodeOf(err error) ErrorCode {
+	var coded interface{ Code() ErrorCode }
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &coded):
+		return coded.Code()
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout
+	}
+	return CodeInternal
+}
@@ -0,0 +1,75 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	cerrdefs "github.com/containerd/errdefs"
+)
+
+func TestErrorKinds(t *testing.T) {
+	if !errors.Is(ErrNotRunning, ErrConflict) || !errors.Is(ErrInvalidState, ErrConflict) {
+		t.Fatalf("ErrNotRunning and ErrInvalidState should match ErrConflict")
+	}
+	if errors.Is(ErrConflict, ErrNotRunning) || errors.Is(ErrInvalidState, ErrNotRunning) {
+		t.Fatalf("specific conflict sentinels must not match each other")
+	}
+	state := &StateError{ID: "c1", Op: "start", State: "running"}
+	if !errors.Is(state, ErrConflict) || errors.Is(state, ErrNotRunning) || CodeOf(state) != CodeConflict {
+		t.Fatalf("unexpected StateError classification")
+	}
+	wrapped := fmt.Errorf("%w: cpus 4 > 2", ErrLimitExceeded)
+	if CodeOf(wrapped) != CodeQuotaExceeded {
+		t.Fatalf("CodeOf(%v) = %s", wrapped, CodeOf(wrapped))
+	}
+	if CodeOf(context.DeadlineExceeded) != CodeTimeout || CodeOf(errors.New("x")) != CodeInternal {
+		t.Fatalf("unexpected CodeOf fallback")
+	}
+}
+
+func TestDockerError_WrapsErrdefs(t *testing.T) {
+	cases := []struct {
+		err  error
+		kind error
+		code ErrorCode
+	}{
+		{fmt.Errorf("No such container: x: %w", cerrdefs.ErrNotFound), ErrNotFound, CodeNotFound},
+		{fmt.Errorf("name in use: %w", cerrdefs.ErrConflict), ErrConflict, CodeConflict},
+		{fmt.Errorf("bad mount: %w", cerrdefs.ErrInvalidArgument), ErrInvalidOptions, CodeInvalidArgument},
+		{fmt.Errorf("no gpu: %w", cerrdefs.ErrNotImplemented), ErrNotSupported, CodeUnsupported},
+		{fmt.Errorf("disk full: %w", cerrdefs.ErrResourceExhausted), ErrLimitExceeded, CodeQuotaExceeded},
+	}
+	for _, tc := range cases {
+		got := dockerError(tc.err)
+		if !errors.Is(got, tc.kind) || !errors.Is(got, tc.err) || CodeOf(got) != tc.code {
+			t.Fatalf("dockerError(%v) = %v (code %s)", tc.err, got, CodeOf(got))
+		}
+		if got.Error() != tc.err.Error() {
+			t.Fatalf("message should come from the Docker error, got %q", got.Error())
+		}
+	}
+	plain := errors.New("daemon exploded")
+	if dockerError(plain) != plain || dockerError(nil) != nil {
+		t.Fatalf("unclassified errors should pass through")
+	}
+}
+
+func TestPullError(t *testing.T) {
+	missing := fmt.Errorf("pull access denied for nope, repository does not exist: %w", cerrdefs.ErrNotFound)
+	if err := pullError("nope", missing); !errors.Is(err, ErrImageNotFound) || errors.Is(err, ErrImagePull) || CodeOf(err) != CodeImageNotFound {
+		t.Fatalf("expected ErrImageNotFound, got %v", err)
+	}
+	// 進度串流中的錯誤沒有 errdefs 分類，依訊息判斷
+	if err := pullError("alpine:nope", errors.New("manifest for alpine:nope not found: manifest unknown")); !errors.Is(err, ErrImageNotFound) {
+		t.Fatalf("expected ErrImageNotFound from stream message, got %v", err)
+	}
+	offline := errors.New("dial tcp: lookup registry-1.docker.io: no such host")
+	if err := pullError("alpine", offline); !errors.Is(err, ErrImagePull) || !errors.Is(err, offline) || err.Error() != "pull alpine: "+offline.Error() {
+		t.Fatalf("expected ErrImagePull, got %v", err)
+	}
+	if err := pullError("alpine", context.Canceled); err != context.Canceled {
+		t.Fatalf("cancellation should pass through, got %v", err)
+	}
+}
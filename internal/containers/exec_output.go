@@ -0,0 +1,60 @@
+package containers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// DefaultExecOutputLimit 單一 exec 每個輸出串流保留的最大位元組數。
+const DefaultExecOutputLimit = 1 << 20
+
+// ExecResult 為 exec 的執行結果，stdout 與 stderr 分開保存。
+type ExecResult struct {
+	ExitCode  int    `json:"exitCode"`
+	Stdout    string `json:"stdout"`
+	Stderr    string `json:"stderr"`
+	Truncated bool   `json:"truncated,omitempty"` // 任一串流超過上限被截斷
+}
+
+// execOutputLimitFromEnv 讀取 EXEC_OUTPUT_LIMIT（位元組），未設定或格式錯誤時使用預設值。
+func execOutputLimitFromEnv() int {
+	if v := os.Getenv("EXEC_OUTPUT_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return DefaultExecOutputLimit
+}
+
+// cappedBuffer 只保留前 limit 個位元組，其餘丟棄但持續計數，
+// 讓底層串流可以被完整讀完而不會卡住 exec。
+type cappedBuffer struct {
+	limit   int
+	buf     []byte
+	dropped int
+}
+
+func newCappedBuffer(limit int) *cappedBuffer { return &cappedBuffer{limit: limit} }
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	room := b.limit - len(b.buf)
+	if room > len(p) {
+		room = len(p)
+	}
+	if room > 0 {
+		b.buf = append(b.buf, p[:room]...)
+	}
+	b.dropped += len(p) - room
+	return len(p), nil
+}
+
+func (b *cappedBuffer) Truncated() bool { return b.dropped > 0 }
+
+// String 回傳內容；若有截斷則附上標記。
+func (b *cappedBuffer) String() string {
+	if b.dropped == 0 {
+		return string(b.buf)
+	}
+	return string(b.buf) + fmt.Sprintf("\n...[truncated %d bytes]", b.dropped)
+}
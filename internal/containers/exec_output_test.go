@@ -0,0 +1,24 @@
+package containers
+
+import "testing"
+
+func TestCappedBuffer_TruncatesWithMarker(t *testing.T) {
+	b := newCappedBuffer(5)
+	for _, chunk := range []string{"abc", "defg", "hij"} {
+		if n, err := b.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("write %q: n=%d err=%v", chunk, n, err)
+		}
+	}
+	if !b.Truncated() {
+		t.Fatalf("expected truncated")
+	}
+	if got, want := b.String(), "abcde\n...[truncated 5 bytes]"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	small := newCappedBuffer(10)
+	_, _ = small.Write([]byte("ok"))
+	if small.Truncated() || small.String() != "ok" {
+		t.Fatalf("unexpected: %q", small.String())
+	}
+}
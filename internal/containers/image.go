@@ -0,0 +1,115 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/distribution/reference"
+)
+
+// Image 本機的映像；架構、作業系統與預設設定只在 InspectImage 時填入。
+type Image struct {
+	ID        string            `json:"id"`
+	Tags      []string          `json:"tags"`
+	Digests   []string          `json:"digests,omitempty"`
+	Size      int64             `json:"size"`
+	CreatedAt int64             `json:"createdAt"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Built     bool              `json:"built"` // 由本服務建置（POST /v1/images/build）
+
+	Architecture string   `json:"architecture,omitempty"`
+	OS           string   `json:"os,omitempty"`
+	Entrypoint   []string `json:"entrypoint,omitempty"`
+	Cmd          []string `json:"cmd,omitempty"`
+	Env          []string `json:"env,omitempty"`
+	WorkingDir   string   `json:"workingDir,omitempty"`
+	ExposedPorts []string `json:"exposedPorts,omitempty"`
+}
+
+// ImageFilter 列出映像的條件；零值列出所有映像。
+type ImageFilter struct {
+	Reference string // repository 或 repository:tag，可用 * 萬用字元，例如 container-manager/*
+	Dangling  bool   // 只列出沒有 tag 的映像
+	Built     bool   // 只列出本服務建置的映像
+}
+
+// PullProgress 拉取映像時的一則進度；ID 為 layer，Current/Total 為下載或解壓縮的位元組數。
+type PullProgress struct {
+	ID      string `json:"id,omitempty"`
+	Status  string `json:"status"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+// ImageDeleted 刪除映像的一個步驟：移除 tag 或刪除映像本身。
+type ImageDeleted struct {
+	Untagged string `json:"untagged,omitempty"`
+	Deleted  string `json:"deleted,omitempty"`
+}
+
+// PruneImagesOptions 清除映像的條件；預設只清除沒有 tag 的映像。
+type PruneImagesOptions struct {
+	All   bool          `json:"all"`   // 一併清除沒有容器使用的映像
+	Until time.Duration `json:"until"` // 只清除建立超過此時間的映像
+	Built bool          `json:"built"` // 只清除本服務建置的映像
+}
+
+// PruneReport 清除的映像與釋放的空間。
+type PruneReport struct {
+	Deleted        []ImageDeleted `json:"deleted"`
+	SpaceReclaimed uint64         `json:"spaceReclaimed"`
+}
+
+// ImageManager 可選介面：管理本機映像。PullImage 失敗時，registry 上不存在的映像回傳可比對
+// ErrImageNotFound 的錯誤，其他原因（連線、認證等）回傳可比對 ErrImagePull 的錯誤；
+// 本機找不到 ref 時 InspectImage 與 RemoveImage 回傳 ErrNotFound，映像仍被容器使用時
+// RemoveImage 回傳 ErrConflict（force 除外）。
+type ImageManager interface {
+	PullImage(ctx context.Context, ref string, progress func(PullProgress)) (Image, error)
+	ListImages(ctx context.Context, filter ImageFilter) ([]Image, error)
+	InspectImage(ctx context.Context, ref string) (Image, error)
+	RemoveImage(ctx context.Context, ref string, force bool) ([]ImageDeleted, error)
+	PruneImages(ctx context.Context, opts PruneImagesOptions) (PruneReport, error)
+}
+
+// NormalizeImageRef 檢查映像名稱並補上預設的 tag，例如 alpine 轉為 alpine:latest；
+// Docker Hub 的映像維持簡短的寫法。
+func NormalizeImageRef(ref string) (string, error) {
+	named, err := reference.ParseNormalizedNamed(strings.TrimSpace(ref))
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid image reference %q: %v", ErrInvalidOptions, ref, err)
+	}
+	return reference.FamiliarString(reference.TagNameOnly(named)), nil
+}
+
+// pullError 分類拉取失敗的原因：registry 上沒有此映像（含 Docker Hub 對不存在的 repository 回報的
+// pull access denied）為 ErrImageNotFound，其餘為 ErrImagePull；context 取消或逾時原樣回傳。
+// Docker 的錯誤依 errdefs 分類，Podman 的錯誤依狀態碼或訊息分類。
+func pullError(ref string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	kind := ErrImagePull
+	switch {
+	case cerrdefs.IsInvalidArgument(err) || CodeOf(err) == CodeInvalidArgument:
+		kind = ErrInvalidOptions
+	case cerrdefs.IsNotFound(err) || errors.Is(err, ErrNotFound) || imageNotFoundMessage(err.Error()):
+		kind = ErrImageNotFound
+	}
+	return &Error{Kind: kind, Op: "pull " + ref, Err: err}
+}
+
+// imageNotFoundMessage 判斷進度串流中的錯誤訊息（沒有 errdefs 分類）是否代表映像不存在。
+func imageNotFoundMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	for _, s := range []string{"manifest unknown", "not found", "repository does not exist", "name unknown"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,232 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	// DefaultJobTimeout 未指定 Timeout 且伺服器未設定 JOB_TIMEOUT 時的作業逾時。
+	DefaultJobTimeout = 10 * time.Minute
+	// DefaultJobGracePeriod 逾時後送出 SIGTERM 到強制終止之間的等待時間。
+	DefaultJobGracePeriod = 10 * time.Second
+)
+
+// JobStatus 一次性作業的結束狀態。
+type JobStatus string
+
+const (
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"    // exit code 非 0
+	JobTimedOut  JobStatus = "timed_out" // 超過 Timeout 被終止，Logs 為終止前的輸出
+)
+
+// JobOptions 定義一次性作業的參數：將主機資料夾掛載進容器並執行命令。
+type JobOptions struct {
+	Image        string            `json:"image"`
+	HostDir      string            `json:"hostDir"`
+	ContainerDir string            `json:"containerDir"`
+	Cmd          []string          `json:"cmd"`
+	Env          map[string]string `json:"env"`
+	Network      string            `json:"network"` // none（預設）|bridge|host|自訂網路名稱
+	Resources    ResourceLimits    `json:"resources"`
+	Security     SecurityOptions   `json:"security"`
+	Timeout      time.Duration     `json:"timeout"`     // 零值使用伺服器預設值
+	GracePeriod  time.Duration     `json:"gracePeriod"` // 零值使用 DefaultJobGracePeriod
+	Retry        *RetryPolicy      `json:"retry,omitempty"`
+	Build        *BuildOptions     `json:"build,omitempty"` // 非 nil 時先建置映像，Image 為建置的 tag
+}
+
+// Validate 檢查作業參數的格式。
+func (o JobOptions) Validate() error {
+	if err := validateEnv(o.Env); err != nil {
+		return err
+	}
+	if o.Timeout < 0 || o.GracePeriod < 0 {
+		return fmt.Errorf("%w: timeout and grace period must not be negative", ErrInvalidOptions)
+	}
+	if err := o.Retry.Validate(); err != nil {
+		return err
+	}
+	if o.Build != nil {
+		if err := o.Build.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := o.Resources.Validate(); err != nil {
+		return err
+	}
+	return o.Security.Validate()
+}
+
+func (o JobOptions) network() string {
+	if o.Network == "" {
+		return "none"
+	}
+	return o.Network
+}
+
+func (o JobOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return DefaultJobTimeout
+	}
+	return o.Timeout
+}
+
+func (o JobOptions) gracePeriod() time.Duration {
+	if o.GracePeriod <= 0 {
+		return DefaultJobGracePeriod
+	}
+	return o.GracePeriod
+}
+
+// JobResult 一次性作業的結果；logs 為 stdout 與 stderr 的合併內容。
+type JobResult struct {
+	ExitCode  int64     `json:"exitCode"`
+	Logs      string    `json:"logs"`
+	Status    JobStatus `json:"status"`
+	OOMKilled bool      `json:"oomKilled,omitempty"` // 因超過記憶體上限被終止
+}
+
+// exitedJob 依 exit code 決定作業成功或失敗。
+func exitedJob(code int64, logs string) JobResult {
+	status := JobSucceeded
+	if code != 0 {
+		status = JobFailed
+	}
+	return JobResult{ExitCode: code, Logs: logs, Status: status}
+}
+
+// MaxJobAttempts RetryPolicy.MaxAttempts 的上限。
+const MaxJobAttempts = 10
+
+// RetryPolicy 作業失敗時的重試設定；符合任一條件才重試，逾時的作業不重試。
+// 第 n 次重試前等待 InitialBackoff * Multiplier^(n-1)，不超過 MaxBackoff。
+type RetryPolicy struct {
+	MaxAttempts    int           `json:"maxAttempts"`    // 含第一次執行的總次數
+	InitialBackoff time.Duration `json:"initialBackoff"` // 預設 1s
+	MaxBackoff     time.Duration `json:"maxBackoff"`     // 預設 5m
+	Multiplier     float64       `json:"multiplier"`     // 預設 2
+	ExitCodes      []int64       `json:"exitCodes"`      // 以這些非 0 exit code 結束時重試
+	OOMKilled      bool          `json:"oomKilled"`      // 因記憶體不足被終止時重試
+	ProviderError  bool          `json:"providerError"`  // 映像拉取失敗等 provider 錯誤時重試
+}
+
+// Validate 檢查重試設定；nil 代表不重試。
+func (p *RetryPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.MaxAttempts < 0 || p.MaxAttempts > MaxJobAttempts {
+		return fmt.Errorf("%w: maxAttempts must be between 0 and %d", ErrInvalidOptions, MaxJobAttempts)
+	}
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 || p.Multiplier < 0 || (p.Multiplier > 0 && p.Multiplier < 1) {
+		return fmt.Errorf("%w: backoff must not be negative and multiplier must be at least 1", ErrInvalidOptions)
+	}
+	for _, code := range p.ExitCodes {
+		if code == 0 {
+			return fmt.Errorf("%w: retry exit codes must be non-zero", ErrInvalidOptions)
+		}
+	}
+	return nil
+}
+
+// Attempts 回傳最多執行的次數，至少為 1。
+func (p *RetryPolicy) Attempts() int {
+	if p == nil || p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Retryable 回傳一次執行的結果是否符合重試條件。
+func (p *RetryPolicy) Retryable(res JobResult, err error) bool {
+	if p == nil {
+		return false
+	}
+	if err != nil {
+		// 參數或上限錯誤、不存在的映像與建置失敗重試也不會成功
+		switch CodeOf(err) {
+		case CodeInvalidArgument, CodeQuotaExceeded, CodeUnsupported, CodeImageNotFound, CodeBuildFailed:
+			return false
+		}
+		return p.ProviderError && !errors.Is(err, context.Canceled)
+	}
+	if res.Status != JobFailed {
+		return false
+	}
+	if res.OOMKilled {
+		return p.OOMKilled
+	}
+	for _, code := range p.ExitCodes {
+		if res.ExitCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff 回傳第 retry 次重試（從 1 起算）前的等待時間。
+func (p *RetryPolicy) Backoff(retry int) time.Duration {
+	d, limit, mult := time.Second, 5*time.Minute, 2.0
+	if p != nil {
+		if p.InitialBackoff > 0 {
+			d = p.InitialBackoff
+		}
+		if p.MaxBackoff > 0 {
+			limit = p.MaxBackoff
+		}
+		if p.Multiplier > 0 {
+			mult = p.Multiplier
+		}
+	}
+	for i := 1; i < retry && d < limit; i++ {
+		d = time.Duration(float64(d) * mult)
+	}
+	return min(d, limit)
+}
+
+// JobRunner 可選介面：支援一次性作業。超過 opts.Timeout 時回傳 Status 為 JobTimedOut 的結果而非錯誤。
+type JobRunner interface {
+	RunJob(ctx context.Context, opts JobOptions) (JobResult, error)
+}
+
+// JobLimits 伺服器端的作業逾時設定；零值欄位代表使用內建預設值或不限制。
+type JobLimits struct {
+	DefaultTimeout time.Duration
+	MaxTimeout     time.Duration
+}
+
+// JobLimitsFromEnv 讀取 JOB_TIMEOUT 與 JOB_MAX_TIMEOUT（例如 30m、2h）。
+func JobLimitsFromEnv() JobLimits {
+	var l JobLimits
+	if d, err := time.ParseDuration(os.Getenv("JOB_TIMEOUT")); err == nil && d > 0 {
+		l.DefaultTimeout = d
+	}
+	if d, err := time.ParseDuration(os.Getenv("JOB_MAX_TIMEOUT")); err == nil && d > 0 {
+		l.MaxTimeout = d
+	}
+	return l
+}
+
+// Apply 補上預設的逾時與寬限時間；要求的逾時超過上限時回傳 ErrLimitExceeded。
+func (l JobLimits) Apply(o *JobOptions) error {
+	if o.Timeout == 0 {
+		o.Timeout = l.DefaultTimeout
+		if o.Timeout == 0 {
+			o.Timeout = DefaultJobTimeout
+		}
+		if l.MaxTimeout > 0 && o.Timeout > l.MaxTimeout {
+			o.Timeout = l.MaxTimeout
+		}
+	} else if l.MaxTimeout > 0 && o.Timeout > l.MaxTimeout {
+		return fmt.Errorf("%w: timeout %s > %s", ErrLimitExceeded, o.Timeout, l.MaxTimeout)
+	}
+	if o.GracePeriod == 0 {
+		o.GracePeriod = DefaultJobGracePeriod
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	p := &RetryPolicy{MaxAttempts: 3, ExitCodes: []int64{75}, OOMKilled: true, ProviderError: true}
+	cases := []struct {
+		name string
+		res  JobResult
+		err  error
+		want bool
+	}{
+		{"listed exit code", JobResult{ExitCode: 75, Status: JobFailed}, nil, true},
+		{"other exit code", JobResult{ExitCode: 1, Status: JobFailed}, nil, false},
+		{"oom killed", JobResult{ExitCode: 137, Status: JobFailed, OOMKilled: true}, nil, true},
+		{"timed out", JobResult{ExitCode: 75, Status: JobTimedOut}, nil, false},
+		{"succeeded", JobResult{Status: JobSucceeded}, nil, false},
+		{"image pull", JobResult{}, &Error{Kind: ErrImagePull, Op: "pull alpine"}, true},
+		{"image not found", JobResult{}, &Error{Kind: ErrImageNotFound, Op: "pull alpne"}, false},
+		{"build failed", JobResult{}, &Error{Kind: ErrBuildFailed, Op: "build x"}, false},
+		{"invalid options", JobResult{}, ErrInvalidOptions, false},
+		{"limit exceeded", JobResult{}, ErrLimitExceeded, false},
+		{"cancelled", JobResult{}, context.Canceled, false},
+	}
+	for _, tc := range cases {
+		if got := p.Retryable(tc.res, tc.err); got != tc.want {
+			t.Fatalf("%s: Retryable=%v want %v", tc.name, got, tc.want)
+		}
+	}
+	if (&RetryPolicy{ExitCodes: []int64{75}}).Retryable(JobResult{ExitCode: 137, Status: JobFailed, OOMKilled: true}, nil) {
+		t.Fatalf("oom retried without oomKilled condition")
+	}
+	var none *RetryPolicy
+	if none.Retryable(JobResult{ExitCode: 1, Status: JobFailed}, nil) || none.Attempts() != 1 {
+		t.Fatalf("nil policy should not retry")
+	}
+}
+
+func TestRetryPolicy_BackoffAndValidate(t *testing.T) {
+	p := &RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second, Multiplier: 3}
+	for retry, want := range map[int]time.Duration{1: time.Second, 2: 3 * time.Second, 3: 5 * time.Second, 8: 5 * time.Second} {
+		if got := p.Backoff(retry); got != want {
+			t.Fatalf("Backoff(%d)=%s want %s", retry, got, want)
+		}
+	}
+	if got := (&RetryPolicy{}).Backoff(2); got != 2*time.Second {
+		t.Fatalf("default backoff=%s", got)
+	}
+
+	for name, bad := range map[string]*RetryPolicy{
+		"too many attempts": {MaxAttempts: MaxJobAttempts + 1},
+		"zero exit code":    {MaxAttempts: 2, ExitCodes: []int64{0}},
+		"multiplier":        {MaxAttempts: 2, Multiplier: 0.5},
+		"negative backoff":  {MaxAttempts: 2, InitialBackoff: -time.Second},
+	} {
+		if err := (JobOptions{Retry: bad}).Validate(); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("%s: expected ErrInvalidOptions, got %v", name, err)
+		}
+	}
+}
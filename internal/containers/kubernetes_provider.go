@@ -0,0 +1,760 @@
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	k8sContainerName     = "main"
+	k8sUploadsVolume     = "uploads"
+	k8sPodSpecKey        = "pod.json"
+	k8sStateAnnotation   = "container-manager/state" // created|running|stopped，Pod 不存在時用來區分 created 與 stopped
+	k8sNameAnnotation    = "container-manager/name"
+	k8sImageAnnotation   = "container-manager/image"
+	k8sCreatedAnnotation = "container-manager/created-at"
+)
+
+// podExecutor 透過 exec subresource 在 Pod 內執行命令；測試時可替換。
+type podExecutor func(ctx context.Context, namespace, pod string, cmd []string, stdout, stderr io.Writer) error
+
+// KubernetesOptions KubernetesProvider 的部署設定。
+type KubernetesOptions struct {
+	Namespace    string // 建立 Pod/Job 的 namespace
+	UploadsClaim string // 存放上傳資料的 PVC 名稱
+	UploadsRoot  string // 上傳目錄在本服務中的路徑；掛載時以相對路徑作為 PVC 的 subPath
+}
+
+// KubernetesOptionsFromEnv 讀取 K8S_NAMESPACE、K8S_UPLOADS_PVC 與 K8S_UPLOADS_ROOT。
+// 未設定 namespace 時使用 ServiceAccount 所在的 namespace，再退回 default；
+// 未設定 uploads root 時依序使用 HOST_DATA_DIR、DATA_DIR。
+func KubernetesOptionsFromEnv() KubernetesOptions {
+	o := KubernetesOptions{
+		Namespace:    os.Getenv("K8S_NAMESPACE"),
+		UploadsClaim: os.Getenv("K8S_UPLOADS_PVC"),
+		UploadsRoot:  os.Getenv("K8S_UPLOADS_ROOT"),
+	}
+	if o.Namespace == "" {
+		if b, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+			o.Namespace = strings.TrimSpace(string(b))
+		}
+	}
+	if o.Namespace == "" {
+		o.Namespace = "default"
+	}
+	if o.UploadsClaim == "" {
+		o.UploadsClaim = "container-manager-uploads"
+	}
+	for _, dir := range []string{o.UploadsRoot, os.Getenv("HOST_DATA_DIR"), os.Getenv("DATA_DIR"), "./data"} {
+		if dir != "" {
+			o.UploadsRoot = dir
+			break
+		}
+	}
+	return o
+}
+
+// KubernetesProvider 以 Pod 實作容器生命週期，以 batch/v1 Job 實作一次性作業。
+//
+// 每個容器對應一個 ConfigMap（保存 Pod 定義與狀態）與執行中時的一個 Pod：
+// Create 只寫入 ConfigMap，Start 依定義建立 Pod，Stop 刪除 Pod，Delete 兩者皆刪除。
+type KubernetesProvider struct {
+	client          kubernetes.Interface
+	opts            KubernetesOptions
+	exec            podExecutor
+	execOutputLimit int
+	pollInterval    time.Duration // RunJob 輪詢 Job 狀態的間隔
+}
+
+// NewKubernetesProvider 優先使用 in-cluster 設定，否則讀取 KUBECONFIG 或 ~/.kube/config。
+func NewKubernetesProvider() (*KubernetesProvider, error) {
+	cfg, err := rest.InClusterConfig()
+	if err != nil {
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		cfg, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("load kubernetes config: %w", err)
+		}
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewKubernetesProviderWith(client, cfg, KubernetesOptionsFromEnv()), nil
+}
+
+// NewKubernetesProviderWith 允許注入 clientset（例如 client-go 的 fake clientset）；
+// restConfig 為 nil 時 Exec 不可用。
+func NewKubernetesProviderWith(client kubernetes.Interface, restConfig *rest.Config, opts KubernetesOptions) *KubernetesProvider {
+	p := &KubernetesProvider{
+		client:          client,
+		opts:            opts,
+		execOutputLimit: execOutputLimitFromEnv(),
+		pollInterval:    time.Second,
+	}
+	p.exec = func(ctx context.Context, namespace, pod string, cmd []string, stdout, stderr io.Writer) error {
+		if restConfig == nil {
+			return ErrNotSupported
+		}
+		req := client.CoreV1().RESTClient().Post().
+			Resource("pods").Namespace(namespace).Name(pod).SubResource("exec").
+			VersionedParams(&corev1.PodExecOptions{
+				Container: k8sContainerName,
+				Command:   cmd,
+				Stdout:    true,
+				Stderr:    true,
+			}, scheme.ParameterCodec)
+		ex, err := remotecommand.NewSPDYExecutor(restConfig, "POST", req.URL())
+		if err != nil {
+			return err
+		}
+		return ex.StreamWithContext(ctx, remotecommand.StreamOptions{Stdout: stdout, Stderr: stderr})
+	}
+	return p
+}
+
+func (k *KubernetesProvider) Create(ctx context.Context, opts CreateOptions) (Container, error) {
+	if err := opts.Validate(); err != nil {
+		return Container{}, err
+	}
+	id := "cm-" + uuid.NewString()
+	pod, err := k.podFor(id, opts)
+	if err != nil {
+		return Container{}, err
+	}
+	data, err := json.Marshal(pod)
+	if err != nil {
+		return Container{}, err
+	}
+	now := time.Now().Unix()
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   id,
+			Labels: map[string]string{managedLabel: "true"},
+			Annotations: map[string]string{
+				k8sStateAnnotation:   "created",
+				k8sNameAnnotation:    opts.Name,
+				k8sImageAnnotation:   opts.Image,
+				k8sCreatedAnnotation: strconv.FormatInt(now, 10),
+			},
+		},
+		Data: map[string]string{k8sPodSpecKey: string(data)},
+	}
+	if _, err := k.client.CoreV1().ConfigMaps(k.opts.Namespace).Create(ctx, cm, metav1.CreateOptions{}); err != nil {
+		return Container{}, kubernetesError(err)
+	}
+	return Container{ID: id, Name: opts.Name, Image: opts.Image, CreatedAt: now, Status: "created"}, nil
+}
+
+// podFor 將 CreateOptions 轉成 Pod 定義。
+func (k *KubernetesProvider) podFor(id string, opts CreateOptions) (*corev1.Pod, error) {
+	labels := map[string]string{}
+	for key, v := range opts.Labels {
+		labels[key] = v
+	}
+	labels[managedLabel] = "true"
+
+	ctr := corev1.Container{
+		Name:       k8sContainerName,
+		Image:      opts.Image,
+		Command:    opts.Entrypoint,
+		Args:       opts.Cmd,
+		WorkingDir: opts.WorkingDir,
+	}
+	if len(opts.Entrypoint) == 0 && len(opts.Cmd) == 0 {
+		// 與 DockerProvider 相同，以常駐命令讓容器可供 exec
+		ctr.Command = []string{"tail", "-f", "/dev/null"}
+	}
+	ctr.Env = kubernetesEnv(opts.Env)
+	if err := applyKubernetesLimits(opts.Resources, opts.Security, &ctr); err != nil {
+		return nil, err
+	}
+	for _, p := range opts.Ports {
+		ctr.Ports = append(ctr.Ports, corev1.ContainerPort{
+			ContainerPort: int32(p.ContainerPort),
+			HostPort:      int32(p.HostPort),
+			HostIP:        p.HostIP,
+			Protocol:      corev1.Protocol(strings.ToUpper(p.protocol())),
+		})
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: id, Labels: labels},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Hostname:      opts.Hostname,
+		},
+	}
+	hostDirs := make([]string, 0, len(opts.Mounts))
+	for hostDir := range opts.Mounts {
+		hostDirs = append(hostDirs, hostDir)
+	}
+	sort.Strings(hostDirs)
+	for _, hostDir := range hostDirs {
+		sub, err := k.uploadsSubPath(hostDir)
+		if err != nil {
+			return nil, err
+		}
+		ctr.VolumeMounts = append(ctr.VolumeMounts, corev1.VolumeMount{Name: k8sUploadsVolume, MountPath: opts.Mounts[hostDir], SubPath: sub})
+	}
+	if len(ctr.VolumeMounts) > 0 {
+		pod.Spec.Volumes = []corev1.Volume{k.uploadsVolume()}
+	}
+	if err := applyKubernetesNetwork(opts.Network, &pod.Spec); err != nil {
+		return nil, err
+	}
+	pod.Spec.Containers = []corev1.Container{ctr}
+	return pod, nil
+}
+
+// applyKubernetesLimits 將資源限制與安全設定套到容器上。
+// PidsLimit、MemorySwap 與 Ulimits 由節點設定決定，此處不處理。
+func applyKubernetesLimits(r ResourceLimits, sec SecurityOptions, ctr *corev1.Container) error {
+	limits := corev1.ResourceList{}
+	if cpus := r.cpus(); cpus > 0 {
+		limits[corev1.ResourceCPU] = *resource.NewMilliQuantity(int64(cpus*1000), resource.DecimalSI)
+	}
+	if r.Memory > 0 {
+		limits[corev1.ResourceMemory] = *resource.NewQuantity(r.Memory, resource.BinarySI)
+	}
+	if len(limits) > 0 {
+		ctr.Resources.Limits = limits
+	}
+	if r.CPUShares > 0 {
+		// Docker 以 1024 shares 代表一顆 CPU 的權重
+		ctr.Resources.Requests = corev1.ResourceList{
+			corev1.ResourceCPU: *resource.NewMilliQuantity(r.CPUShares*1000/1024, resource.DecimalSI),
+		}
+	}
+
+	sc := &corev1.SecurityContext{}
+	if sec.ReadOnlyRootfs {
+		sc.ReadOnlyRootFilesystem = ptr.To(true)
+	}
+	if sec.NoNewPrivileges {
+		sc.AllowPrivilegeEscalation = ptr.To(false)
+	}
+	if len(sec.CapDrop) > 0 {
+		drop := make([]corev1.Capability, 0, len(sec.CapDrop))
+		for _, c := range sec.CapDrop {
+			drop = append(drop, corev1.Capability(strings.TrimPrefix(c, "CAP_")))
+		}
+		sc.Capabilities = &corev1.Capabilities{Drop: drop}
+	}
+	if sec.User != "" {
+		uid, err := strconv.ParseInt(sec.User, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: kubernetes requires a numeric user", ErrInvalidOptions)
+		}
+		sc.RunAsUser = ptr.To(uid)
+	}
+	if sec.Group != "" {
+		gid, err := strconv.ParseInt(sec.Group, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: kubernetes requires a numeric group", ErrInvalidOptions)
+		}
+		sc.RunAsGroup = ptr.To(gid)
+	}
+	if *sc != (corev1.SecurityContext{}) {
+		ctr.SecurityContext = sc
+	}
+	return nil
+}
+
+// kubernetesEnv 將環境變數轉成依名稱排序的 EnvVar 列表。
+func kubernetesEnv(env map[string]string) []corev1.EnvVar {
+	var out []corev1.EnvVar
+	for _, kv := range envList(env) {
+		name, value, _ := strings.Cut(kv, "=")
+		out = append(out, corev1.EnvVar{Name: name, Value: value})
+	}
+	return out
+}
+
+// applyKubernetesNetwork 對應網路設定：host 使用 hostNetwork，自訂網路與 none 不支援。
+func applyKubernetesNetwork(n NetworkOptions, spec *corev1.PodSpec) error {
+	switch n.Network {
+	case "", "bridge", "default":
+	case "host":
+		spec.HostNetwork = true
+	default:
+		return fmt.Errorf("%w: kubernetes provider does not support network %q", ErrNotSupported, n.Network)
+	}
+	if len(n.DNS) == 0 && len(n.DNSSearch) == 0 && len(n.DNSOptions) == 0 {
+		return nil
+	}
+	cfg := &corev1.PodDNSConfig{Nameservers: n.DNS, Searches: n.DNSSearch}
+	for _, o := range n.DNSOptions {
+		name, value, ok := strings.Cut(o, ":")
+		opt := corev1.PodDNSConfigOption{Name: name}
+		if ok {
+			opt.Value = ptr.To(value)
+		}
+		cfg.Options = append(cfg.Options, opt)
+	}
+	if len(n.DNS) > 0 {
+		// 指定 DNS 伺服器時不使用叢集 DNS，與 Docker 的 --dns 行為一致
+		spec.DNSPolicy = corev1.DNSNone
+	}
+	spec.DNSConfig = cfg
+	return nil
+}
+
+func (k *KubernetesProvider) uploadsVolume() corev1.Volume {
+	return corev1.Volume{
+		Name: k8sUploadsVolume,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: k.opts.UploadsClaim},
+		},
+	}
+}
+
+// uploadsSubPath 將上傳目錄轉成 PVC 內的相對路徑；不在 uploads root 之下者拒絕掛載。
+func (k *KubernetesProvider) uploadsSubPath(dir string) (string, error) {
+	root, err := filepath.Abs(k.opts.UploadsRoot)
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("%w: %s is outside the uploads volume", ErrInvalidOptions, dir)
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+func (k *KubernetesProvider) Start(ctx context.Context, id string) error {
+	cm, err := k.configMap(ctx, id)
+	if err != nil {
+		return err
+	}
+	pods := k.client.CoreV1().Pods(k.opts.Namespace)
+	if pod, err := pods.Get(ctx, id, metav1.GetOptions{}); err == nil {
+		if !podFinished(pod) {
+			return &StateError{ID: id, Op: "start", State: "running"}
+		}
+		// 已結束的 Pod 無法重新啟動，刪除後依定義重建
+		if err := pods.Delete(ctx, id, metav1.DeleteOptions{GracePeriodSeconds: ptr.To[int64](0)}); err != nil && !apierrors.IsNotFound(err) {
+			return kubernetesError(err)
+		}
+	} else if !apierrors.IsNotFound(err) {
+		return kubernetesError(err)
+	}
+	var pod corev1.Pod
+	if err := json.Unmarshal([]byte(cm.Data[k8sPodSpecKey]), &pod); err != nil {
+		return fmt.Errorf("decode pod spec: %w", err)
+	}
+	if _, err := pods.Create(ctx, &pod, metav1.CreateOptions{}); err != nil {
+		return kubernetesError(err)
+	}
+	return k.setState(ctx, cm, "running")
+}
+
+func (k *KubernetesProvider) Stop(ctx context.Context, id string) error {
+	cm, err := k.configMap(ctx, id)
+	if err != nil {
+		return err
+	}
+	pods := k.client.CoreV1().Pods(k.opts.Namespace)
+	pod, err := pods.Get(ctx, id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		pod = nil
+	} else if err != nil {
+		return kubernetesError(err)
+	}
+	if c := kubernetesContainer(cm, pod); c.Status != "running" {
+		return &StateError{ID: id, Op: "stop", State: c.Status}
+	}
+	grace := int64(10)
+	err = pods.Delete(ctx, id, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return kubernetesError(err)
+	}
+	return k.setState(ctx, cm, "stopped")
+}
+
+// Delete 刪除 Pod 與保存定義的 ConfigMap；namespace 可能與其他服務共用，
+// 只刪除帶有 managed 標籤的容器，其餘名稱回傳 ErrNotFound。
+func (k *KubernetesProvider) Delete(ctx context.Context, id string) error {
+	if _, err := k.configMap(ctx, id); err != nil {
+		return err
+	}
+	err := k.client.CoreV1().Pods(k.opts.Namespace).Delete(ctx, id, metav1.DeleteOptions{GracePeriodSeconds: ptr.To[int64](0)})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return kubernetesError(err)
+	}
+	err = k.client.CoreV1().ConfigMaps(k.opts.Namespace).Delete(ctx, id, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return ErrNotFound
+	}
+	return kubernetesError(err)
+}
+
+// kubernetesError 依 API server 回報的狀態將錯誤分類包裝成 *Error；無法分類者原樣回傳。
+func kubernetesError(err error) error {
+	var kind error
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsNotFound(err):
+		kind = ErrNotFound
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		kind = ErrInvalidOptions
+	case apierrors.IsForbidden(err), apierrors.IsTooManyRequests(err):
+		kind = ErrLimitExceeded
+	case apierrors.IsConflict(err), apierrors.IsAlreadyExists(err):
+		kind = ErrConflict
+	default:
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// Exec 透過 exec subresource 執行命令；容器未執行時回傳錯誤。
+func (k *KubernetesProvider) Exec(ctx context.Context, id string, cmd []string) (ExecResult, error) {
+	if _, err := k.configMap(ctx, id); err != nil {
+		return ExecResult{}, err
+	}
+	pod, err := k.client.CoreV1().Pods(k.opts.Namespace).Get(ctx, id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) || (err == nil && pod.Status.Phase != corev1.PodRunning) {
+		return ExecResult{}, ErrNotRunning
+	}
+	if err != nil {
+		return ExecResult{}, kubernetesError(err)
+	}
+	stdout := newCappedBuffer(k.execOutputLimit)
+	stderr := newCappedBuffer(k.execOutputLimit)
+	err = k.exec(ctx, k.opts.Namespace, id, cmd, stdout, stderr)
+	res := ExecResult{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.Truncated() || stderr.Truncated(),
+	}
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) {
+		res.ExitCode = exitErr.ExitStatus()
+		return res, nil
+	}
+	if err != nil {
+		return ExecResult{}, kubernetesError(err)
+	}
+	return res, nil
+}
+
+// List 列出本服務建立的容器，狀態由 Pod phase 推得。
+func (k *KubernetesProvider) List(ctx context.Context, filter ListFilter) ([]Container, error) {
+	selector := metav1.ListOptions{LabelSelector: managedLabel + "=true"}
+	cms, err := k.client.CoreV1().ConfigMaps(k.opts.Namespace).List(ctx, selector)
+	if err != nil {
+		return nil, kubernetesError(err)
+	}
+	pods, err := k.client.CoreV1().Pods(k.opts.Namespace).List(ctx, selector)
+	if err != nil {
+		return nil, kubernetesError(err)
+	}
+	byName := make(map[string]*corev1.Pod, len(pods.Items))
+	for i := range pods.Items {
+		byName[pods.Items[i].Name] = &pods.Items[i]
+	}
+	out := make([]Container, 0, len(cms.Items))
+	for i := range cms.Items {
+		c := kubernetesContainer(&cms.Items[i], byName[cms.Items[i].Name])
+		if filter.Match(c) {
+			out = append(out, c)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// Inspect 查詢單一容器的目前狀態。
+func (k *KubernetesProvider) Inspect(ctx context.Context, id string) (Container, error) {
+	cm, err := k.configMap(ctx, id)
+	if err != nil {
+		return Container{}, err
+	}
+	pod, err := k.client.CoreV1().Pods(k.opts.Namespace).Get(ctx, id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		pod = nil
+	} else if err != nil {
+		return Container{}, kubernetesError(err)
+	}
+	return kubernetesContainer(cm, pod), nil
+}
+
+func (k *KubernetesProvider) configMap(ctx context.Context, id string) (*corev1.ConfigMap, error) {
+	cm, err := k.client.CoreV1().ConfigMaps(k.opts.Namespace).Get(ctx, id, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, kubernetesError(err)
+	}
+	if cm.Labels[managedLabel] != "true" {
+		return nil, ErrNotFound
+	}
+	return cm, nil
+}
+
+func (k *KubernetesProvider) setState(ctx context.Context, cm *corev1.ConfigMap, state string) error {
+	cm = cm.DeepCopy()
+	if cm.Annotations == nil {
+		cm.Annotations = map[string]string{}
+	}
+	cm.Annotations[k8sStateAnnotation] = state
+	_, err := k.client.CoreV1().ConfigMaps(k.opts.Namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return kubernetesError(err)
+}
+
+// kubernetesContainer 由 ConfigMap 與（可能不存在的）Pod 組成 Container。
+func kubernetesContainer(cm *corev1.ConfigMap, pod *corev1.Pod) Container {
+	c := Container{
+		ID:    cm.Name,
+		Name:  cm.Annotations[k8sNameAnnotation],
+		Image: cm.Annotations[k8sImageAnnotation],
+	}
+	if v, err := strconv.ParseInt(cm.Annotations[k8sCreatedAnnotation], 10, 64); err == nil {
+		c.CreatedAt = v
+	} else {
+		c.CreatedAt = cm.CreationTimestamp.Unix()
+	}
+	switch {
+	case pod == nil && cm.Annotations[k8sStateAnnotation] == "created":
+		c.Status = "created"
+	case pod == nil, podFinished(pod):
+		c.Status = "stopped"
+	default:
+		c.Status = "running"
+		for _, ctr := range pod.Spec.Containers {
+			for _, p := range ctr.Ports {
+				if p.HostPort == 0 {
+					continue
+				}
+				c.Ports = append(c.Ports, PortMapping{
+					ContainerPort: int(p.ContainerPort),
+					HostPort:      int(p.HostPort),
+					HostIP:        p.HostIP,
+					Protocol:      strings.ToLower(string(p.Protocol)),
+				})
+			}
+		}
+		sortPorts(c.Ports)
+	}
+	return c
+}
+
+func podFinished(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// k8sNetworkLabel 標記作業 Pod 要求的網路模式；network=none 由 RunJob 建立的 NetworkPolicy 隔離。
+const k8sNetworkLabel = "container-manager.network"
+
+// RunJob 以 batch/v1 Job 執行一次性作業；HostDir 透過 uploads PVC 的 subPath 掛載。
+// 逾時由 ActiveDeadlineSeconds 執行，kubelet 依 TerminationGracePeriodSeconds 終止 Pod。
+func (k *KubernetesProvider) RunJob(ctx context.Context, opts JobOptions) (JobResult, error) {
+	sub, err := k.uploadsSubPath(opts.HostDir)
+	if err != nil {
+		return JobResult{}, err
+	}
+	ctr := corev1.Container{
+		Name:         k8sContainerName,
+		Image:        opts.Image,
+		Args:         opts.Cmd,
+		Env:          kubernetesEnv(opts.Env),
+		WorkingDir:   opts.ContainerDir,
+		VolumeMounts: []corev1.VolumeMount{{Name: k8sUploadsVolume, MountPath: opts.ContainerDir, SubPath: sub}},
+	}
+	if err := applyKubernetesLimits(opts.Resources, opts.Security, &ctr); err != nil {
+		return JobResult{}, err
+	}
+	podSpec := corev1.PodSpec{
+		RestartPolicy:                 corev1.RestartPolicyNever,
+		Containers:                    []corev1.Container{ctr},
+		Volumes:                       []corev1.Volume{k.uploadsVolume()},
+		TerminationGracePeriodSeconds: ptr.To(int64(opts.gracePeriod().Seconds())),
+	}
+	network := opts.network()
+	if network != "none" {
+		if err := applyKubernetesNetwork(NetworkOptions{Network: network}, &podSpec); err != nil {
+			return JobResult{}, err
+		}
+	}
+	timeout := opts.timeout()
+	name := "cm-job-" + uuid.NewString()[:8]
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{managedLabel: "true"}},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:            ptr.To[int32](0),
+			ActiveDeadlineSeconds:   ptr.To(deadlineSeconds(timeout)),
+			TTLSecondsAfterFinished: ptr.To[int32](600),
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{managedLabel: "true", k8sNetworkLabel: network}},
+				Spec:       podSpec,
+			},
+		},
+	}
+	if network == "none" {
+		// 先建立 NetworkPolicy 再建立 Job，Pod 啟動時即已隔離
+		policies := k.client.NetworkingV1().NetworkPolicies(k.opts.Namespace)
+		if _, err := policies.Create(ctx, denyAllPolicy(name), metav1.CreateOptions{}); err != nil {
+			return JobResult{}, kubernetesError(err)
+		}
+		defer func() { _ = policies.Delete(context.Background(), name, metav1.DeleteOptions{}) }()
+	}
+	jobs := k.client.BatchV1().Jobs(k.opts.Namespace)
+	if _, err := jobs.Create(ctx, job, metav1.CreateOptions{}); err != nil {
+		return JobResult{}, kubernetesError(err)
+	}
+	defer func() {
+		_ = jobs.Delete(context.Background(), name, metav1.DeleteOptions{PropagationPolicy: ptr.To(metav1.DeletePropagationBackground)})
+	}()
+
+	// 等待結束；controller 未回報時，超過逾時加寬限期即視為逾時
+	deadline := time.NewTimer(timeout + opts.gracePeriod())
+	defer deadline.Stop()
+	tick := time.NewTicker(k.pollInterval)
+	defer tick.Stop()
+	for {
+		j, err := jobs.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return JobResult{}, kubernetesError(err)
+		}
+		if j.Status.Succeeded > 0 || j.Status.Failed > 0 || jobDeadlineExceeded(j) {
+			return k.jobResult(ctx, j), nil
+		}
+		select {
+		case <-tick.C:
+		case <-deadline.C:
+			return JobResult{ExitCode: -1, Logs: k.podLogs(ctx, name), Status: JobTimedOut}, nil
+		case <-ctx.Done():
+			return JobResult{}, ctx.Err()
+		}
+	}
+}
+
+// denyAllPolicy 建立與 Job 同名的 NetworkPolicy，以 Job controller 加上的 job-name 標籤
+// 選取其 Pod，且不允許任何進出流量；需要叢集的網路外掛支援 NetworkPolicy。
+func denyAllPolicy(job string) *networkingv1.NetworkPolicy {
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: job, Labels: map[string]string{managedLabel: "true"}},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"job-name": job}},
+			PolicyTypes: []networkingv1.PolicyType{networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress},
+		},
+	}
+}
+
+// deadlineSeconds 將逾時無條件進位為秒；API server 不接受 0，最少為 1 秒。
+func deadlineSeconds(d time.Duration) int64 {
+	return max(int64(math.Ceil(d.Seconds())), 1)
+}
+
+// jobDeadlineExceeded 判斷 Job 是否因 ActiveDeadlineSeconds 被終止。
+func jobDeadlineExceeded(j *batchv1.Job) bool {
+	for _, c := range j.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue && c.Reason == batchv1.JobReasonDeadlineExceeded {
+			return true
+		}
+	}
+	return false
+}
+
+// jobResult 從 Job 建立的 Pod 讀取 exit code 與日誌。
+func (k *KubernetesProvider) jobResult(ctx context.Context, j *batchv1.Job) JobResult {
+	timedOut := jobDeadlineExceeded(j)
+	var code int64
+	switch {
+	case timedOut:
+		code = -1
+	case j.Status.Succeeded == 0:
+		code = 1
+	}
+	var logs string
+	var oom bool
+	if pod, ok := k.jobPod(ctx, j.Name); ok {
+		for _, st := range pod.Status.ContainerStatuses {
+			if st.Name == k8sContainerName && st.State.Terminated != nil {
+				code = int64(st.State.Terminated.ExitCode)
+				oom = st.State.Terminated.Reason == "OOMKilled"
+			}
+		}
+		logs = k.logsOf(ctx, pod.Name)
+	}
+	if timedOut {
+		return JobResult{ExitCode: code, Logs: logs, Status: JobTimedOut}
+	}
+	res := exitedJob(code, logs)
+	res.OOMKilled = oom
+	return res
+}
+
+// jobPod 回傳 Job 最後建立的 Pod；List 不保證順序，依建立時間排序。
+func (k *KubernetesProvider) jobPod(ctx context.Context, job string) (corev1.Pod, bool) {
+	pods, err := k.client.CoreV1().Pods(k.opts.Namespace).List(ctx, metav1.ListOptions{LabelSelector: "job-name=" + job})
+	if err != nil || len(pods.Items) == 0 {
+		return corev1.Pod{}, false
+	}
+	sort.SliceStable(pods.Items, func(i, j int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[j].CreationTimestamp)
+	})
+	return pods.Items[len(pods.Items)-1], true
+}
+
+// podLogs 讀取 Job 的 Pod 日誌；找不到 Pod 或讀取失敗時回傳空字串。
+func (k *KubernetesProvider) podLogs(ctx context.Context, job string) string {
+	pod, ok := k.jobPod(ctx, job)
+	if !ok {
+		return ""
+	}
+	return k.logsOf(ctx, pod.Name)
+}
+
+func (k *KubernetesProvider) logsOf(ctx context.Context, pod string) string {
+	b, err := k.client.CoreV1().Pods(k.opts.Namespace).GetLogs(pod, &corev1.PodLogOptions{Container: k8sContainerName}).DoRaw(ctx)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+var (
+	_ Provider  = (*KubernetesProvider)(nil)
+	_ JobRunner = (*KubernetesProvider)(nil)
+)
@@ -0,0 +1,326 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+func newFakeKubernetesProvider() (*KubernetesProvider, *fake.Clientset) {
+	cs := fake.NewClientset()
+	p := NewKubernetesProviderWith(cs, nil, KubernetesOptions{Namespace: "jobs", UploadsClaim: "uploads", UploadsRoot: "/data"})
+	p.pollInterval = time.Millisecond
+	return p, cs
+}
+
+func TestKubernetesProvider_Lifecycle(t *testing.T) {
+	p, cs := newFakeKubernetesProvider()
+	ctx := context.Background()
+
+	c, err := p.Create(ctx, CreateOptions{
+		Name:      "web",
+		Image:     "nginx:1.27",
+		Mounts:    map[string]string{"/data/u1/site": "/usr/share/nginx/html"},
+		Env:       map[string]string{"MODE": "prod"},
+		Ports:     []PortMapping{{ContainerPort: 80, HostPort: 8080}},
+		Resources: ResourceLimits{CPUQuota: 50000, Memory: 64 << 20},
+		Security:  SecurityOptions{User: "1000", NoNewPrivileges: true, CapDrop: []string{"ALL"}},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if c.Status != "created" {
+		t.Fatalf("status=%s", c.Status)
+	}
+	if _, err := cs.CoreV1().Pods("jobs").Get(ctx, c.ID, metav1.GetOptions{}); err == nil {
+		t.Fatalf("pod should not exist before start")
+	}
+
+	if err := p.Start(ctx, c.ID); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	pod, err := cs.CoreV1().Pods("jobs").Get(ctx, c.ID, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("pod not created: %v", err)
+	}
+	ctr := pod.Spec.Containers[0]
+	if ctr.Command[0] != "tail" || ctr.Env[0].Name != "MODE" || ctr.Resources.Limits.Cpu().MilliValue() != 500 {
+		t.Fatalf("unexpected container spec: %+v", ctr)
+	}
+	if *ctr.SecurityContext.RunAsUser != 1000 || *ctr.SecurityContext.AllowPrivilegeEscalation || ctr.SecurityContext.Capabilities.Drop[0] != "ALL" {
+		t.Fatalf("unexpected security context: %+v", ctr.SecurityContext)
+	}
+	if ctr.VolumeMounts[0].SubPath != "u1/site" || pod.Spec.Volumes[0].PersistentVolumeClaim.ClaimName != "uploads" {
+		t.Fatalf("unexpected volumes: %+v %+v", ctr.VolumeMounts, pod.Spec.Volumes)
+	}
+
+	got, err := p.Inspect(ctx, c.ID)
+	if err != nil || got.Status != "running" || got.Name != "web" || len(got.Ports) != 1 || got.Ports[0].HostPort != 8080 {
+		t.Fatalf("inspect: %+v %v", got, err)
+	}
+
+	if err := p.Stop(ctx, c.ID); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	list, err := p.List(ctx, ListFilter{Status: "stopped"})
+	if err != nil || len(list) != 1 || list[0].ID != c.ID {
+		t.Fatalf("list stopped: %+v %v", list, err)
+	}
+
+	if err := p.Delete(ctx, c.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := p.Inspect(ctx, c.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := p.Start(ctx, "missing"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestKubernetesProvider_DeleteUnmanaged(t *testing.T) {
+	p, cs := newFakeKubernetesProvider()
+	ctx := context.Background()
+	// 共用 namespace 中其他服務的 Pod 與 ConfigMap 不可經由本服務刪除
+	_, _ = cs.CoreV1().ConfigMaps("jobs").Create(ctx, &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt"}}, metav1.CreateOptions{})
+	_, _ = cs.CoreV1().Pods("jobs").Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt"}}, metav1.CreateOptions{})
+	if err := p.Delete(ctx, "kube-root-ca.crt"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for unmanaged name, got %v", err)
+	}
+	if _, err := cs.CoreV1().ConfigMaps("jobs").Get(ctx, "kube-root-ca.crt", metav1.GetOptions{}); err != nil {
+		t.Fatalf("unmanaged configmap deleted: %v", err)
+	}
+	if _, err := cs.CoreV1().Pods("jobs").Get(ctx, "kube-root-ca.crt", metav1.GetOptions{}); err != nil {
+		t.Fatalf("unmanaged pod deleted: %v", err)
+	}
+}
+
+func TestKubernetesProvider_CreateRejectsUnsupported(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newFakeKubernetesProvider()
+	if _, err := p.Create(ctx, CreateOptions{Image: "alpine", Mounts: map[string]string{"/etc": "/x"}}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions for mount outside uploads, got %v", err)
+	}
+	if _, err := p.Create(ctx, CreateOptions{Image: "alpine", Security: SecurityOptions{User: "nobody"}}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions for non-numeric user, got %v", err)
+	}
+	if _, err := p.Create(ctx, CreateOptions{Image: "alpine", Network: NetworkOptions{Network: "apps"}}); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported for custom network, got %v", err)
+	}
+}
+
+func TestKubernetesProvider_Exec(t *testing.T) {
+	ctx := context.Background()
+	p, cs := newFakeKubernetesProvider()
+	c, _ := p.Create(ctx, CreateOptions{Image: "alpine"})
+	if _, err := p.Exec(ctx, c.ID, []string{"true"}); err != ErrNotRunning {
+		t.Fatalf("expected ErrNotRunning before start, got %v", err)
+	}
+	_ = p.Start(ctx, c.ID)
+	pod, _ := cs.CoreV1().Pods("jobs").Get(context.Background(), c.ID, metav1.GetOptions{})
+	pod.Status.Phase = corev1.PodRunning
+	_, _ = cs.CoreV1().Pods("jobs").UpdateStatus(context.Background(), pod, metav1.UpdateOptions{})
+
+	p.exec = func(ctx context.Context, namespace, pod string, cmd []string, stdout, stderr io.Writer) error {
+		_, _ = io.WriteString(stdout, "hello")
+		_, _ = io.WriteString(stderr, "oops")
+		return utilexec.CodeExitError{Err: errors.New("command terminated with exit code 3"), Code: 3}
+	}
+	res, err := p.Exec(ctx, c.ID, []string{"sh", "-c", "exit 3"})
+	if err != nil {
+		t.Fatalf("exec: %v", err)
+	}
+	if res.ExitCode != 3 || res.Stdout != "hello" || res.Stderr != "oops" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestKubernetesProvider_RunJob(t *testing.T) {
+	p, cs := newFakeKubernetesProvider()
+	ctx := context.Background()
+
+	// 模擬 Job controller：建立 Pod 並將 Job 標記為失敗
+	go func() {
+		for {
+			jobs, _ := cs.BatchV1().Jobs("jobs").List(ctx, metav1.ListOptions{})
+			if len(jobs.Items) == 0 {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			job := jobs.Items[0]
+			_, _ = cs.CoreV1().Pods("jobs").Create(ctx, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: job.Name + "-abcde", Labels: map[string]string{"job-name": job.Name}},
+				Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  k8sContainerName,
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 2}},
+				}}},
+			}, metav1.CreateOptions{})
+			job.Status.Failed = 1
+			_, _ = cs.BatchV1().Jobs("jobs").UpdateStatus(ctx, &job, metav1.UpdateOptions{})
+			return
+		}
+	}()
+
+	res, err := p.RunJob(ctx, JobOptions{Image: "python:3.12", HostDir: "/data/u1/batch", ContainerDir: "/workspace", Cmd: []string{"python", "main.py"}, Env: map[string]string{"MODE": "ci"}, Timeout: time.Minute, GracePeriod: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("run job: %v", err)
+	}
+	if res.ExitCode != 2 || res.Logs != "fake logs" || res.Status != JobFailed {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	jobs, _ := cs.BatchV1().Jobs("jobs").List(ctx, metav1.ListOptions{})
+	if len(jobs.Items) != 0 {
+		t.Fatalf("job should be deleted after completion")
+	}
+	policies, _ := cs.NetworkingV1().NetworkPolicies("jobs").List(ctx, metav1.ListOptions{})
+	if len(policies.Items) != 0 {
+		t.Fatalf("network policy should be deleted after completion")
+	}
+	policyCreated := false
+	for _, a := range cs.Actions() {
+		if a.GetVerb() == "create" && a.GetResource().Resource == "jobs" {
+			job := a.(k8stesting.CreateAction).GetObject().(*batchv1.Job)
+			spec := job.Spec.Template.Spec
+			mount := spec.Containers[0].VolumeMounts[0]
+			if mount.SubPath != "u1/batch" || mount.MountPath != "/workspace" {
+				t.Fatalf("unexpected mount %+v", mount)
+			}
+			if *job.Spec.ActiveDeadlineSeconds != 60 || *spec.TerminationGracePeriodSeconds != 5 {
+				t.Fatalf("unexpected deadline %d / grace %d", *job.Spec.ActiveDeadlineSeconds, *spec.TerminationGracePeriodSeconds)
+			}
+			if env := spec.Containers[0].Env; len(env) != 1 || env[0].Name != "MODE" || env[0].Value != "ci" {
+				t.Fatalf("unexpected env %+v", env)
+			}
+			if job.Spec.Template.Labels[k8sNetworkLabel] != "none" || spec.HostNetwork {
+				t.Fatalf("job should default to network none, labels %v", job.Spec.Template.Labels)
+			}
+		}
+		if a.GetVerb() == "create" && a.GetResource().Resource == "networkpolicies" {
+			np := a.(k8stesting.CreateAction).GetObject().(*networkingv1.NetworkPolicy)
+			if np.Spec.PodSelector.MatchLabels["job-name"] != np.Name || len(np.Spec.PolicyTypes) != 2 || len(np.Spec.Ingress)+len(np.Spec.Egress) != 0 {
+				t.Fatalf("expected deny-all policy for the job pod, got %+v", np.Spec)
+			}
+			policyCreated = true
+		}
+	}
+	if !policyCreated {
+		t.Fatal("network none should create a NetworkPolicy")
+	}
+
+	if _, err := p.RunJob(ctx, JobOptions{Image: "alpine", HostDir: "/tmp/x", ContainerDir: "/w"}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions, got %v", err)
+	}
+	if _, err := p.RunJob(ctx, JobOptions{Image: "alpine", HostDir: "/data/u1", ContainerDir: "/w", Network: "apps"}); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported for custom network, got %v", err)
+	}
+}
+
+func TestKubernetesProvider_RunJobDeadlineExceeded(t *testing.T) {
+	p, cs := newFakeKubernetesProvider()
+	ctx := context.Background()
+
+	// 模擬 Job controller：ActiveDeadlineSeconds 到期後將 Job 標記為 DeadlineExceeded
+	go func() {
+		for {
+			jobs, _ := cs.BatchV1().Jobs("jobs").List(ctx, metav1.ListOptions{})
+			if len(jobs.Items) == 0 {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			job := jobs.Items[0]
+			job.Status.Failed = 1
+			job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: batchv1.JobReasonDeadlineExceeded}}
+			_, _ = cs.BatchV1().Jobs("jobs").UpdateStatus(ctx, &job, metav1.UpdateOptions{})
+			return
+		}
+	}()
+
+	res, err := p.RunJob(ctx, JobOptions{Image: "alpine", HostDir: "/data/u1", ContainerDir: "/w", Cmd: []string{"sleep", "600"}, Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("run job: %v", err)
+	}
+	if res.Status != JobTimedOut || res.ExitCode != -1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+}
+
+func TestKubernetesProvider_JobPodLatest(t *testing.T) {
+	p, cs := newFakeKubernetesProvider()
+	ctx := context.Background()
+	now := time.Now()
+	// 名稱順序與建立順序相反，確認不依 List 回傳的順序選擇
+	for name, created := range map[string]time.Time{"j-a": now, "j-b": now.Add(-time.Minute)} {
+		_, _ = cs.CoreV1().Pods("jobs").Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name: name, Labels: map[string]string{"job-name": "j"}, CreationTimestamp: metav1.NewTime(created),
+		}}, metav1.CreateOptions{})
+	}
+	if pod, ok := p.jobPod(ctx, "j"); !ok || pod.Name != "j-a" {
+		t.Fatalf("jobPod = %s, %v; want the most recently created pod j-a", pod.Name, ok)
+	}
+}
+
+func TestKubernetesProvider_RunJobAPIErrors(t *testing.T) {
+	ctx := context.Background()
+	gr := batchv1.Resource("jobs")
+	for want, apiErr := range map[error]error{
+		ErrLimitExceeded:  apierrors.NewForbidden(gr, "cm-job", errors.New("exceeded quota")),
+		ErrInvalidOptions: apierrors.NewInvalid(batchv1.SchemeGroupVersion.WithKind("Job").GroupKind(), "cm-job", nil),
+		ErrConflict:       apierrors.NewAlreadyExists(gr, "cm-job"),
+	} {
+		p, cs := newFakeKubernetesProvider()
+		cs.PrependReactor("create", "jobs", func(k8stesting.Action) (bool, runtime.Object, error) {
+			return true, nil, apiErr
+		})
+		_, err := p.RunJob(ctx, JobOptions{Image: "alpine", HostDir: "/data/u1", ContainerDir: "/w"})
+		if !errors.Is(err, want) {
+			t.Fatalf("%v: expected %v, got %v", apiErr, want, err)
+		}
+	}
+}
+
+func TestKubernetesProvider_ContainerAPIErrors(t *testing.T) {
+	p, cs := newFakeKubernetesProvider()
+	ctx := context.Background()
+	c, err := p.Create(ctx, CreateOptions{Name: "web", Image: "nginx"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	cs.PrependReactor("update", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewConflict(corev1.Resource("configmaps"), c.ID, errors.New("object was modified"))
+	})
+	cs.PrependReactor("delete", "pods", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(corev1.Resource("pods"), c.ID, errors.New("rbac"))
+	})
+	cs.PrependReactor("list", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewBadRequest("bad selector")
+	})
+	if err := p.Start(ctx, c.ID); !errors.Is(err, ErrConflict) {
+		t.Fatalf("start: expected ErrConflict, got %v", err)
+	}
+	if err := p.Delete(ctx, c.ID); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("delete: expected ErrLimitExceeded, got %v", err)
+	}
+	if _, err := p.List(ctx, ListFilter{}); !errors.Is(err, ErrInvalidOptions) {
+		t.Fatalf("list: expected ErrInvalidOptions, got %v", err)
+	}
+}
+
+func TestDeadlineSeconds(t *testing.T) {
+	for d, want := range map[time.Duration]int64{300 * time.Millisecond: 1, time.Second: 1, 1500 * time.Millisecond: 2, time.Minute: 60} {
+		if got := deadlineSeconds(d); got != want {
+			t.Fatalf("deadlineSeconds(%s) = %d, want %d", d, got, want)
+		}
+	}
+}
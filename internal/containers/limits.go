@@ -0,0 +1,162 @@
+package containers
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultCPUPeriod 與 Docker 相同的 CFS period（微秒）。
+const defaultCPUPeriod = 100000
+
+// minMemory Docker 允許的最小記憶體限制。
+const minMemory = 6 * 1024 * 1024
+
+// ResourceLimits 容器資源限制；零值代表未指定，由伺服器上限決定。
+type ResourceLimits struct {
+	CPUQuota   int64    `json:"cpuQuota,omitempty"`   // 每個 period 可用的 CPU 微秒數
+	CPUPeriod  int64    `json:"cpuPeriod,omitempty"`  // 微秒，預設 100000
+	CPUShares  int64    `json:"cpuShares,omitempty"`  // 相對權重
+	Memory     int64    `json:"memory,omitempty"`     // bytes
+	MemorySwap int64    `json:"memorySwap,omitempty"` // 記憶體 + swap（bytes）；-1 代表 swap 不限
+	PidsLimit  int64    `json:"pidsLimit,omitempty"`
+	Ulimits    []Ulimit `json:"ulimits,omitempty"`
+}
+
+// Ulimit 例如 {"name":"nofile","soft":1024,"hard":2048}。
+type Ulimit struct {
+	Name string `json:"name"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
+}
+
+// SecurityOptions 容器安全相關設定。
+type SecurityOptions struct {
+	ReadOnlyRootfs  bool     `json:"readOnlyRootfs,omitempty"`
+	CapDrop         []string `json:"capDrop,omitempty"` // 例如 ["NET_RAW"] 或 ["ALL"]
+	NoNewPrivileges bool     `json:"noNewPrivileges,omitempty"`
+	User            string   `json:"user,omitempty"`  // uid 或使用者名稱
+	Group           string   `json:"group,omitempty"` // gid 或群組名稱，需搭配 User
+}
+
+// cpus 回傳 quota/period 換算的 CPU 數，未指定 quota 時為 0。
+func (r ResourceLimits) cpus() float64 {
+	if r.CPUQuota <= 0 {
+		return 0
+	}
+	period := r.CPUPeriod
+	if period == 0 {
+		period = defaultCPUPeriod
+	}
+	return float64(r.CPUQuota) / float64(period)
+}
+
+func (r ResourceLimits) Validate() error {
+	switch {
+	case r.CPUQuota < 0, r.CPUPeriod < 0, r.CPUShares < 0, r.Memory < 0, r.PidsLimit < 0:
+		return fmt.Errorf("%w: resource values must not be negative", ErrInvalidOptions)
+	case r.CPUPeriod != 0 && (r.CPUPeriod < 1000 || r.CPUPeriod > 1000000):
+		return fmt.Errorf("%w: cpuPeriod must be between 1000 and 1000000", ErrInvalidOptions)
+	case r.CPUQuota != 0 && r.CPUQuota < 1000:
+		return fmt.Errorf("%w: cpuQuota must be at least 1000", ErrInvalidOptions)
+	case r.Memory != 0 && r.Memory < minMemory:
+		return fmt.Errorf("%w: memory must be at least %d bytes", ErrInvalidOptions, minMemory)
+	case r.MemorySwap < -1:
+		return fmt.Errorf("%w: memorySwap must be -1 or a byte count", ErrInvalidOptions)
+	case r.MemorySwap > 0 && r.Memory == 0:
+		return fmt.Errorf("%w: memorySwap requires memory", ErrInvalidOptions)
+	case r.MemorySwap > 0 && r.MemorySwap < r.Memory:
+		return fmt.Errorf("%w: memorySwap must not be less than memory", ErrInvalidOptions)
+	}
+	for _, u := range r.Ulimits {
+		if u.Name == "" || u.Soft < 0 || u.Hard < u.Soft {
+			return fmt.Errorf("%w: invalid ulimit %q", ErrInvalidOptions, u.Name)
+		}
+	}
+	return nil
+}
+
+var capabilityName = regexp.MustCompile(`^(CAP_)?[A-Z_]+$`)
+
+func (s SecurityOptions) Validate() error {
+	for _, c := range s.CapDrop {
+		if !capabilityName.MatchString(c) {
+			return fmt.Errorf("%w: invalid capability %q", ErrInvalidOptions, c)
+		}
+	}
+	if strings.Contains(s.User, ":") || strings.Contains(s.Group, ":") {
+		return fmt.Errorf("%w: user and group must not contain ':'", ErrInvalidOptions)
+	}
+	if s.Group != "" && s.User == "" {
+		return fmt.Errorf("%w: group requires user", ErrInvalidOptions)
+	}
+	return nil
+}
+
+// userSpec 組成 Docker 的 user[:group] 字串。
+func (s SecurityOptions) userSpec() string {
+	if s.Group == "" {
+		return s.User
+	}
+	return s.User + ":" + s.Group
+}
+
+// Limits 伺服器端強制的資源上限；0 代表不限制。
+// 未指定的資源會直接套用上限值，避免單一容器耗盡主機資源。
+type Limits struct {
+	MaxCPUs   float64
+	MaxMemory int64
+	MaxPids   int64
+}
+
+// LimitsFromEnv 讀取 MAX_CPUS、MAX_MEMORY（bytes）與 MAX_PIDS。
+func LimitsFromEnv() Limits {
+	var l Limits
+	if v, err := strconv.ParseFloat(os.Getenv("MAX_CPUS"), 64); err == nil && v > 0 {
+		l.MaxCPUs = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("MAX_MEMORY"), 10, 64); err == nil && v > 0 {
+		l.MaxMemory = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("MAX_PIDS"), 10, 64); err == nil && v > 0 {
+		l.MaxPids = v
+	}
+	return l
+}
+
+// Apply 驗證 r 並套用上限：超過上限回傳 ErrLimitExceeded，未指定者補上上限值。
+func (l Limits) Apply(r ResourceLimits) (ResourceLimits, error) {
+	if err := r.Validate(); err != nil {
+		return r, err
+	}
+	if l.MaxCPUs > 0 {
+		if r.CPUQuota == 0 {
+			if r.CPUPeriod == 0 {
+				r.CPUPeriod = defaultCPUPeriod
+			}
+			r.CPUQuota = int64(l.MaxCPUs * float64(r.CPUPeriod))
+		} else if r.cpus() > l.MaxCPUs {
+			return r, fmt.Errorf("%w: cpus %.2f > %.2f", ErrLimitExceeded, r.cpus(), l.MaxCPUs)
+		}
+	}
+	if l.MaxMemory > 0 {
+		if r.Memory == 0 {
+			r.Memory = l.MaxMemory
+		} else if r.Memory > l.MaxMemory {
+			return r, fmt.Errorf("%w: memory %d > %d", ErrLimitExceeded, r.Memory, l.MaxMemory)
+		}
+		if r.MemorySwap == -1 {
+			return r, fmt.Errorf("%w: unlimited swap is not allowed", ErrLimitExceeded)
+		}
+	}
+	if l.MaxPids > 0 {
+		if r.PidsLimit == 0 {
+			r.PidsLimit = l.MaxPids
+		} else if r.PidsLimit > l.MaxPids {
+			return r, fmt.Errorf("%w: pids %d > %d", ErrLimitExceeded, r.PidsLimit, l.MaxPids)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,65 @@
+package containers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLimits_ApplyDefaultsAndRejectsExcess(t *testing.T) {
+	l := Limits{MaxCPUs: 2, MaxMemory: 512 << 20, MaxPids: 256}
+
+	got, err := l.Apply(ResourceLimits{})
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if got.CPUQuota != 200000 || got.CPUPeriod != defaultCPUPeriod || got.Memory != 512<<20 || got.PidsLimit != 256 {
+		t.Fatalf("defaults not applied: %+v", got)
+	}
+
+	got, err = l.Apply(ResourceLimits{CPUQuota: 50000, Memory: 64 << 20, PidsLimit: 10})
+	if err != nil || got.CPUQuota != 50000 || got.Memory != 64<<20 || got.PidsLimit != 10 {
+		t.Fatalf("within limits: %+v err=%v", got, err)
+	}
+
+	for name, r := range map[string]ResourceLimits{
+		"cpu":    {CPUQuota: 300000},
+		"memory": {Memory: 1 << 30},
+		"pids":   {PidsLimit: 1000},
+		"swap":   {Memory: 64 << 20, MemorySwap: -1},
+	} {
+		if _, err := l.Apply(r); !errors.Is(err, ErrLimitExceeded) {
+			t.Fatalf("%s: expected ErrLimitExceeded, got %v", name, err)
+		}
+	}
+
+	// 未設定上限時保持原值
+	got, err = Limits{}.Apply(ResourceLimits{})
+	if err != nil || got.CPUQuota != 0 || got.Memory != 0 {
+		t.Fatalf("no limits: %+v err=%v", got, err)
+	}
+}
+
+func TestValidate_RejectsInvalidOptions(t *testing.T) {
+	for name, r := range map[string]ResourceLimits{
+		"negative": {CPUShares: -1},
+		"tiny mem": {Memory: 1024},
+		"swap<mem": {Memory: 64 << 20, MemorySwap: 32 << 20},
+		"ulimit":   {Ulimits: []Ulimit{{Name: "nofile", Soft: 10, Hard: 5}}},
+	} {
+		if err := r.Validate(); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("%s: expected ErrInvalidOptions, got %v", name, err)
+		}
+	}
+	for name, s := range map[string]SecurityOptions{
+		"cap":   {CapDrop: []string{"net raw"}},
+		"user":  {User: "1000:1000"},
+		"group": {Group: "1000"},
+	} {
+		if err := s.Validate(); !errors.Is(err, ErrInvalidOptions) {
+			t.Fatalf("%s: expected ErrInvalidOptions, got %v", name, err)
+		}
+	}
+	if s := (SecurityOptions{User: "1000", Group: "1000"}); s.Validate() != nil || s.userSpec() != "1000:1000" {
+		t.Fatalf("unexpected user spec %q", s.userSpec())
+	}
+}
@@ -0,0 +1,186 @@
+package containers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// LogsOptions 讀取容器日誌的參數。
+type LogsOptions struct {
+	Follow     bool      // 持續輸出直到容器結束或呼叫端關閉
+	Tail       int       // 只取最後 N 行；負數代表全部
+	Since      time.Time // 零值代表不限制
+	Until      time.Time // 零值代表不限制
+	Stdout     bool
+	Stderr     bool
+	Timestamps bool // 是否解析並回傳每行的時間戳
+}
+
+// normalize 在未指定任何輸出來源時預設同時讀取 stdout 與 stderr。
+func (o LogsOptions) normalize() LogsOptions {
+	if !o.Stdout && !o.Stderr {
+		o.Stdout, o.Stderr = true, true
+	}
+	return o
+}
+
+// LogLine 一行已解多工並標記來源的日誌。
+type LogLine struct {
+	Stream string `json:"stream"` // stdout|stderr
+	Time   string `json:"time,omitempty"`
+	Line   string `json:"line"`
+}
+
+// LogReader 逐行讀取日誌；結束時 Next 回傳 io.EOF。
+// Close 可在另一個 goroutine 呼叫以中斷 follow 模式下的等待。
+type LogReader interface {
+	Next() (LogLine, error)
+	Close() error
+}
+
+// LogStreamer 可選介面：支援讀取容器日誌。
+type LogStreamer interface {
+	Logs(ctx context.Context, id string, opts LogsOptions) (LogReader, error)
+}
+
+// Docker 多工串流的 header：[stream, 0, 0, 0, size(4 bytes, big endian)]
+const (
+	streamStdin     = 0
+	streamStdout    = 1
+	streamStderr    = 2
+	streamSystemErr = 3
+)
+
+// muxLogReader 將 Docker 的多工串流（或 TTY 的原始串流）切成帶標記的行。
+type muxLogReader struct {
+	rc         io.ReadCloser
+	br         *bufio.Reader
+	tty        bool
+	timestamps bool
+	partial    [3][]byte
+	pending    []LogLine
+	err        error
+}
+
+func newMuxLogReader(rc io.ReadCloser, tty, timestamps bool) *muxLogReader {
+	return &muxLogReader{rc: rc, br: bufio.NewReader(rc), tty: tty, timestamps: timestamps}
+}
+
+func (r *muxLogReader) Next() (LogLine, error) {
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return LogLine{}, r.err
+		}
+		r.fill()
+	}
+	l := r.pending[0]
+	r.pending = r.pending[1:]
+	return l, nil
+}
+
+func (r *muxLogReader) Close() error { return r.rc.Close() }
+
+func (r *muxLogReader) fill() {
+	if r.tty {
+		buf := make([]byte, 32*1024)
+		n, err := r.br.Read(buf)
+		r.push(streamStdout, buf[:n])
+		if err != nil {
+			r.finish(err)
+		}
+		return
+	}
+	var hdr [8]byte
+	if _, err := io.ReadFull(r.br, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = io.EOF
+		}
+		r.finish(err)
+		return
+	}
+	payload := make([]byte, binary.BigEndian.Uint32(hdr[4:]))
+	if _, err := io.ReadFull(r.br, payload); err != nil {
+		r.finish(io.EOF)
+		return
+	}
+	switch hdr[0] {
+	case streamStdout, streamStderr:
+		r.push(int(hdr[0]), payload)
+	case streamSystemErr:
+		r.finish(fmt.Errorf("error from daemon in stream: %s", payload))
+	case streamStdin:
+		// 不會出現在日誌中，忽略
+	default:
+		r.finish(fmt.Errorf("unrecognized stream type: %d", hdr[0]))
+	}
+}
+
+// push 累積資料直到遇到換行，再切成完整的行。
+func (r *muxLogReader) push(stream int, data []byte) {
+	buf := append(r.partial[stream], data...)
+	for {
+		i := bytes.IndexByte(buf, '\n')
+		if i < 0 {
+			break
+		}
+		r.pending = append(r.pending, r.parse(stream, buf[:i]))
+		buf = buf[i+1:]
+	}
+	r.partial[stream] = append([]byte(nil), buf...)
+}
+
+// finish 輸出尚未換行的殘餘資料並記錄結束原因。
+func (r *muxLogReader) finish(err error) {
+	for stream, buf := range r.partial {
+		if len(buf) > 0 {
+			r.pending = append(r.pending, r.parse(stream, buf))
+			r.partial[stream] = nil
+		}
+	}
+	if errors.Is(err, io.ErrClosedPipe) {
+		err = io.EOF
+	}
+	r.err = err
+}
+
+func (r *muxLogReader) parse(stream int, raw []byte) LogLine {
+	line := strings.TrimSuffix(string(raw), "\r")
+	l := LogLine{Stream: streamName(stream), Line: line}
+	if r.timestamps {
+		if ts, rest, ok := strings.Cut(line, " "); ok {
+			if t, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+				l.Time = t.UTC().Format(time.RFC3339Nano)
+				l.Line = rest
+			}
+		}
+	}
+	return l
+}
+
+func streamName(stream int) string {
+	if stream == streamStderr {
+		return "stderr"
+	}
+	return "stdout"
+}
+
+// sliceLogReader 以記憶體中的行實作 LogReader（供 MockProvider 使用）。
+type sliceLogReader struct{ lines []LogLine }
+
+func (r *sliceLogReader) Next() (LogLine, error) {
+	if len(r.lines) == 0 {
+		return LogLine{}, io.EOF
+	}
+	l := r.lines[0]
+	r.lines = r.lines[1:]
+	return l, nil
+}
+
+func (r *sliceLogReader) Close() error { return nil }
@@ -0,0 +1,57 @@
+package containers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+func frame(stream byte, payload string) []byte {
+	hdr := []byte{stream, 0, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(hdr[4:], uint32(len(payload)))
+	return append(hdr, payload...)
+}
+
+func TestMuxLogReader_DemuxesAndSplitsLines(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(frame(streamStdout, "2024-01-02T03:04:05.000000006Z hello\n2024-01-02T03:04:05.1Z wor"))
+	buf.Write(frame(streamStderr, "2024-01-02T03:04:06Z oops\n"))
+	buf.Write(frame(streamStdout, "ld\n"))
+	buf.Write(frame(streamStdout, "2024-01-02T03:04:07Z tail without newline"))
+
+	r := newMuxLogReader(io.NopCloser(&buf), false, true)
+	want := []LogLine{
+		{Stream: "stdout", Time: "2024-01-02T03:04:05.000000006Z", Line: "hello"},
+		{Stream: "stderr", Time: "2024-01-02T03:04:06Z", Line: "oops"},
+		{Stream: "stdout", Time: "2024-01-02T03:04:05.1Z", Line: "world"},
+		{Stream: "stdout", Time: "2024-01-02T03:04:07Z", Line: "tail without newline"},
+	}
+	for i, w := range want {
+		got, err := r.Next()
+		if err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		if got != w {
+			t.Fatalf("line %d: got %+v want %+v", i, got, w)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestMuxLogReader_TTYAndSystemError(t *testing.T) {
+	r := newMuxLogReader(io.NopCloser(bytes.NewBufferString("a\r\nb\n")), true, false)
+	for _, w := range []string{"a", "b"} {
+		got, err := r.Next()
+		if err != nil || got.Line != w || got.Stream != "stdout" {
+			t.Fatalf("got %+v err=%v want %q", got, err, w)
+		}
+	}
+
+	r = newMuxLogReader(io.NopCloser(bytes.NewReader(frame(streamSystemErr, "boom"))), false, false)
+	if _, err := r.Next(); err == nil || err == io.EOF {
+		t.Fatalf("expected daemon error, got %v", err)
+	}
+}
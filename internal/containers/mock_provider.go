@@ -1,77 +1,742 @@
 package containers
 
 import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// mock 容器的生命週期狀態；exited 代表主程序自行結束，對外狀態與 stopped 相同。
+const (
+	mockCreated = "created"
+	mockRunning = "running"
+	mockStopped = "stopped"
+	mockExited  = "exited"
+)
+
+// mockTransitions 各操作允許的來源狀態與轉換後狀態；Delete 可在任何狀態執行。
+var mockTransitions = map[string]map[string]string{
+	"start": {mockCreated: mockRunning, mockStopped: mockRunning, mockExited: mockRunning},
+	"stop":  {mockRunning: mockStopped},
+	"exit":  {mockRunning: mockExited},
+}
+
+// MockExecResponse 預先設定的 exec/RunJob 回應。
+type MockExecResponse struct {
+	ExitCode  int
+	Stdout    string
+	Stderr    string
+	Delay     time.Duration // 回應前等待的時間，模擬長時間執行的命令
+	Err       error         // 非 nil 時直接回傳此錯誤
+	OOMKilled bool          // RunJob 回報因記憶體不足被終止
+}
+
+// MockFault 在每個操作開始前被呼叫；回傳非 nil 錯誤時該操作以此錯誤失敗。
+// op 為 create|start|stop|delete|exec|list|inspect|logs|attach|runjob|build|
+// pull|listimages|inspectimage|removeimage|pruneimages，id 為容器 ID（create、list、runjob、build、
+// listimages 與 pruneimages 為空字串）；映像操作的 id 為映像名稱，pull 也包含建立容器與作業時本機沒有映像的隱含拉取。
+type MockFault func(op, id string) error
+
 // MockProvider 以記憶體模擬容器生命週期，便於測試與無 Docker 環境。
 type MockProvider struct {
 	mu         sync.RWMutex
-	containers map[string]Container
+	containers map[string]*mockContainer
+	scripts    map[string]MockExecResponse // 完整命令列或程式名稱 -> 回應
+	fault      MockFault
+	failNext   map[string][]error // op -> 依序回傳一次的錯誤
+	jobs       []JobOptions
+	builds     []BuildOptions
+	images     map[string]*Image // 映像 ID -> 映像
+	nextPort   int               // 下一個自動分配的主機連接埠
+}
+
+type mockContainer struct {
+	info     Container
+	state    string
+	exitCode int
+	opts     CreateOptions
+	logs     []mockLogLine
+}
+
+type mockLogLine struct {
+	at   time.Time
+	line LogLine
 }
 
 func NewMockProvider() *MockProvider {
-	return &MockProvider{containers: make(map[string]Container)}
+	return &MockProvider{
+		containers: make(map[string]*mockContainer),
+		scripts:    make(map[string]MockExecResponse),
+		failNext:   make(map[string][]error),
+		images:     make(map[string]*Image),
+		nextPort:   32768,
+	}
+}
+
+// SetExecResponse 設定命令的回應。command 可為以空白連接的完整命令列（優先比對）或程式名稱。
+func (m *MockProvider) SetExecResponse(command string, r MockExecResponse) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scripts[command] = r
+}
+
+// SetFault 設定錯誤注入函式，傳入 nil 取消。
+func (m *MockProvider) SetFault(f MockFault) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fault = f
+}
+
+// FailNext 讓下一次 op 操作回傳 err；可重複呼叫以排入多個錯誤。
+func (m *MockProvider) FailNext(op string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.failNext[op] = append(m.failNext[op], err)
+}
+
+// injectedLocked 回傳注入的錯誤；呼叫端需持有寫鎖。
+func (m *MockProvider) injectedLocked(op, id string) error {
+	if errs := m.failNext[op]; len(errs) > 0 {
+		m.failNext[op] = errs[1:]
+		return errs[0]
+	}
+	if m.fault != nil {
+		return m.fault(op, id)
+	}
+	return nil
+}
+
+// CreateOptions 回傳建立容器時收到的參數（已套用伺服器上限），供測試斷言。
+func (m *MockProvider) CreateOptions(id string) (CreateOptions, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.containers[id]
+	if !ok {
+		return CreateOptions{}, false
+	}
+	return c.opts, true
+}
+
+// Jobs 回傳 RunJob 收到的參數，供測試斷言。
+func (m *MockProvider) Jobs() []JobOptions {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]JobOptions(nil), m.jobs...)
+}
+
+// Builds 回傳 BuildImage 收到的參數，供測試斷言。
+func (m *MockProvider) Builds() []BuildOptions {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]BuildOptions(nil), m.builds...)
+}
+
+// AppendLog 寫入一行模擬日誌，stream 為 stdout 或 stderr。
+func (m *MockProvider) AppendLog(id, stream, line string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.containers[id]; ok {
+		c.appendLog(stream, line)
+	}
+}
+
+func (c *mockContainer) appendLog(stream, line string) {
+	c.logs = append(c.logs, mockLogLine{at: time.Now().UTC(), line: LogLine{Stream: stream, Line: line}})
+}
+
+// transitionLocked 依 mockTransitions 轉換狀態，非法轉換回傳 *StateError。
+func (m *MockProvider) transitionLocked(op, id string) (*mockContainer, error) {
+	c, ok := m.containers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	next, ok := mockTransitions[op][c.state]
+	if !ok {
+		return nil, &StateError{ID: id, Op: op, State: c.info.Status}
+	}
+	c.state = next
+	c.info.Status = next
+	if next == mockExited {
+		c.info.Status = mockStopped
+	}
+	return c, nil
 }
 
-func (m *MockProvider) Create(opts CreateOptions) (Container, error) {
+func (m *MockProvider) Create(ctx context.Context, opts CreateOptions) (Container, error) {
+	if err := opts.Validate(); err != nil {
+		return Container{}, err
+	}
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if err := m.injectedLocked("create", ""); err != nil {
+		return Container{}, err
+	}
+	if err := m.ensureImageLocked(opts.Image); err != nil {
+		return Container{}, err
+	}
 	id := uuid.NewString()
 	c := Container{
 		ID:        id,
 		Name:      opts.Name,
 		Image:     opts.Image,
 		CreatedAt: time.Now().Unix(),
-		Status:    "created",
+		Status:    mockCreated,
 	}
-	m.containers[id] = c
+	m.containers[id] = &mockContainer{info: c, state: mockCreated, opts: opts}
 	return c, nil
 }
 
-func (m *MockProvider) Start(id string) error {
+// Start 啟動 created、stopped 或 exited 的容器；已在執行中回傳 *StateError。
+func (m *MockProvider) Start(ctx context.Context, id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	c, ok := m.containers[id]
-	if !ok {
-		return ErrNotFound
+	if err := m.injectedLocked("start", id); err != nil {
+		return err
 	}
-	c.Status = "running"
-	m.containers[id] = c
+	c, err := m.transitionLocked("start", id)
+	if err != nil {
+		return err
+	}
+	c.exitCode = 0
+	c.info.Ports = m.assignPortsLocked(c.opts.Ports)
 	return nil
 }
 
-func (m *MockProvider) Stop(id string) error {
+// assignPortsLocked 模擬 Docker 在啟動時分配主機連接埠：hostPort 為 0 者依序自動分配。
+func (m *MockProvider) assignPortsLocked(ports []PortMapping) []PortMapping {
+	if len(ports) == 0 {
+		return nil
+	}
+	out := make([]PortMapping, 0, len(ports))
+	for _, p := range ports {
+		p.Protocol = p.protocol()
+		if p.HostIP == "" {
+			p.HostIP = "0.0.0.0"
+		}
+		if p.HostPort == 0 {
+			p.HostPort = m.nextPort
+			m.nextPort++
+		}
+		out = append(out, p)
+	}
+	sortPorts(out)
+	return out
+}
+
+// Stop 停止執行中的容器；其他狀態回傳 *StateError。
+func (m *MockProvider) Stop(ctx context.Context, id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	c, ok := m.containers[id]
-	if !ok {
-		return ErrNotFound
+	if err := m.injectedLocked("stop", id); err != nil {
+		return err
+	}
+	c, err := m.transitionLocked("stop", id)
+	if err != nil {
+		return err
 	}
-	c.Status = "stopped"
-	m.containers[id] = c
+	c.info.Ports = nil
 	return nil
 }
 
-func (m *MockProvider) Delete(id string) error {
+// Exit 模擬容器主程序以 code 自行結束（running -> exited）。
+func (m *MockProvider) Exit(id string, code int) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	if _, ok := m.containers[id]; !ok {
-		return ErrNotFound
+	c, err := m.transitionLocked("exit", id)
+	if err != nil {
+		return err
 	}
-	delete(m.containers, id)
+	c.exitCode = code
+	c.info.Ports = nil
 	return nil
 }
 
-func (m *MockProvider) Exec(id string, cmd []string) (int, string, error) {
+// ExitCode 回傳容器主程序的結束碼；僅在 exited 狀態下 ok 為 true。
+func (m *MockProvider) ExitCode(id string) (code int, ok bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
+	c, found := m.containers[id]
+	if !found || c.state != mockExited {
+		return 0, false
+	}
+	return c.exitCode, true
+}
+
+// Delete 移除任何狀態的容器（與 Docker 的 force remove 相同）。
+func (m *MockProvider) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("delete", id); err != nil {
+		return err
+	}
 	if _, ok := m.containers[id]; !ok {
-		return 0, "", ErrNotFound
+		return ErrNotFound
+	}
+	delete(m.containers, id)
+	return nil
+}
+
+// scriptLocked 依完整命令列、再依程式名稱尋找預設回應；
+// 未設定時模擬 echo/true/false，其他命令回傳命令名稱。
+func (m *MockProvider) scriptLocked(cmd []string) MockExecResponse {
+	if r, ok := m.scripts[strings.Join(cmd, " ")]; ok {
+		return r
+	}
+	if len(cmd) == 0 {
+		return MockExecResponse{}
+	}
+	if r, ok := m.scripts[cmd[0]]; ok {
+		return r
+	}
+	switch cmd[0] {
+	case "echo":
+		return MockExecResponse{Stdout: strings.Join(cmd[1:], " ") + "\n"}
+	case "true":
+		return MockExecResponse{}
+	case "false":
+		return MockExecResponse{ExitCode: 1}
+	}
+	return MockExecResponse{Stdout: "mock exec: " + cmd[0]}
+}
+
+// Exec 在執行中的容器內回傳預設回應；Delay 期間不持有鎖。
+func (m *MockProvider) Exec(ctx context.Context, id string, cmd []string) (ExecResult, error) {
+	m.mu.Lock()
+	if err := m.injectedLocked("exec", id); err != nil {
+		m.mu.Unlock()
+		return ExecResult{}, err
+	}
+	c, ok := m.containers[id]
+	if !ok {
+		m.mu.Unlock()
+		return ExecResult{}, ErrNotFound
+	}
+	if c.state != mockRunning {
+		m.mu.Unlock()
+		return ExecResult{}, &StateError{ID: id, Op: "exec", State: c.info.Status}
+	}
+	r := m.scriptLocked(cmd)
+	m.mu.Unlock()
+
+	if err := mockDelay(ctx, r.Delay); err != nil {
+		return ExecResult{}, err
+	}
+	if r.Err != nil {
+		return ExecResult{}, r.Err
+	}
+	m.mu.Lock()
+	if c, ok := m.containers[id]; ok {
+		for _, l := range splitLogLines(r.Stdout) {
+			c.appendLog("stdout", l)
+		}
+		for _, l := range splitLogLines(r.Stderr) {
+			c.appendLog("stderr", l)
+		}
+	}
+	m.mu.Unlock()
+	return ExecResult{ExitCode: r.ExitCode, Stdout: r.Stdout, Stderr: r.Stderr}, nil
+}
+
+// mockDelay 模擬耗時操作；ctx 先結束時回傳 ctx.Err()。
+func mockDelay(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func splitLogLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// RunJob 依 opts.Cmd 的預設回應模擬一次性作業，logs 為 stdout 與 stderr 的合併內容。
+func (m *MockProvider) RunJob(ctx context.Context, opts JobOptions) (JobResult, error) {
+	m.mu.Lock()
+	if err := m.injectedLocked("runjob", ""); err != nil {
+		m.mu.Unlock()
+		return JobResult{}, err
+	}
+	if err := m.ensureImageLocked(opts.Image); err != nil {
+		m.mu.Unlock()
+		return JobResult{}, err
+	}
+	m.jobs = append(m.jobs, opts)
+	r := m.scriptLocked(opts.Cmd)
+	m.mu.Unlock()
+
+	// Delay 超過逾時視為作業被終止，回傳逾時前的 stdout
+	if r.Delay > opts.timeout() {
+		if err := mockDelay(ctx, opts.timeout()); err != nil {
+			return JobResult{}, err
+		}
+		return JobResult{ExitCode: 137, Logs: r.Stdout, Status: JobTimedOut}, nil
+	}
+	if err := mockDelay(ctx, r.Delay); err != nil {
+		return JobResult{}, err
+	}
+	if r.Err != nil {
+		return JobResult{}, r.Err
+	}
+	res := exitedJob(int64(r.ExitCode), r.Stdout+r.Stderr)
+	res.OOMKilled = r.OOMKilled
+	return res, nil
+}
+
+func (m *MockProvider) List(ctx context.Context, filter ListFilter) ([]Container, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("list", ""); err != nil {
+		return nil, err
+	}
+	out := make([]Container, 0, len(m.containers))
+	for _, c := range m.containers {
+		if filter.Match(c.info) {
+			out = append(out, c.info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt < out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (m *MockProvider) Inspect(ctx context.Context, id string) (Container, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("inspect", id); err != nil {
+		return Container{}, err
+	}
+	c, ok := m.containers[id]
+	if !ok {
+		return Container{}, ErrNotFound
+	}
+	return c.info, nil
+}
+
+// Logs 回傳目前累積的模擬日誌；Follow 不會等待新資料。
+func (m *MockProvider) Logs(ctx context.Context, id string, opts LogsOptions) (LogReader, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("logs", id); err != nil {
+		return nil, err
+	}
+	c, ok := m.containers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	opts = opts.normalize()
+	var lines []LogLine
+	for _, l := range c.logs {
+		if (l.line.Stream == "stdout" && !opts.Stdout) || (l.line.Stream == "stderr" && !opts.Stderr) {
+			continue
+		}
+		if (!opts.Since.IsZero() && l.at.Before(opts.Since)) || (!opts.Until.IsZero() && !l.at.Before(opts.Until)) {
+			continue
+		}
+		line := l.line
+		if opts.Timestamps {
+			line.Time = l.at.Format(time.RFC3339Nano)
+		}
+		lines = append(lines, line)
+	}
+	if opts.Tail >= 0 && len(lines) > opts.Tail {
+		lines = lines[len(lines)-opts.Tail:]
+	}
+	return &sliceLogReader{lines: lines}, nil
+}
+
+// Attach 回傳一個回音 session：寫入的內容會原樣出現在輸出。容器需在執行中。
+func (m *MockProvider) Attach(ctx context.Context, id string, opts AttachOptions) (TTYSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("attach", id); err != nil {
+		return nil, err
+	}
+	c, ok := m.containers[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if c.state != mockRunning {
+		return nil, &StateError{ID: id, Op: "attach", State: c.info.Status}
+	}
+	opts = opts.normalize()
+	return newEchoSession(opts.Cols, opts.Rows), nil
+}
+
+// BuildImage 模擬建置：Dockerfile 的每個指令輸出為一個步驟；Dockerfile 不存在時回傳 ErrBuildFailed。
+func (m *MockProvider) BuildImage(ctx context.Context, opts BuildOptions, progress func(line string)) (BuildResult, error) {
+	m.mu.Lock()
+	if err := m.injectedLocked("build", ""); err != nil {
+		m.mu.Unlock()
+		return BuildResult{}, err
+	}
+	m.builds = append(m.builds, opts)
+	m.mu.Unlock()
+
+	b, err := os.ReadFile(filepath.Join(opts.ContextDir, opts.dockerfile()))
+	if err != nil {
+		return BuildResult{}, &Error{Kind: ErrBuildFailed, Op: "build " + opts.Tag, Err: err}
+	}
+	var steps []string
+	for _, l := range splitLogLines(string(b)) {
+		if l = strings.TrimSpace(l); l != "" && !strings.HasPrefix(l, "#") {
+			steps = append(steps, l)
+		}
+	}
+	for i, s := range steps {
+		if err := ctx.Err(); err != nil {
+			return BuildResult{}, err
+		}
+		progress(fmt.Sprintf("Step %d/%d : %s", i+1, len(steps), s))
+	}
+	progress("Successfully tagged " + opts.Tag)
+	id := fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+	m.mu.Lock()
+	m.tagImageLocked(Image{ID: id, Size: int64(len(b)), CreatedAt: time.Now().Unix(), Labels: map[string]string{managedLabel: "true"}, Built: true}, opts.Tag)
+	m.mu.Unlock()
+	return BuildResult{Image: opts.Tag, ID: id}, nil
+}
+
+// mockImageSize 拉取的映像的大小。
+const mockImageSize = 5 << 20
+
+// ensureImageLocked 模擬建立容器前的拉取：本機沒有映像時經過 pull 的錯誤注入後加入映像；
+// 建置的映像不拉取。呼叫端需持有寫鎖。
+func (m *MockProvider) ensureImageLocked(ref string) error {
+	if builtImage(ref) {
+		return nil
+	}
+	if _, ok := m.imageLocked(ref); ok {
+		return nil
+	}
+	if err := m.injectedLocked("pull", ref); err != nil {
+		return err
+	}
+	m.pullLocked(ref)
+	return nil
+}
+
+// pullLocked 加入映像，ID 由名稱產生；呼叫端需持有寫鎖。
+func (m *MockProvider) pullLocked(ref string) *Image {
+	tag := ref
+	if n, err := NormalizeImageRef(ref); err == nil {
+		tag = n
 	}
-	// Mock: 模擬執行成功，回傳命令內容
-	return 0, "mock exec: " + cmd[0], nil
+	id := fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(tag)))
+	return m.tagImageLocked(Image{ID: id, Size: mockImageSize, CreatedAt: time.Now().Unix()}, tag)
 }
+
+// tagImageLocked 加入映像（已存在時沿用）並把 tag 從原本的映像移到此映像，原本的映像沒有其他 tag 時成為 dangling。
+func (m *MockProvider) tagImageLocked(img Image, tag string) *Image {
+	for _, other := range m.images {
+		other.Tags = slices.DeleteFunc(other.Tags, func(t string) bool { return t == tag })
+	}
+	cur, ok := m.images[img.ID]
+	if !ok {
+		img.Tags = []string{}
+		cur = &img
+		m.images[img.ID] = cur
+	}
+	cur.Tags = append(cur.Tags, tag)
+	return cur
+}
+
+// imageLocked 以 ID（可省略 sha256: 前綴，或至少 12 個字元的前綴）或名稱尋找映像。
+func (m *MockProvider) imageLocked(ref string) (*Image, bool) {
+	tag := ref
+	if n, err := NormalizeImageRef(ref); err == nil {
+		tag = n
+	}
+	for id, img := range m.images {
+		hex := strings.TrimPrefix(id, "sha256:")
+		if ref == id || ref == hex || (len(ref) >= 12 && strings.HasPrefix(hex, ref)) || slices.Contains(img.Tags, tag) {
+			return img, true
+		}
+	}
+	return nil, false
+}
+
+// imageUsersLocked 回傳使用映像的容器 ID。
+func (m *MockProvider) imageUsersLocked(img *Image) []string {
+	var ids []string
+	for id, c := range m.containers {
+		if u, ok := m.imageLocked(c.opts.Image); ok && u.ID == img.ID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// mockRepository 去掉名稱的 tag 或 digest。
+func mockRepository(ref string) string {
+	if i := strings.IndexByte(ref, '@'); i >= 0 {
+		return ref[:i]
+	}
+	if i := strings.LastIndexByte(ref, ':'); i > strings.LastIndexByte(ref, '/') {
+		return ref[:i]
+	}
+	return ref
+}
+
+func noSuchImage(op, ref string) error {
+	return &Error{Kind: ErrNotFound, Op: op + " " + ref, Err: fmt.Errorf("no such image: %s", ref)}
+}
+
+// PullImage 模擬拉取：依序回報開始、下載與完成的進度；本機已有映像時只回報已是最新版本。
+func (m *MockProvider) PullImage(ctx context.Context, ref string, progress func(PullProgress)) (Image, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("pull", ref); err != nil {
+		return Image{}, err
+	}
+	if img, ok := m.imageLocked(ref); ok {
+		progress(PullProgress{Status: "Status: Image is up to date for " + ref})
+		return *img, nil
+	}
+	img := m.pullLocked(ref)
+	layer := strings.TrimPrefix(img.ID, "sha256:")[:12]
+	progress(PullProgress{ID: layer, Status: "Pulling fs layer"})
+	progress(PullProgress{ID: layer, Status: "Downloading", Current: img.Size / 2, Total: img.Size})
+	progress(PullProgress{ID: layer, Status: "Pull complete"})
+	progress(PullProgress{Status: "Status: Downloaded newer image for " + ref})
+	return *img, nil
+}
+
+// ListImages 依 filter 列出映像，由新到舊。
+func (m *MockProvider) ListImages(ctx context.Context, filter ImageFilter) ([]Image, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("listimages", ""); err != nil {
+		return nil, err
+	}
+	out := []Image{}
+	for _, img := range m.images {
+		if (filter.Dangling && len(img.Tags) > 0) || (filter.Built && !img.Built) {
+			continue
+		}
+		if filter.Reference != "" && !slices.ContainsFunc(img.Tags, func(t string) bool {
+			ok1, _ := path.Match(filter.Reference, t)
+			ok2, _ := path.Match(filter.Reference, mockRepository(t))
+			return ok1 || ok2
+		}) {
+			continue
+		}
+		out = append(out, *img)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt != out[j].CreatedAt {
+			return out[i].CreatedAt > out[j].CreatedAt
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+// InspectImage 回傳映像；模擬的映像皆為 linux/amd64。
+func (m *MockProvider) InspectImage(ctx context.Context, ref string) (Image, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("inspectimage", ref); err != nil {
+		return Image{}, err
+	}
+	img, ok := m.imageLocked(ref)
+	if !ok {
+		return Image{}, noSuchImage("inspect image", ref)
+	}
+	out := *img
+	out.Architecture, out.OS = "amd64", "linux"
+	return out, nil
+}
+
+// RemoveImage 與 Docker 相同：ref 為其中一個 tag 且映像還有其他 tag 時只移除該 tag；
+// 否則刪除映像，容器仍在使用時回傳 ErrConflict（force 除外）。
+func (m *MockProvider) RemoveImage(ctx context.Context, ref string, force bool) ([]ImageDeleted, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("removeimage", ref); err != nil {
+		return nil, err
+	}
+	img, ok := m.imageLocked(ref)
+	if !ok {
+		return nil, noSuchImage("remove image", ref)
+	}
+	if tag, err := NormalizeImageRef(ref); err == nil && len(img.Tags) > 1 && slices.Contains(img.Tags, tag) {
+		img.Tags = slices.DeleteFunc(img.Tags, func(t string) bool { return t == tag })
+		return []ImageDeleted{{Untagged: tag}}, nil
+	}
+	if users := m.imageUsersLocked(img); len(users) > 0 && !force {
+		return nil, &Error{Kind: ErrConflict, Op: "remove image " + ref, Err: fmt.Errorf("image is being used by container %s", users[0])}
+	}
+	return m.deleteImageLocked(img), nil
+}
+
+func (m *MockProvider) deleteImageLocked(img *Image) []ImageDeleted {
+	var out []ImageDeleted
+	for _, t := range img.Tags {
+		out = append(out, ImageDeleted{Untagged: t})
+	}
+	delete(m.images, img.ID)
+	return append(out, ImageDeleted{Deleted: img.ID})
+}
+
+// PruneImages 刪除沒有容器使用且符合條件的映像。
+func (m *MockProvider) PruneImages(ctx context.Context, opts PruneImagesOptions) (PruneReport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.injectedLocked("pruneimages", ""); err != nil {
+		return PruneReport{}, err
+	}
+	report := PruneReport{Deleted: []ImageDeleted{}}
+	cutoff := time.Now().Add(-opts.Until).Unix()
+	ids := make([]string, 0, len(m.images))
+	for id := range m.images {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		img := m.images[id]
+		switch {
+		case !opts.All && len(img.Tags) > 0,
+			opts.Built && !img.Built,
+			opts.Until > 0 && img.CreatedAt > cutoff,
+			len(m.imageUsersLocked(img)) > 0:
+			continue
+		}
+		report.Deleted = append(report.Deleted, m.deleteImageLocked(img)...)
+		report.SpaceReclaimed += uint64(img.Size)
+	}
+	return report, nil
+}
+
+var (
+	_ JobRunner    = (*MockProvider)(nil)
+	_ LogStreamer  = (*MockProvider)(nil)
+	_ Attacher     = (*MockProvider)(nil)
+	_ ImageBuilder = (*MockProvider)(nil)
+	_ ImageManager = (*MockProvider)(nil)
+)
@@ -0,0 +1,168 @@
+package containers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMockProvider_StateMachine(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockProvider()
+	c, _ := m.Create(ctx, CreateOptions{Image: "alpine"})
+
+	var se *StateError
+	if err := m.Stop(ctx, c.ID); !errors.As(err, &se) || se.Op != "stop" || se.State != "created" {
+		t.Fatalf("stop created: %v", err)
+	}
+	if _, err := m.Exec(ctx, c.ID, []string{"true"}); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("exec created: %v", err)
+	}
+	if err := m.Start(ctx, c.ID); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := m.Start(ctx, c.ID); !errors.Is(err, ErrInvalidState) || errors.Is(err, ErrNotRunning) {
+		t.Fatalf("double start: %v", err)
+	}
+
+	// 主程序自行結束：對外狀態為 stopped，並保留結束碼
+	if err := m.Exit(c.ID, 137); err != nil {
+		t.Fatalf("exit: %v", err)
+	}
+	if got, _ := m.Inspect(ctx, c.ID); got.Status != "stopped" {
+		t.Fatalf("status after exit = %s", got.Status)
+	}
+	if code, ok := m.ExitCode(c.ID); !ok || code != 137 {
+		t.Fatalf("exit code = %d, %v", code, ok)
+	}
+	if err := m.Stop(ctx, c.ID); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("stop exited: %v", err)
+	}
+	if _, err := m.Attach(ctx, c.ID, AttachOptions{}); !errors.Is(err, ErrNotRunning) {
+		t.Fatalf("attach exited: %v", err)
+	}
+	if err := m.Start(ctx, c.ID); err != nil {
+		t.Fatalf("restart exited: %v", err)
+	}
+	if _, ok := m.ExitCode(c.ID); ok {
+		t.Fatalf("exit code should reset on restart")
+	}
+
+	if err := m.Delete(ctx, c.ID); err != nil {
+		t.Fatalf("delete running: %v", err)
+	}
+	if err := m.Start(ctx, c.ID); err != ErrNotFound {
+		t.Fatalf("start removed: %v", err)
+	}
+	if err := m.Exit(c.ID, 0); err != ErrNotFound {
+		t.Fatalf("exit removed: %v", err)
+	}
+}
+
+func TestMockProvider_ScriptedExec(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockProvider()
+	c, _ := m.Create(ctx, CreateOptions{Image: "alpine"})
+	_ = m.Start(ctx, c.ID)
+
+	m.SetExecResponse("python", MockExecResponse{ExitCode: 2, Stderr: "Traceback\nValueError\n"})
+	m.SetExecResponse("python -V", MockExecResponse{Stdout: "Python 3.12.1\n", Delay: 20 * time.Millisecond})
+	boom := errors.New("boom")
+	m.SetExecResponse("crash", MockExecResponse{Err: boom})
+
+	start := time.Now()
+	res, err := m.Exec(ctx, c.ID, []string{"python", "-V"})
+	if err != nil || res.Stdout != "Python 3.12.1\n" || res.ExitCode != 0 {
+		t.Fatalf("python -V: %+v %v", res, err)
+	}
+	if time.Since(start) < 20*time.Millisecond {
+		t.Fatalf("delay not applied")
+	}
+	res, err = m.Exec(ctx, c.ID, []string{"python", "main.py"})
+	if err != nil || res.ExitCode != 2 || res.Stderr != "Traceback\nValueError\n" {
+		t.Fatalf("python main.py: %+v %v", res, err)
+	}
+	if _, err := m.Exec(ctx, c.ID, []string{"crash"}); err != boom {
+		t.Fatalf("crash: %v", err)
+	}
+
+	rd, _ := m.Logs(ctx, c.ID, LogsOptions{Tail: -1, Stderr: true})
+	var stderr []string
+	for {
+		l, err := rd.Next()
+		if err != nil {
+			break
+		}
+		stderr = append(stderr, l.Line)
+	}
+	if len(stderr) != 2 || stderr[1] != "ValueError" {
+		t.Fatalf("stderr logs: %v", stderr)
+	}
+}
+
+func TestMockProvider_FaultInjection(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockProvider()
+	c, _ := m.Create(ctx, CreateOptions{Image: "alpine"})
+
+	oneShot := errors.New("daemon unavailable")
+	m.FailNext("start", oneShot)
+	if err := m.Start(ctx, c.ID); err != oneShot {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+	if err := m.Start(ctx, c.ID); err != nil {
+		t.Fatalf("second start should succeed: %v", err)
+	}
+
+	m.SetFault(func(op, id string) error {
+		if op == "inspect" && id == c.ID {
+			return ErrNotFound
+		}
+		return nil
+	})
+	if _, err := m.Inspect(ctx, c.ID); err != ErrNotFound {
+		t.Fatalf("expected fault, got %v", err)
+	}
+	if _, err := m.List(ctx, ListFilter{}); err != nil {
+		t.Fatalf("list should not be affected: %v", err)
+	}
+	m.SetFault(nil)
+	if _, err := m.Inspect(ctx, c.ID); err != nil {
+		t.Fatalf("fault should be cleared: %v", err)
+	}
+}
+
+func TestMockProvider_RunJob(t *testing.T) {
+	ctx := context.Background()
+	m := NewMockProvider()
+	m.SetExecResponse("make test", MockExecResponse{ExitCode: 1, Stdout: "ok 1\n", Stderr: "FAIL 2\n"})
+	res, err := m.RunJob(ctx, JobOptions{Image: "golang:1.24", HostDir: "/data/u1", ContainerDir: "/workspace", Cmd: []string{"make", "test"}})
+	if err != nil || res.ExitCode != 1 || res.Logs != "ok 1\nFAIL 2\n" || res.Status != JobFailed {
+		t.Fatalf("run job: %+v err=%v", res, err)
+	}
+	if jobs := m.Jobs(); len(jobs) != 1 || jobs[0].HostDir != "/data/u1" {
+		t.Fatalf("jobs: %+v", jobs)
+	}
+	m.FailNext("runjob", ErrNotSupported)
+	if _, err := m.RunJob(ctx, JobOptions{Image: "alpine"}); err != ErrNotSupported {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+}
+
+func TestMockProvider_ExecHonoursCancellation(t *testing.T) {
+	m := NewMockProvider()
+	c, _ := m.Create(context.Background(), CreateOptions{Image: "alpine"})
+	_ = m.Start(context.Background(), c.ID)
+	m.SetExecResponse("sleep", MockExecResponse{Delay: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := m.Exec(ctx, c.ID, []string{"sleep", "60"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("exec did not return promptly after cancellation")
+	}
+}
@@ -0,0 +1,105 @@
+package containers
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+)
+
+// PortMapping 將容器連接埠發佈到主機。
+type PortMapping struct {
+	ContainerPort int    `json:"containerPort"`
+	HostPort      int    `json:"hostPort,omitempty"` // 0 代表由 provider 自動分配
+	HostIP        string `json:"hostIp,omitempty"`   // 預設綁定所有介面
+	Protocol      string `json:"protocol,omitempty"` // tcp|udp|sctp，預設 tcp
+}
+
+func (p PortMapping) protocol() string {
+	if p.Protocol == "" {
+		return "tcp"
+	}
+	return p.Protocol
+}
+
+// Validate 檢查連接埠範圍、協定與主機 IP。
+func (p PortMapping) Validate() error {
+	switch {
+	case p.ContainerPort < 1 || p.ContainerPort > 65535:
+		return fmt.Errorf("%w: containerPort must be between 1 and 65535", ErrInvalidOptions)
+	case p.HostPort < 0 || p.HostPort > 65535:
+		return fmt.Errorf("%w: hostPort must be between 0 and 65535", ErrInvalidOptions)
+	case p.HostIP != "" && net.ParseIP(p.HostIP) == nil:
+		return fmt.Errorf("%w: invalid hostIp %q", ErrInvalidOptions, p.HostIP)
+	}
+	switch p.protocol() {
+	case "tcp", "udp", "sctp":
+	default:
+		return fmt.Errorf("%w: unsupported protocol %q", ErrInvalidOptions, p.Protocol)
+	}
+	return nil
+}
+
+// NetworkOptions 容器的網路與 DNS 設定。
+type NetworkOptions struct {
+	Network    string   `json:"network,omitempty"`    // bridge|host|none 或自訂網路名稱，預設 bridge
+	Aliases    []string `json:"aliases,omitempty"`    // 僅適用於自訂網路
+	DNS        []string `json:"dns,omitempty"`        // DNS 伺服器 IP
+	DNSSearch  []string `json:"dnsSearch,omitempty"`  // 搜尋網域
+	DNSOptions []string `json:"dnsOptions,omitempty"` // resolv.conf options，例如 ndots:2
+}
+
+// isolated 回傳此網路模式是否無法發佈連接埠。
+func (n NetworkOptions) isolated() bool {
+	return n.Network == "host" || n.Network == "none"
+}
+
+// Validate 檢查網路設定，並確認與連接埠發佈相容。
+func (n NetworkOptions) Validate(ports []PortMapping) error {
+	if len(n.Aliases) > 0 {
+		switch n.Network {
+		case "", "bridge", "default", "host", "none":
+			return fmt.Errorf("%w: aliases require a user-defined network", ErrInvalidOptions)
+		}
+	}
+	for _, d := range n.DNS {
+		if net.ParseIP(d) == nil {
+			return fmt.Errorf("%w: invalid dns server %q", ErrInvalidOptions, d)
+		}
+	}
+	if len(ports) > 0 && n.isolated() {
+		return fmt.Errorf("%w: ports cannot be published with network %q", ErrInvalidOptions, n.Network)
+	}
+	seen := map[string]bool{}
+	for _, p := range ports {
+		if err := p.Validate(); err != nil {
+			return err
+		}
+		if p.HostPort == 0 {
+			continue
+		}
+		key := p.HostIP + "/" + strconv.Itoa(p.HostPort) + "/" + p.protocol()
+		if seen[key] {
+			return fmt.Errorf("%w: host port %d/%s published more than once", ErrInvalidOptions, p.HostPort, p.protocol())
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// sortPorts 依容器連接埠、協定與主機連接埠排序，讓輸出穩定。
+func sortPorts(ports []PortMapping) {
+	sort.Slice(ports, func(i, j int) bool {
+		a, b := ports[i], ports[j]
+		if a.ContainerPort != b.ContainerPort {
+			return a.ContainerPort < b.ContainerPort
+		}
+		if a.protocol() != b.protocol() {
+			return a.protocol() < b.protocol()
+		}
+		if a.HostPort != b.HostPort {
+			return a.HostPort < b.HostPort
+		}
+		return a.HostIP < b.HostIP
+	})
+}
@@ -0,0 +1,637 @@
+package containers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// podmanAPIVersion libpod REST API 的版本前綴；Podman 4.0 以上皆支援。
+const podmanAPIVersion = "v4.0.0"
+
+// PodmanProvider 透過 unix socket 呼叫 libpod REST API（podman system service）。
+type PodmanProvider struct {
+	client          *http.Client
+	base            string // 例如 http://podman/v4.0.0/libpod
+	execOutputLimit int
+
+	rootlessMu    sync.Mutex
+	rootlessKnown bool
+	rootless      bool
+}
+
+// NewPodmanProvider 連線到 PODMAN_SOCKET 或 CONTAINER_HOST（unix://...）指定的 socket；
+// 未設定時，rootless 使用 $XDG_RUNTIME_DIR/podman/podman.sock，否則 /run/podman/podman.sock。
+func NewPodmanProvider() *PodmanProvider {
+	return NewPodmanProviderWith(podmanSocketFromEnv())
+}
+
+// NewPodmanProviderWith 連線到指定的 unix socket，便於測試。
+func NewPodmanProviderWith(socket string) *PodmanProvider {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	return &PodmanProvider{
+		client:          &http.Client{Transport: transport},
+		base:            "http://podman/" + podmanAPIVersion + "/libpod",
+		execOutputLimit: execOutputLimitFromEnv(),
+	}
+}
+
+func podmanSocketFromEnv() string {
+	if s := os.Getenv("PODMAN_SOCKET"); s != "" {
+		return s
+	}
+	if s := os.Getenv("CONTAINER_HOST"); strings.HasPrefix(s, "unix://") {
+		return strings.TrimPrefix(s, "unix://")
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" && os.Getuid() != 0 {
+		return filepath.Join(dir, "podman", "podman.sock")
+	}
+	return "/run/podman/podman.sock"
+}
+
+// podmanError libpod 的錯誤回應格式。
+type podmanError struct {
+	Cause    string `json:"cause"`
+	Message  string `json:"message"`
+	Response int    `json:"response"`
+}
+
+func (e *podmanError) Error() string { return "podman: " + e.Message }
+
+// Code 依 libpod 的狀態碼分類錯誤。
+func (e *podmanError) Code() ErrorCode {
+	switch e.Response {
+	case http.StatusBadRequest:
+		return CodeInvalidArgument
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusNotImplemented:
+		return CodeUnsupported
+	}
+	return CodeInternal
+}
+
+// do 送出請求；狀態碼 >= 400 時解析錯誤並關閉 body，404 轉成 ErrNotFound。
+func (p *PodmanProvider) do(ctx context.Context, method, path string, query url.Values, body any) (*http.Response, error) {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		rd = bytes.NewReader(b)
+	}
+	u := p.base + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, rd)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 400 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	perr := &podmanError{Response: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(perr); err != nil || perr.Message == "" {
+		perr.Message = resp.Status
+	}
+	return nil, perr
+}
+
+// call 送出請求並將回應解碼到 out（可為 nil）。
+func (p *PodmanProvider) call(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	resp, err := p.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// isRootless 查詢 /info 判斷 Podman 是否以 rootless 模式執行；只快取查詢成功的結果，
+// 服務暫時無法連線時下次再查。
+func (p *PodmanProvider) isRootless(ctx context.Context) bool {
+	p.rootlessMu.Lock()
+	defer p.rootlessMu.Unlock()
+	if p.rootlessKnown {
+		return p.rootless
+	}
+	var info struct {
+		Host struct {
+			Security struct {
+				Rootless bool `json:"rootless"`
+			} `json:"security"`
+		} `json:"host"`
+	}
+	if err := p.call(ctx, http.MethodGet, "/info", nil, nil, &info); err != nil {
+		return false
+	}
+	p.rootless, p.rootlessKnown = info.Host.Security.Rootless, true
+	return p.rootless
+}
+
+// podmanSpec libpod SpecGenerator 中本服務使用到的欄位。
+type podmanSpec struct {
+	Name           string                       `json:"name,omitempty"`
+	Image          string                       `json:"image"`
+	Env            map[string]string            `json:"env,omitempty"`
+	Entrypoint     []string                     `json:"entrypoint,omitempty"`
+	Command        []string                     `json:"command,omitempty"`
+	WorkDir        string                       `json:"work_dir,omitempty"`
+	Labels         map[string]string            `json:"labels,omitempty"`
+	Hostname       string                       `json:"hostname,omitempty"`
+	Mounts         []podmanMount                `json:"mounts,omitempty"`
+	PortMappings   []podmanPort                 `json:"portmappings,omitempty"`
+	NetNS          *podmanNamespace             `json:"netns,omitempty"`
+	Networks       map[string]podmanNetworkOpts `json:"networks,omitempty"`
+	DNSServer      []string                     `json:"dns_server,omitempty"`
+	DNSSearch      []string                     `json:"dns_search,omitempty"`
+	DNSOption      []string                     `json:"dns_option,omitempty"`
+	ResourceLimits *podmanResources             `json:"resource_limits,omitempty"`
+	Rlimits        []podmanRlimit               `json:"r_limits,omitempty"`
+	ReadOnlyFS     bool                         `json:"read_only_filesystem,omitempty"`
+	CapDrop        []string                     `json:"cap_drop,omitempty"`
+	NoNewPrivs     bool                         `json:"no_new_privileges,omitempty"`
+	User           string                       `json:"user,omitempty"`
+	UserNS         *podmanNamespace             `json:"userns,omitempty"`
+}
+
+type podmanMount struct {
+	Destination string   `json:"destination"`
+	Source      string   `json:"source"`
+	Type        string   `json:"type"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type podmanPort struct {
+	ContainerPort uint16 `json:"container_port"`
+	HostPort      uint16 `json:"host_port,omitempty"`
+	HostIP        string `json:"host_ip,omitempty"`
+	Protocol      string `json:"protocol,omitempty"`
+}
+
+type podmanNamespace struct {
+	NSMode string `json:"nsmode"`
+}
+
+type podmanNetworkOpts struct {
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// podmanResources 對應 OCI runtime spec 的 LinuxResources。
+type podmanResources struct {
+	CPU    *podmanCPU    `json:"cpu,omitempty"`
+	Memory *podmanMemory `json:"memory,omitempty"`
+	Pids   *podmanPids   `json:"pids,omitempty"`
+}
+
+type podmanCPU struct {
+	Quota  int64  `json:"quota,omitempty"`
+	Period uint64 `json:"period,omitempty"`
+	Shares uint64 `json:"shares,omitempty"`
+}
+
+type podmanMemory struct {
+	Limit int64 `json:"limit,omitempty"`
+	Swap  int64 `json:"swap,omitempty"`
+}
+
+type podmanPids struct {
+	Limit int64 `json:"limit"`
+}
+
+type podmanRlimit struct {
+	Type string `json:"type"`
+	Hard uint64 `json:"hard"`
+	Soft uint64 `json:"soft"`
+}
+
+// podmanBaseSpec 依資源限制、安全設定與掛載組成 spec；rootless 時讓上傳目錄可被容器寫入。
+// 掛載一律加上 SELinux 共用標籤 z，rootful 與 rootless 在啟用 SELinux 的宿主機上都需要重新標記，
+// 未啟用 SELinux 時 podman 會忽略此選項。
+func podmanBaseSpec(image string, r ResourceLimits, sec SecurityOptions, mounts map[string]string, rootless bool) podmanSpec {
+	spec := podmanSpec{
+		Image:      image,
+		ReadOnlyFS: sec.ReadOnlyRootfs,
+		CapDrop:    sec.CapDrop,
+		NoNewPrivs: sec.NoNewPrivileges,
+		User:       sec.userSpec(),
+	}
+	var res podmanResources
+	if r.CPUQuota > 0 || r.CPUPeriod > 0 || r.CPUShares > 0 {
+		res.CPU = &podmanCPU{Quota: r.CPUQuota, Period: uint64(r.CPUPeriod), Shares: uint64(r.CPUShares)}
+	}
+	if r.Memory > 0 || r.MemorySwap != 0 {
+		res.Memory = &podmanMemory{Limit: r.Memory, Swap: r.MemorySwap}
+	}
+	if r.PidsLimit > 0 {
+		res.Pids = &podmanPids{Limit: r.PidsLimit}
+	}
+	if res.CPU != nil || res.Memory != nil || res.Pids != nil {
+		spec.ResourceLimits = &res
+	}
+	for _, u := range r.Ulimits {
+		spec.Rlimits = append(spec.Rlimits, podmanRlimit{Type: u.Name, Hard: uint64(u.Hard), Soft: uint64(u.Soft)})
+	}
+
+	hostDirs := make([]string, 0, len(mounts))
+	for hostDir := range mounts {
+		hostDirs = append(hostDirs, hostDir)
+	}
+	sort.Strings(hostDirs)
+	options := []string{"rbind", "z"}
+	for _, hostDir := range hostDirs {
+		spec.Mounts = append(spec.Mounts, podmanMount{Destination: mounts[hostDir], Source: hostDir, Type: "bind", Options: options})
+	}
+	if rootless && len(spec.Mounts) > 0 && spec.User == "" {
+		// keep-id 讓容器內的使用者對應到宿主機上擁有上傳目錄的使用者
+		spec.UserNS = &podmanNamespace{NSMode: "keep-id"}
+	}
+	return spec
+}
+
+// setNetwork 設定網路模式；空字串沿用 podman 預設，其餘名稱視為自訂網路。
+func (spec *podmanSpec) setNetwork(n string, aliases []string) {
+	switch n {
+	case "":
+	case "default":
+		spec.NetNS = &podmanNamespace{NSMode: "bridge"}
+	case "bridge", "host", "none":
+		spec.NetNS = &podmanNamespace{NSMode: n}
+	default:
+		spec.NetNS = &podmanNamespace{NSMode: "bridge"}
+		spec.Networks = map[string]podmanNetworkOpts{n: {Aliases: aliases}}
+	}
+}
+
+// ensureImage 建立容器前拉取映像。拉取失敗但本機已有映像時照常使用，讓離線環境仍可使用快取的映像。
+func (p *PodmanProvider) ensureImage(ctx context.Context, ref string) error {
+	err := p.pull(ctx, ref)
+	if err == nil {
+		return nil
+	}
+	if p.call(ctx, http.MethodGet, "/images/"+ref+"/exists", nil, nil, nil) == nil {
+		return nil
+	}
+	return pullError(ref, err)
+}
+
+// pull 拉取映像並讀完回應；libpod 在狀態碼 200 的串流中以 error 欄位回報拉取失敗。
+func (p *PodmanProvider) pull(ctx context.Context, ref string) error {
+	resp, err := p.do(ctx, http.MethodPost, "/images/pull", url.Values{"reference": {ref}, "quiet": {"true"}}, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if msg.Error != "" {
+			return errors.New("podman: " + msg.Error)
+		}
+	}
+}
+
+func (p *PodmanProvider) Create(ctx context.Context, opts CreateOptions) (Container, error) {
+	if err := opts.Validate(); err != nil {
+		return Container{}, err
+	}
+	if err := p.ensureImage(ctx, opts.Image); err != nil {
+		return Container{}, err
+	}
+
+	spec := podmanBaseSpec(opts.Image, opts.Resources, opts.Security, opts.Mounts, p.isRootless(ctx))
+	spec.Name = opts.Name
+	spec.Env = opts.Env
+	spec.Entrypoint = opts.Entrypoint
+	spec.Command = opts.Cmd
+	if len(opts.Entrypoint) == 0 && len(opts.Cmd) == 0 {
+		spec.Command = []string{"tail", "-f", "/dev/null"}
+	}
+	spec.WorkDir = opts.WorkingDir
+	spec.Hostname = opts.Hostname
+	spec.Labels = map[string]string{}
+	for k, v := range opts.Labels {
+		spec.Labels[k] = v
+	}
+	spec.Labels[managedLabel] = "true"
+	for _, pm := range opts.Ports {
+		spec.PortMappings = append(spec.PortMappings, podmanPort{
+			ContainerPort: uint16(pm.ContainerPort),
+			HostPort:      uint16(pm.HostPort),
+			HostIP:        pm.HostIP,
+			Protocol:      pm.protocol(),
+		})
+	}
+	spec.setNetwork(opts.Network.Network, opts.Network.Aliases)
+	spec.DNSServer = opts.Network.DNS
+	spec.DNSSearch = opts.Network.DNSSearch
+	spec.DNSOption = opts.Network.DNSOptions
+
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := p.call(ctx, http.MethodPost, "/containers/create", nil, spec, &created); err != nil {
+		return Container{}, err
+	}
+	return Container{
+		ID:        created.ID,
+		Name:      opts.Name,
+		Image:     opts.Image,
+		CreatedAt: time.Now().Unix(),
+		Status:    "created",
+	}, nil
+}
+
+// Start 啟動容器；已在執行中時 libpod 回傳 304，轉成 *StateError。
+func (p *PodmanProvider) Start(ctx context.Context, id string) error {
+	return p.transition(ctx, id, "start", nil, "running")
+}
+
+// Stop 停止容器；未在執行中時 libpod 回傳 304，轉成 *StateError。
+func (p *PodmanProvider) Stop(ctx context.Context, id string) error {
+	return p.transition(ctx, id, "stop", url.Values{"timeout": {"10"}}, "stopped")
+}
+
+func (p *PodmanProvider) transition(ctx context.Context, id, op string, query url.Values, unchanged string) error {
+	resp, err := p.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/"+op, query, nil)
+	if err != nil {
+		return err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		return &StateError{ID: id, Op: op, State: unchanged}
+	}
+	return nil
+}
+
+func (p *PodmanProvider) Delete(ctx context.Context, id string) error {
+	return p.call(ctx, http.MethodDelete, "/containers/"+url.PathEscape(id), url.Values{"force": {"true"}}, nil, nil)
+}
+
+// podmanListEntry libpod 的容器列表項目。
+type podmanListEntry struct {
+	ID      string    `json:"Id"`
+	Names   []string  `json:"Names"`
+	Image   string    `json:"Image"`
+	Created time.Time `json:"Created"`
+	State   string    `json:"State"`
+	Ports   []struct {
+		HostIP        string `json:"host_ip"`
+		ContainerPort uint16 `json:"container_port"`
+		HostPort      uint16 `json:"host_port"`
+		Protocol      string `json:"protocol"`
+	} `json:"Ports"`
+}
+
+// List 列出本服務建立的容器，狀態篩選在本地比對。
+func (p *PodmanProvider) List(ctx context.Context, filter ListFilter) ([]Container, error) {
+	f, _ := json.Marshal(map[string][]string{"label": {managedLabel + "=true"}})
+	var entries []podmanListEntry
+	if err := p.call(ctx, http.MethodGet, "/containers/json", url.Values{"all": {"true"}, "filters": {string(f)}}, nil, &entries); err != nil {
+		return nil, err
+	}
+	out := make([]Container, 0, len(entries))
+	for _, e := range entries {
+		c := Container{ID: e.ID, Image: e.Image, CreatedAt: e.Created.Unix(), Status: dockerStatus(e.State)}
+		if len(e.Names) > 0 {
+			c.Name = e.Names[0]
+		}
+		if c.Status == "running" {
+			for _, pm := range e.Ports {
+				c.Ports = append(c.Ports, PortMapping{ContainerPort: int(pm.ContainerPort), HostPort: int(pm.HostPort), HostIP: pm.HostIP, Protocol: pm.Protocol})
+			}
+			sortPorts(c.Ports)
+		}
+		if filter.Match(c) {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+// Inspect 查詢單一容器的目前狀態。
+func (p *PodmanProvider) Inspect(ctx context.Context, id string) (Container, error) {
+	var info struct {
+		ID        string    `json:"Id"`
+		Name      string    `json:"Name"`
+		Created   time.Time `json:"Created"`
+		ImageName string    `json:"ImageName"`
+		State     struct {
+			Status string `json:"Status"`
+		} `json:"State"`
+		NetworkSettings struct {
+			Ports map[string][]struct {
+				HostIP   string `json:"HostIp"`
+				HostPort string `json:"HostPort"`
+			} `json:"Ports"`
+		} `json:"NetworkSettings"`
+	}
+	if err := p.call(ctx, http.MethodGet, "/containers/"+url.PathEscape(id)+"/json", nil, nil, &info); err != nil {
+		return Container{}, err
+	}
+	c := Container{
+		ID:        info.ID,
+		Name:      info.Name,
+		Image:     info.ImageName,
+		CreatedAt: info.Created.Unix(),
+		Status:    dockerStatus(info.State.Status),
+	}
+	for port, bindings := range info.NetworkSettings.Ports {
+		num, proto, _ := strings.Cut(port, "/")
+		cp, err := strconv.Atoi(num)
+		if err != nil {
+			continue
+		}
+		for _, b := range bindings {
+			if hp, err := strconv.Atoi(b.HostPort); err == nil {
+				c.Ports = append(c.Ports, PortMapping{ContainerPort: cp, HostPort: hp, HostIP: b.HostIP, Protocol: proto})
+			}
+		}
+	}
+	sortPorts(c.Ports)
+	return c, nil
+}
+
+// Exec 建立 exec session 並讀取多工的 stdout/stderr，結束後查詢 exit code。
+func (p *PodmanProvider) Exec(ctx context.Context, id string, cmd []string) (ExecResult, error) {
+	var created struct {
+		ID string `json:"Id"`
+	}
+	err := p.call(ctx, http.MethodPost, "/containers/"+url.PathEscape(id)+"/exec", nil, map[string]any{
+		"AttachStdout": true,
+		"AttachStderr": true,
+		"Cmd":          cmd,
+	}, &created)
+	var perr *podmanError
+	if errors.As(err, &perr) && perr.Response == http.StatusConflict {
+		return ExecResult{}, ErrNotRunning
+	}
+	if err != nil {
+		return ExecResult{}, err
+	}
+	resp, err := p.do(ctx, http.MethodPost, "/exec/"+created.ID+"/start", nil, map[string]any{"Detach": false, "Tty": false})
+	if err != nil {
+		return ExecResult{}, err
+	}
+	defer resp.Body.Close()
+	stdout := newCappedBuffer(p.execOutputLimit)
+	stderr := newCappedBuffer(p.execOutputLimit)
+	if _, err := stdcopy.StdCopy(stdout, stderr, resp.Body); err != nil {
+		return ExecResult{}, err
+	}
+	res := ExecResult{
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		Truncated: stdout.Truncated() || stderr.Truncated(),
+	}
+	// 串流結束後 exit code 可能尚未更新，輪詢直到程序結束或 ctx 取消
+	for {
+		var st struct {
+			ExitCode int  `json:"ExitCode"`
+			Running  bool `json:"Running"`
+		}
+		if err := p.call(ctx, http.MethodGet, "/exec/"+created.ID+"/json", nil, nil, &st); err != nil {
+			return ExecResult{}, err
+		}
+		if !st.Running {
+			res.ExitCode = st.ExitCode
+			return res, nil
+		}
+		select {
+		case <-ctx.Done():
+			return res, ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// RunJob 建立一次性容器、等待結束並回傳合併後的輸出。
+// 超過 opts.Timeout 時以 GracePeriod 停止容器，回傳 JobTimedOut。
+func (p *PodmanProvider) RunJob(ctx context.Context, opts JobOptions) (JobResult, error) {
+	if err := p.ensureImage(ctx, opts.Image); err != nil {
+		return JobResult{}, err
+	}
+
+	spec := podmanBaseSpec(opts.Image, opts.Resources, opts.Security, map[string]string{opts.HostDir: opts.ContainerDir}, p.isRootless(ctx))
+	spec.Command = opts.Cmd
+	spec.Env = opts.Env
+	spec.WorkDir = opts.ContainerDir
+	spec.Labels = map[string]string{managedLabel: "true"}
+	spec.setNetwork(opts.network(), nil)
+	var created struct {
+		ID string `json:"Id"`
+	}
+	if err := p.call(ctx, http.MethodPost, "/containers/create", nil, spec, &created); err != nil {
+		return JobResult{}, err
+	}
+	id := url.PathEscape(created.ID)
+	defer func() {
+		_ = p.call(context.Background(), http.MethodDelete, "/containers/"+id, url.Values{"force": {"true"}}, nil, nil)
+	}()
+	if err := p.call(ctx, http.MethodPost, "/containers/"+id+"/start", nil, nil, nil); err != nil {
+		return JobResult{}, err
+	}
+
+	// 等待結束
+	waitCtx, cancel := context.WithTimeout(ctx, opts.timeout())
+	defer cancel()
+	var code int64
+	err := p.call(waitCtx, http.MethodPost, "/containers/"+id+"/wait", url.Values{"condition": {"stopped", "exited"}}, nil, &code)
+	if ctx.Err() != nil {
+		return JobResult{}, ctx.Err()
+	}
+	if waitCtx.Err() == context.DeadlineExceeded {
+		grace := strconv.Itoa(int(opts.gracePeriod().Seconds()))
+		_ = p.call(ctx, http.MethodPost, "/containers/"+id+"/stop", url.Values{"timeout": {grace}}, nil, nil)
+		res := JobResult{ExitCode: -1, Logs: p.jobLogs(ctx, id), Status: JobTimedOut}
+		if st, ok := p.jobState(ctx, id); ok {
+			res.ExitCode = st.ExitCode
+		}
+		return res, nil
+	}
+	if err != nil {
+		return JobResult{}, err
+	}
+	res := exitedJob(code, p.jobLogs(ctx, id))
+	if st, ok := p.jobState(ctx, id); ok {
+		res.OOMKilled = st.OOMKilled
+	}
+	return res, nil
+}
+
+type podmanJobState struct {
+	ExitCode  int64 `json:"ExitCode"`
+	OOMKilled bool  `json:"OOMKilled"`
+}
+
+// jobState 讀取已結束容器的 exit code 與是否因記憶體不足被終止。
+func (p *PodmanProvider) jobState(ctx context.Context, id string) (podmanJobState, bool) {
+	var info struct {
+		State podmanJobState `json:"State"`
+	}
+	if err := p.call(ctx, http.MethodGet, "/containers/"+id+"/json", nil, nil, &info); err != nil {
+		return podmanJobState{}, false
+	}
+	return info.State, true
+}
+
+// jobLogs 讀取容器的 stdout 與 stderr；讀取失敗時回傳空字串。
+func (p *PodmanProvider) jobLogs(ctx context.Context, id string) string {
+	resp, err := p.do(ctx, http.MethodGet, "/containers/"+id+"/logs", url.Values{"stdout": {"true"}, "stderr": {"true"}}, nil)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	var logs bytes.Buffer
+	_, _ = stdcopy.StdCopy(&logs, &logs, resp.Body)
+	return logs.String()
+}
+
+var (
+	_ Provider  = (*PodmanProvider)(nil)
+	_ JobRunner = (*PodmanProvider)(nil)
+)
@@ -0,0 +1,406 @@
+package containers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// fakeLibpod 以記憶體模擬 libpod REST API 中本服務用到的端點。
+type fakeLibpod struct {
+	mu         sync.Mutex
+	containers map[string]*fakePodmanContainer
+	execs      map[string][]string
+	nextID     int
+	infoDown   bool // /info 回應 500，模擬服務暫時無法使用
+	infoCalls  int
+}
+
+type fakePodmanContainer struct {
+	spec    podmanSpec
+	state   string
+	created time.Time
+}
+
+func (f *fakeLibpod) handler() http.Handler {
+	const prefix = "/" + podmanAPIVersion + "/libpod"
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	fail := func(w http.ResponseWriter, status int, msg string) {
+		writeJSON(w, status, podmanError{Cause: msg, Message: msg, Response: status})
+	}
+	lookup := func(w http.ResponseWriter, r *http.Request) *fakePodmanContainer {
+		c, ok := f.containers[r.PathValue("id")]
+		if !ok {
+			fail(w, http.StatusNotFound, "no such container")
+		}
+		return c
+	}
+
+	mux.HandleFunc("GET "+prefix+"/info", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.infoCalls++
+		if f.infoDown {
+			fail(w, http.StatusInternalServerError, "service unavailable")
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"host": map[string]any{"security": map[string]any{"rootless": true}}})
+	})
+	// nope:1 在 registry 上不存在；offline:1 與 cached:1 連不上 registry，其中 cached:1 已在本機。
+	mux.HandleFunc("POST "+prefix+"/images/pull", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("reference") {
+		case "nope:1":
+			writeJSON(w, http.StatusOK, map[string]any{"error": "initializing source docker.io/library/nope:1: reading manifest 1 in docker.io/library/nope: manifest unknown"})
+		case "offline:1", "cached:1":
+			writeJSON(w, http.StatusOK, map[string]any{"error": "pinging container registry docker.io: dial tcp: lookup docker.io: no such host"})
+		default:
+			writeJSON(w, http.StatusOK, map[string]any{"id": "sha256:abc"})
+		}
+	})
+	mux.HandleFunc("GET "+prefix+"/images/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		if name, ok := strings.CutSuffix(r.PathValue("path"), "/exists"); ok && name == "cached:1" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		fail(w, http.StatusNotFound, "no such image")
+	})
+	mux.HandleFunc("POST "+prefix+"/containers/create", func(w http.ResponseWriter, r *http.Request) {
+		var spec podmanSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil || spec.Image == "" {
+			fail(w, http.StatusBadRequest, "invalid spec")
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.nextID++
+		id := fmt.Sprintf("ctr%04d", f.nextID)
+		f.containers[id] = &fakePodmanContainer{spec: spec, state: "created", created: time.Now()}
+		writeJSON(w, http.StatusCreated, map[string]any{"Id": id, "Warnings": []string{}})
+	})
+	mux.HandleFunc("POST "+prefix+"/containers/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if c := lookup(w, r); c != nil {
+			if c.state == "running" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			c.state = "running"
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	mux.HandleFunc("POST "+prefix+"/containers/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if c := lookup(w, r); c != nil {
+			if c.state != "running" {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			c.state = "exited"
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	mux.HandleFunc("DELETE "+prefix+"/containers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if c := lookup(w, r); c != nil {
+			if c.state == "running" && r.URL.Query().Get("force") != "true" {
+				fail(w, http.StatusConflict, "container is running")
+				return
+			}
+			delete(f.containers, r.PathValue("id"))
+			writeJSON(w, http.StatusOK, []map[string]string{{"Id": r.PathValue("id")}})
+		}
+	})
+	mux.HandleFunc("GET "+prefix+"/containers/json", func(w http.ResponseWriter, r *http.Request) {
+		var filters map[string][]string
+		_ = json.Unmarshal([]byte(r.URL.Query().Get("filters")), &filters)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		out := []map[string]any{}
+		for id, c := range f.containers {
+			if len(filters["label"]) == 1 && c.spec.Labels[managedLabel] != "true" {
+				continue
+			}
+			ports := []map[string]any{}
+			for _, p := range c.spec.PortMappings {
+				ports = append(ports, map[string]any{"host_ip": "", "container_port": p.ContainerPort, "host_port": f.hostPort(p), "protocol": p.Protocol})
+			}
+			out = append(out, map[string]any{"Id": id, "Names": []string{c.spec.Name}, "Image": c.spec.Image, "Created": c.created, "State": c.state, "Ports": ports})
+		}
+		writeJSON(w, http.StatusOK, out)
+	})
+	mux.HandleFunc("GET "+prefix+"/containers/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		c := lookup(w, r)
+		if c == nil {
+			return
+		}
+		ports := map[string]any{}
+		for _, p := range c.spec.PortMappings {
+			key := fmt.Sprintf("%d/%s", p.ContainerPort, p.Protocol)
+			if c.state != "running" {
+				ports[key] = nil
+				continue
+			}
+			ports[key] = []map[string]string{{"HostIp": "0.0.0.0", "HostPort": fmt.Sprint(f.hostPort(p))}}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"Id": r.PathValue("id"), "Name": c.spec.Name, "Created": c.created, "ImageName": c.spec.Image,
+			"State":           map[string]any{"Status": c.state},
+			"NetworkSettings": map[string]any{"Ports": ports},
+		})
+	})
+	mux.HandleFunc("POST "+prefix+"/containers/{id}/exec", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Cmd []string }
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		c := lookup(w, r)
+		if c == nil {
+			return
+		}
+		if c.state != "running" {
+			fail(w, http.StatusConflict, "container state improper")
+			return
+		}
+		id := fmt.Sprintf("exec%d", len(f.execs)+1)
+		f.execs[id] = body.Cmd
+		writeJSON(w, http.StatusCreated, map[string]string{"Id": id})
+	})
+	mux.HandleFunc("POST "+prefix+"/exec/{id}/start", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		cmd := f.execs[r.PathValue("id")]
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		_, _ = stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte(strings.Join(cmd, " ")))
+		_, _ = stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte("warn"))
+	})
+	mux.HandleFunc("GET "+prefix+"/exec/{id}/json", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		cmd := f.execs[r.PathValue("id")]
+		f.mu.Unlock()
+		code := 0
+		if len(cmd) > 0 && cmd[0] == "false" {
+			code = 1
+		}
+		// hang 模擬關閉輸出後仍在執行的程序
+		writeJSON(w, http.StatusOK, map[string]any{"ExitCode": code, "Running": len(cmd) > 0 && cmd[0] == "hang"})
+	})
+	mux.HandleFunc("POST "+prefix+"/containers/{id}/wait", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if c := lookup(w, r); c != nil {
+			c.state = "exited"
+			writeJSON(w, http.StatusOK, 3)
+		}
+	})
+	mux.HandleFunc("GET "+prefix+"/containers/{id}/logs", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte("building\n"))
+		_, _ = stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte("failed\n"))
+	})
+	return mux
+}
+
+// hostPort 模擬自動分配：未指定 host port 時以 container port + 30000 代替。
+func (f *fakeLibpod) hostPort(p podmanPort) uint16 {
+	if p.HostPort != 0 {
+		return p.HostPort
+	}
+	return p.ContainerPort + 30000
+}
+
+// newFakePodman 在 unix socket 上啟動 fakeLibpod，回傳連線到它的 provider。
+func newFakePodman(t *testing.T) (*PodmanProvider, *fakeLibpod) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "podman")
+	if err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	socket := filepath.Join(dir, "podman.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	fake := &fakeLibpod{containers: map[string]*fakePodmanContainer{}, execs: map[string][]string{}}
+	srv := httptest.NewUnstartedServer(fake.handler())
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return NewPodmanProviderWith(socket), fake
+}
+
+func TestPodmanProvider_Contract(t *testing.T) {
+	ctx := context.Background()
+	p, fake := newFakePodman(t)
+
+	c, err := p.Create(ctx, CreateOptions{
+		Name:      "web",
+		Image:     "nginx:1.27",
+		Mounts:    map[string]string{"/srv/uploads/u1": "/workspace"},
+		Env:       map[string]string{"MODE": "prod"},
+		Labels:    map[string]string{"team": "web"},
+		Ports:     []PortMapping{{ContainerPort: 80}, {ContainerPort: 53, HostPort: 5353, Protocol: "udp"}},
+		Network:   NetworkOptions{Network: "apps", Aliases: []string{"web"}, DNS: []string{"1.1.1.1"}},
+		Resources: ResourceLimits{Memory: 64 << 20, PidsLimit: 50, Ulimits: []Ulimit{{Name: "nofile", Soft: 1024, Hard: 2048}}},
+		Security:  SecurityOptions{CapDrop: []string{"ALL"}, NoNewPrivileges: true},
+	})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if c.Status != "created" || c.Name != "web" {
+		t.Fatalf("unexpected container %+v", c)
+	}
+	spec := fake.containers[c.ID].spec
+	if spec.Command[0] != "tail" || spec.Env["MODE"] != "prod" || spec.Labels[managedLabel] != "true" || spec.Labels["team"] != "web" {
+		t.Fatalf("unexpected spec %+v", spec)
+	}
+	// rootless：bind mount 加上 z 並以 keep-id 對應使用者
+	if m := spec.Mounts[0]; m.Source != "/srv/uploads/u1" || m.Destination != "/workspace" || strings.Join(m.Options, ",") != "rbind,z" {
+		t.Fatalf("unexpected mount %+v", m)
+	}
+	if spec.UserNS == nil || spec.UserNS.NSMode != "keep-id" {
+		t.Fatalf("expected keep-id userns, got %+v", spec.UserNS)
+	}
+	// rootful 同樣重新標記，但不使用 keep-id
+	if rootful := podmanBaseSpec("alpine", ResourceLimits{}, SecurityOptions{}, map[string]string{"/srv/uploads/u1": "/w"}, false); strings.Join(rootful.Mounts[0].Options, ",") != "rbind,z" || rootful.UserNS != nil {
+		t.Fatalf("unexpected rootful spec %+v", rootful)
+	}
+	if spec.Networks["apps"].Aliases[0] != "web" || spec.DNSServer[0] != "1.1.1.1" || len(spec.PortMappings) != 2 {
+		t.Fatalf("unexpected network spec %+v", spec)
+	}
+	if spec.ResourceLimits.Memory.Limit != 64<<20 || spec.ResourceLimits.Pids.Limit != 50 || spec.Rlimits[0].Type != "nofile" || !spec.NoNewPrivs {
+		t.Fatalf("unexpected limits %+v", spec)
+	}
+
+	if _, err := p.Exec(ctx, c.ID, []string{"true"}); err != ErrNotRunning {
+		t.Fatalf("expected ErrNotRunning when exec on created container, got %v", err)
+	}
+	if err := p.Start(ctx, c.ID); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if err := p.Start(ctx, c.ID); !errors.Is(err, ErrInvalidState) {
+		t.Fatalf("second start: expected ErrInvalidState, got %v", err)
+	}
+
+	got, err := p.Inspect(ctx, c.ID)
+	if err != nil || got.Status != "running" || got.Image != "nginx:1.27" || len(got.Ports) != 2 {
+		t.Fatalf("inspect: %+v %v", got, err)
+	}
+	if got.Ports[0].ContainerPort != 53 || got.Ports[0].HostPort != 5353 || got.Ports[1].HostPort != 30080 {
+		t.Fatalf("unexpected ports %+v", got.Ports)
+	}
+
+	res, err := p.Exec(ctx, c.ID, []string{"echo", "hi"})
+	if err != nil || res.Stdout != "echo hi" || res.Stderr != "warn" || res.ExitCode != 0 {
+		t.Fatalf("exec: %+v %v", res, err)
+	}
+	if res, err := p.Exec(ctx, c.ID, []string{"false"}); err != nil || res.ExitCode != 1 {
+		t.Fatalf("exec false: %+v %v", res, err)
+	}
+	// 輸出結束但程序仍在執行時持續等待，直到 ctx 取消
+	hangCtx, cancel := context.WithTimeout(ctx, 300*time.Millisecond)
+	defer cancel()
+	if _, err := p.Exec(hangCtx, c.ID, []string{"hang"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("exec hang: expected deadline exceeded, got %v", err)
+	}
+
+	list, err := p.List(ctx, ListFilter{Status: "running"})
+	if err != nil || len(list) != 1 || list[0].ID != c.ID || len(list[0].Ports) != 2 {
+		t.Fatalf("list: %+v %v", list, err)
+	}
+	if err := p.Stop(ctx, c.ID); err != nil {
+		t.Fatalf("stop: %v", err)
+	}
+	if got, _ := p.Inspect(ctx, c.ID); got.Status != "stopped" || len(got.Ports) != 0 {
+		t.Fatalf("expected stopped without ports, got %+v", got)
+	}
+	if err := p.Delete(ctx, c.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	for name, err := range map[string]error{
+		"start":  p.Start(ctx, c.ID),
+		"stop":   p.Stop(ctx, c.ID),
+		"delete": p.Delete(ctx, c.ID),
+	} {
+		if err != ErrNotFound {
+			t.Fatalf("%s unknown id: expected ErrNotFound, got %v", name, err)
+		}
+	}
+	if _, err := p.Inspect(ctx, c.ID); err != ErrNotFound {
+		t.Fatalf("inspect unknown id: %v", err)
+	}
+	var perr *podmanError
+	if _, err := p.Create(ctx, CreateOptions{}); !errors.As(err, &perr) || perr.Response != http.StatusBadRequest {
+		t.Fatalf("expected podman error for invalid spec, got %v", err)
+	}
+}
+
+func TestPodmanProvider_PullErrors(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newFakePodman(t)
+	if _, err := p.Create(ctx, CreateOptions{Image: "nope:1"}); !errors.Is(err, ErrImageNotFound) {
+		t.Fatalf("missing image: expected ErrImageNotFound, got %v", err)
+	}
+	if _, err := p.RunJob(ctx, JobOptions{Image: "offline:1", HostDir: "/srv/uploads/u1/batch", ContainerDir: "/workspace"}); !errors.Is(err, ErrImagePull) {
+		t.Fatalf("offline: expected ErrImagePull, got %v", err)
+	}
+	if _, err := p.Create(ctx, CreateOptions{Image: "cached:1"}); err != nil {
+		t.Fatalf("cached image should be used when pull fails: %v", err)
+	}
+}
+
+func TestPodmanProvider_RootlessRetry(t *testing.T) {
+	ctx := context.Background()
+	p, fake := newFakePodman(t)
+	fake.infoDown = true
+	if p.isRootless(ctx) {
+		t.Fatal("expected false while /info fails")
+	}
+	fake.mu.Lock()
+	fake.infoDown = false
+	fake.mu.Unlock()
+	if !p.isRootless(ctx) || !p.isRootless(ctx) {
+		t.Fatal("expected rootless after /info recovers")
+	}
+	if fake.infoCalls != 2 {
+		t.Fatalf("expected failed lookup not to be cached and success to be cached, got %d calls", fake.infoCalls)
+	}
+}
+
+func TestPodmanProvider_RunJob(t *testing.T) {
+	ctx := context.Background()
+	p, fake := newFakePodman(t)
+	res, err := p.RunJob(ctx, JobOptions{Image: "python:3.12", HostDir: "/srv/uploads/u1/batch", ContainerDir: "/workspace", Cmd: []string{"python", "main.py"}})
+	if err != nil {
+		t.Fatalf("run job: %v", err)
+	}
+	if res.ExitCode != 3 || res.Logs != "building\nfailed\n" || res.Status != JobFailed {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(fake.containers) != 0 {
+		t.Fatalf("job container should be removed, got %d", len(fake.containers))
+	}
+}
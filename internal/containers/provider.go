@@ -1,19 +1,21 @@
 package containers
 
-import "errors"
-
-var (
-	ErrNotFound = errors.New("container not found")
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
 )
 
 // Container 描述容器基本資訊
 // 在不同 Provider（Docker/K8s/Mock）之間以此為交換模型。
 type Container struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Image     string `json:"image"`
-	CreatedAt int64  `json:"createdAt"`
-	Status    string `json:"status"` // created|running|stopped
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Image     string        `json:"image"`
+	CreatedAt int64         `json:"createdAt"`
+	Status    string        `json:"status"`          // created|running|stopped（歷史紀錄另有 deleted）
+	Ports     []PortMapping `json:"ports,omitempty"` // 執行中時實際分配的主機連接埠
 }
 
 // CreateOptions 建立容器所需參數。
@@ -22,26 +24,93 @@ type CreateOptions struct {
 	Image        string            `json:"image"`        // 必填
 	Mounts       map[string]string `json:"mounts"`       // 可選：hostDir -> containerDir 的映射
 	ContainerDir string            `json:"containerDir"` // 可選：預設掛載目錄（如果只有一個掛載點）
+	Resources    ResourceLimits    `json:"resources"`    // 可選：未指定時套用伺服器上限
+	Security     SecurityOptions   `json:"security"`     // 可選
+	Env          map[string]string `json:"env"`          // 可選：環境變數
+	Entrypoint   []string          `json:"entrypoint"`   // 可選：覆寫映像的 ENTRYPOINT
+	Cmd          []string          `json:"cmd"`          // 可選：覆寫映像的 CMD；與 Entrypoint 皆未指定時維持容器常駐
+	WorkingDir   string            `json:"workingDir"`   // 可選
+	Labels       map[string]string `json:"labels"`       // 可選
+	Hostname     string            `json:"hostname"`     // 可選
+	Ports        []PortMapping     `json:"ports"`        // 可選：發佈到主機的連接埠
+	Network      NetworkOptions    `json:"network"`      // 可選：網路與 DNS 設定
+	Build        *BuildOptions     `json:"build"`        // 可選：先建置映像，Image 為建置的 tag
 }
 
-// Provider 提供容器操作抽象。
-type Provider interface {
-	Create(opts CreateOptions) (Container, error)
-	Start(id string) error
-	Stop(id string) error
-	Delete(id string) error
-    Exec(id string, cmd []string) (exitCode int, logs string, err error)
+// Validate 檢查建立參數的格式。
+func (o CreateOptions) Validate() error {
+	if err := validateEnv(o.Env); err != nil {
+		return err
+	}
+	for k := range o.Labels {
+		if k == "" {
+			return fmt.Errorf("%w: label key must not be empty", ErrInvalidOptions)
+		}
+	}
+	if err := o.Network.Validate(o.Ports); err != nil {
+		return err
+	}
+	if o.Build != nil {
+		if err := o.Build.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := o.Resources.Validate(); err != nil {
+		return err
+	}
+	return o.Security.Validate()
 }
 
-// JobOptions 定義一次性作業的參數：將主機資料夾掛載進容器並執行命令。
-type JobOptions struct {
-    Image        string   `json:"image"`
-    HostDir      string   `json:"hostDir"`
-    ContainerDir string   `json:"containerDir"`
-    Cmd          []string `json:"cmd"`
+func validateEnv(env map[string]string) error {
+	for k := range env {
+		if k == "" || strings.ContainsAny(k, "= ") {
+			return fmt.Errorf("%w: invalid env name %q", ErrInvalidOptions, k)
+		}
+	}
+	return nil
 }
 
-// JobRunner 可選介面：支援一次性作業。
-type JobRunner interface {
-    RunJob(opts JobOptions) (exitCode int64, logs string, err error)
+// envList 將環境變數轉成依名稱排序的 KEY=VALUE 列表。
+func envList(env map[string]string) []string {
+	if len(env) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(env))
+	for k, v := range env {
+		out = append(out, k+"="+v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ListFilter 列出容器時的篩選條件，空字串代表不篩選。
+type ListFilter struct {
+	Status string `json:"status"` // created|running|stopped
+	Image  string `json:"image"`
+	Name   string `json:"name"` // 名稱包含此字串即符合
+}
+
+// Match 判斷容器是否符合篩選條件。
+func (f ListFilter) Match(c Container) bool {
+	if f.Status != "" && c.Status != f.Status {
+		return false
+	}
+	if f.Image != "" && c.Image != f.Image {
+		return false
+	}
+	if f.Name != "" && !strings.Contains(c.Name, f.Name) {
+		return false
+	}
+	return true
+}
+
+// Provider 提供容器操作抽象。ctx 取消時（例如用戶端斷線）進行中的呼叫應盡快返回。
+type Provider interface {
+	Create(ctx context.Context, opts CreateOptions) (Container, error)
+	Start(ctx context.Context, id string) error
+	Stop(ctx context.Context, id string) error
+	Delete(ctx context.Context, id string) error
+	Exec(ctx context.Context, id string, cmd []string) (ExecResult, error)
+	List(ctx context.Context, filter ListFilter) ([]Container, error)
+	Inspect(ctx context.Context, id string) (Container, error)
 }
@@ -0,0 +1,17 @@
+//go:build docker
+
+package providertest_test
+
+import (
+	"testing"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/containers/providertest"
+)
+
+// 需要可用的 Docker daemon：go test -tags docker ./internal/containers/providertest/
+func TestDockerProvider(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) containers.Provider {
+		return containers.NewDockerProvider()
+	})
+}
@@ -0,0 +1,14 @@
+package providertest_test
+
+import (
+	"testing"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/containers/providertest"
+)
+
+func TestMockProvider(t *testing.T) {
+	providertest.Run(t, func(t *testing.T) containers.Provider {
+		return containers.NewMockProvider()
+	})
+}
@@ -0,0 +1,377 @@
+// Package providertest 提供 containers.Provider 的共用一致性測試，
+// 讓 Mock、Docker 與其他實作在相同情境下表現一致。
+package providertest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"container-manager/internal/containers"
+)
+
+// Image 測試容器使用的映像，需包含 sh、echo、true 與 false。
+var Image = "alpine:3.20"
+
+// Factory 為每個子測試建立一個新的 provider。
+type Factory func(t *testing.T) containers.Provider
+
+// ExecScripter 由無法真正執行命令的 provider（例如 MockProvider）實作；
+// 測試會先設定預期的回應，再執行命令。
+type ExecScripter interface {
+	SetExecResponse(command string, r containers.MockExecResponse)
+}
+
+var seq atomic.Int64
+
+// name 產生在同一台主機上不重複的容器名稱。
+func name(prefix string) string {
+	return fmt.Sprintf("providertest-%s-%d-%d", prefix, time.Now().UnixNano(), seq.Add(1))
+}
+
+// Run 以 factory 建立的 provider 執行所有一致性測試；
+// provider 若實作 containers.JobRunner、containers.ImageBuilder 或 containers.ImageManager，一併測試之。
+func Run(t *testing.T, factory Factory) {
+	t.Run("Lifecycle", func(t *testing.T) { testLifecycle(t, factory(t)) })
+	t.Run("UnknownID", func(t *testing.T) { testUnknownID(t, factory(t)) })
+	t.Run("ListAndInspect", func(t *testing.T) { testList(t, factory(t)) })
+	t.Run("DeleteRunning", func(t *testing.T) { testDeleteRunning(t, factory(t)) })
+	t.Run("RunJob", func(t *testing.T) {
+		jr, ok := factory(t).(containers.JobRunner)
+		if !ok {
+			t.Skip("provider does not implement JobRunner")
+		}
+		testRunJob(t, jr)
+	})
+	t.Run("RunJobTimeout", func(t *testing.T) {
+		jr, ok := factory(t).(containers.JobRunner)
+		if !ok {
+			t.Skip("provider does not implement JobRunner")
+		}
+		testRunJobTimeout(t, jr)
+	})
+	t.Run("BuildImage", func(t *testing.T) {
+		b, ok := factory(t).(containers.ImageBuilder)
+		if !ok {
+			t.Skip("provider does not implement ImageBuilder")
+		}
+		testBuildImage(t, b)
+	})
+	t.Run("ImageManager", func(t *testing.T) {
+		m, ok := factory(t).(containers.ImageManager)
+		if !ok {
+			t.Skip("provider does not implement ImageManager")
+		}
+		testImageManager(t, m)
+	})
+}
+
+// create 建立容器並在測試結束時刪除。
+func create(t *testing.T, p containers.Provider, prefix string) containers.Container {
+	t.Helper()
+	c, err := p.Create(t.Context(), containers.CreateOptions{Name: name(prefix), Image: Image})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Delete(context.Background(), c.ID) })
+	return c
+}
+
+func expectStatus(t *testing.T, p containers.Provider, id, want string) {
+	t.Helper()
+	c, err := p.Inspect(t.Context(), id)
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if c.Status != want {
+		t.Fatalf("status = %q, want %q", c.Status, want)
+	}
+}
+
+func testLifecycle(t *testing.T, p containers.Provider) {
+	ctx := t.Context()
+	c := create(t, p, "lifecycle")
+	if c.ID == "" || c.Status != "created" || c.Image != Image {
+		t.Fatalf("Create returned %+v", c)
+	}
+	expectStatus(t, p, c.ID, "created")
+
+	if _, err := p.Exec(ctx, c.ID, []string{"true"}); !errors.Is(err, containers.ErrNotRunning) {
+		t.Fatalf("Exec on created container: got %v, want ErrNotRunning", err)
+	}
+
+	if err := p.Start(ctx, c.ID); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	expectStatus(t, p, c.ID, "running")
+	if err := p.Start(ctx, c.ID); !errors.Is(err, containers.ErrInvalidState) {
+		t.Fatalf("Start on running container: got %v, want ErrInvalidState", err)
+	}
+	expectStatus(t, p, c.ID, "running")
+
+	res, err := p.Exec(ctx, c.ID, []string{"echo", "conformance"})
+	if err != nil {
+		t.Fatalf("Exec echo: %v", err)
+	}
+	if res.ExitCode != 0 || !strings.Contains(res.Stdout, "conformance") {
+		t.Fatalf("Exec echo returned %+v", res)
+	}
+	res, err = p.Exec(ctx, c.ID, []string{"false"})
+	if err != nil {
+		t.Fatalf("Exec false should report the exit code, not an error: %v", err)
+	}
+	if res.ExitCode == 0 {
+		t.Fatalf("Exec false returned exit code 0")
+	}
+
+	if err := p.Stop(ctx, c.ID); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	expectStatus(t, p, c.ID, "stopped")
+	if err := p.Stop(ctx, c.ID); !errors.Is(err, containers.ErrInvalidState) {
+		t.Fatalf("Stop on stopped container: got %v, want ErrInvalidState", err)
+	}
+	if _, err := p.Exec(ctx, c.ID, []string{"true"}); !errors.Is(err, containers.ErrNotRunning) {
+		t.Fatalf("Exec on stopped container: got %v, want ErrNotRunning", err)
+	}
+
+	// 停止後可以再次啟動
+	if err := p.Start(ctx, c.ID); err != nil {
+		t.Fatalf("restart: %v", err)
+	}
+	expectStatus(t, p, c.ID, "running")
+
+	if err := p.Delete(ctx, c.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := p.Inspect(ctx, c.ID); !errors.Is(err, containers.ErrNotFound) {
+		t.Fatalf("Inspect after Delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func testUnknownID(t *testing.T, p containers.Provider) {
+	ctx := t.Context()
+	const id = "providertest-does-not-exist"
+	checks := map[string]func() error{
+		"Start":   func() error { return p.Start(ctx, id) },
+		"Stop":    func() error { return p.Stop(ctx, id) },
+		"Delete":  func() error { return p.Delete(ctx, id) },
+		"Exec":    func() error { _, err := p.Exec(ctx, id, []string{"true"}); return err },
+		"Inspect": func() error { _, err := p.Inspect(ctx, id); return err },
+	}
+	for op, fn := range checks {
+		if err := fn(); !errors.Is(err, containers.ErrNotFound) {
+			t.Errorf("%s unknown id: got %v, want ErrNotFound", op, err)
+		}
+	}
+}
+
+func testList(t *testing.T, p containers.Provider) {
+	ctx := t.Context()
+	prefix := name("list")
+	a, err := p.Create(ctx, containers.CreateOptions{Name: prefix + "-a", Image: Image})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Delete(context.Background(), a.ID) })
+	b, err := p.Create(ctx, containers.CreateOptions{Name: prefix + "-b", Image: Image})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	t.Cleanup(func() { _ = p.Delete(context.Background(), b.ID) })
+	if err := p.Start(ctx, b.ID); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	ids := func(f containers.ListFilter) map[string]containers.Container {
+		t.Helper()
+		list, err := p.List(ctx, f)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", f, err)
+		}
+		out := map[string]containers.Container{}
+		for _, c := range list {
+			out[c.ID] = c
+		}
+		return out
+	}
+	all := ids(containers.ListFilter{Name: prefix})
+	if len(all) != 2 || all[a.ID].Name != prefix+"-a" || all[b.ID].Image != Image {
+		t.Fatalf("List by name returned %+v", all)
+	}
+	running := ids(containers.ListFilter{Name: prefix, Status: "running"})
+	if len(running) != 1 || running[b.ID].ID == "" {
+		t.Fatalf("List running returned %+v", running)
+	}
+	created := ids(containers.ListFilter{Name: prefix, Status: "created"})
+	if len(created) != 1 || created[a.ID].ID == "" {
+		t.Fatalf("List created returned %+v", created)
+	}
+
+	got, err := p.Inspect(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if got.ID != a.ID || got.Name != prefix+"-a" || got.Image != Image || got.CreatedAt == 0 {
+		t.Fatalf("Inspect returned %+v", got)
+	}
+
+	if err := p.Delete(ctx, a.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if rest := ids(containers.ListFilter{Name: prefix}); len(rest) != 1 {
+		t.Fatalf("List after Delete returned %+v", rest)
+	}
+}
+
+func testDeleteRunning(t *testing.T, p containers.Provider) {
+	ctx := t.Context()
+	c := create(t, p, "delete")
+	if err := p.Start(ctx, c.ID); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := p.Delete(ctx, c.ID); err != nil {
+		t.Fatalf("Delete running container: %v", err)
+	}
+	if _, err := p.Inspect(ctx, c.ID); !errors.Is(err, containers.ErrNotFound) {
+		t.Fatalf("Inspect after Delete: got %v, want ErrNotFound", err)
+	}
+	if err := p.Delete(ctx, c.ID); !errors.Is(err, containers.ErrNotFound) {
+		t.Fatalf("second Delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func testRunJob(t *testing.T, jr containers.JobRunner) {
+	cmd := []string{"sh", "-c", "echo job-output; exit 3"}
+	if s, ok := jr.(ExecScripter); ok {
+		s.SetExecResponse(strings.Join(cmd, " "), containers.MockExecResponse{ExitCode: 3, Stdout: "job-output\n"})
+	}
+	res, err := jr.RunJob(t.Context(), containers.JobOptions{
+		Image:        Image,
+		HostDir:      t.TempDir(),
+		ContainerDir: "/workspace",
+		Cmd:          cmd,
+		Env:          map[string]string{"GREETING": "hi"},
+	})
+	if err != nil {
+		t.Fatalf("RunJob: %v", err)
+	}
+	if res.ExitCode != 3 || res.Status != containers.JobFailed {
+		t.Fatalf("RunJob = exit %d status %s, want exit 3 status failed", res.ExitCode, res.Status)
+	}
+	if !strings.Contains(res.Logs, "job-output") {
+		t.Fatalf("RunJob logs = %q, want job-output", res.Logs)
+	}
+}
+
+// testRunJobTimeout 驗證超過逾時的作業會被終止並回傳 timed_out，而非錯誤。
+func testRunJobTimeout(t *testing.T, jr containers.JobRunner) {
+	cmd := []string{"sh", "-c", "echo started; sleep 60"}
+	if s, ok := jr.(ExecScripter); ok {
+		s.SetExecResponse(strings.Join(cmd, " "), containers.MockExecResponse{Stdout: "started\n", Delay: time.Minute})
+	}
+	start := time.Now()
+	res, err := jr.RunJob(t.Context(), containers.JobOptions{
+		Image:        Image,
+		HostDir:      t.TempDir(),
+		ContainerDir: "/workspace",
+		Cmd:          cmd,
+		Timeout:      time.Second,
+		GracePeriod:  time.Second,
+	})
+	if err != nil {
+		t.Fatalf("RunJob: %v", err)
+	}
+	if res.Status != containers.JobTimedOut {
+		t.Fatalf("RunJob status = %s, want timed_out", res.Status)
+	}
+	if !strings.Contains(res.Logs, "started") {
+		t.Fatalf("RunJob logs = %q, want output produced before the timeout", res.Logs)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Second {
+		t.Fatalf("RunJob took %s, timeout not enforced", elapsed)
+	}
+}
+
+// testBuildImage 驗證建置輸出逐行回報、build arg 與 target 生效，且建置的映像可用於作業。
+func testBuildImage(t *testing.T, b containers.ImageBuilder) {
+	dir := t.TempDir()
+	dockerfile := "FROM " + Image + " AS base\nARG GREETING=none\nRUN echo \"$GREETING\" > /greeting\n\nFROM base AS final\nRUN false\n"
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(dockerfile), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	opts := containers.BuildOptions{
+		ContextDir: dir,
+		Tag:        containers.BuildRepository + "/providertest:" + name("build"),
+		BuildArgs:  map[string]string{"GREETING": "hello"},
+		Target:     "base",
+	}
+	var lines []string
+	res, err := b.BuildImage(t.Context(), opts, func(line string) { lines = append(lines, line) })
+	if err != nil {
+		t.Fatalf("BuildImage: %v (output %q)", err, lines)
+	}
+	if res.Image != opts.Tag || res.ID == "" {
+		t.Fatalf("BuildImage = %+v, want image %s with an ID", res, opts.Tag)
+	}
+	if len(lines) == 0 {
+		t.Fatal("BuildImage reported no output")
+	}
+	if m, ok := b.(containers.ImageManager); ok {
+		t.Cleanup(func() { _, _ = m.RemoveImage(context.Background(), res.Image, true) })
+	}
+
+	jr, ok := b.(containers.JobRunner)
+	if !ok {
+		return
+	}
+	cmd := []string{"cat", "/greeting"}
+	if s, ok := jr.(ExecScripter); ok {
+		s.SetExecResponse(strings.Join(cmd, " "), containers.MockExecResponse{Stdout: "hello\n"})
+	}
+	job, err := jr.RunJob(t.Context(), containers.JobOptions{Image: res.Image, HostDir: t.TempDir(), ContainerDir: "/workspace", Cmd: cmd})
+	if err != nil {
+		t.Fatalf("RunJob with built image: %v", err)
+	}
+	if job.Status != containers.JobSucceeded || strings.TrimSpace(job.Logs) != "hello" {
+		t.Fatalf("RunJob with built image = %+v, want the build arg written by the base stage", job)
+	}
+}
+
+// testImageManager 驗證拉取回報進度，且拉取的映像可查詢與列出；不存在的映像回傳 ErrNotFound。
+func testImageManager(t *testing.T, m containers.ImageManager) {
+	ctx := t.Context()
+	var progress []containers.PullProgress
+	img, err := m.PullImage(ctx, Image, func(p containers.PullProgress) { progress = append(progress, p) })
+	if err != nil {
+		t.Fatalf("PullImage: %v", err)
+	}
+	if img.ID == "" || !slices.Contains(img.Tags, Image) || len(progress) == 0 {
+		t.Fatalf("PullImage = %+v (progress %v), want an image tagged %s", img, progress, Image)
+	}
+	got, err := m.InspectImage(ctx, Image)
+	if err != nil || got.ID != img.ID || got.OS == "" {
+		t.Fatalf("InspectImage = %+v, %v; want %s with os", got, err, img.ID)
+	}
+	if got, err := m.InspectImage(ctx, img.ID); err != nil || got.ID != img.ID {
+		t.Fatalf("InspectImage by ID = %+v, %v", got, err)
+	}
+	list, err := m.ListImages(ctx, containers.ImageFilter{Reference: Image})
+	if err != nil || !slices.ContainsFunc(list, func(i containers.Image) bool { return i.ID == img.ID }) {
+		t.Fatalf("ListImages(%s) = %+v, %v", Image, list, err)
+	}
+	missing := "container-manager/providertest:" + name("missing")
+	if _, err := m.InspectImage(ctx, missing); !errors.Is(err, containers.ErrNotFound) {
+		t.Fatalf("InspectImage missing: expected ErrNotFound, got %v", err)
+	}
+	if _, err := m.RemoveImage(ctx, missing, false); !errors.Is(err, containers.ErrNotFound) {
+		t.Fatalf("RemoveImage missing: expected ErrNotFound, got %v", err)
+	}
+}
@@ -1,15 +1,22 @@
 package containers
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"os"
+	"strings"
 
     "container-manager/internal/storage"
 )
 
 // Service 封裝 Provider 與資料持久化。
+// provider 操作成功後的紀錄寫入不受 ctx 取消影響，避免資料庫與實際狀態不一致。
 type Service struct {
-	provider Provider
-	repo     *storage.ContainerRepository
+	provider  Provider
+	repo      *storage.ContainerRepository
+	limits    Limits
+	jobLimits JobLimits
 }
 
 func NewService() *Service {
@@ -18,6 +25,14 @@ func NewService() *Service {
 	switch p {
 	case "docker":
 		prov = NewDockerProvider()
+	case "podman":
+		prov = NewPodmanProvider()
+	case "kubernetes":
+		kp, err := NewKubernetesProvider()
+		if err != nil {
+			log.Fatalf("kubernetes provider: %v", err)
+		}
+		prov = kp
 	default:
 		prov = NewMockProvider()
 	}
@@ -26,7 +41,7 @@ func NewService() *Service {
 	_ = storage.Migrate(db)
 	repo := storage.NewContainerRepository(db)
 
-	return &Service{provider: prov, repo: repo}
+	return &Service{provider: prov, repo: repo, limits: LimitsFromEnv(), jobLimits: JobLimitsFromEnv()}
 }
 
 // NewServiceWith 允許在測試中注入 provider 與 repository。
@@ -34,42 +49,238 @@ func NewServiceWith(provider Provider, repo *storage.ContainerRepository) *Servi
     return &Service{provider: provider, repo: repo}
 }
 
-func (s *Service) Create(opts CreateOptions) (Container, error) {
-	c, err := s.provider.Create(opts)
+// SetLimits 設定伺服器端資源上限（NewService 會從環境變數讀取）。
+func (s *Service) SetLimits(l Limits) { s.limits = l }
+
+// SetJobLimits 設定作業的預設與最大逾時（NewService 會從環境變數讀取）。
+func (s *Service) SetJobLimits(l JobLimits) { s.jobLimits = l }
+
+// enforceLimits 驗證安全設定並套用資源上限。
+func (s *Service) enforceLimits(r *ResourceLimits, sec SecurityOptions) error {
+	if err := sec.Validate(); err != nil {
+		return err
+	}
+	applied, err := s.limits.Apply(*r)
 	if err != nil {
+		return err
+	}
+	*r = applied
+	return nil
+}
+
+func (s *Service) Create(ctx context.Context, opts CreateOptions) (Container, error) {
+	if err := opts.Validate(); err != nil {
 		return Container{}, err
 	}
-	_ = s.repo.Create(storage.ContainerRecord{ID: c.ID, Name: c.Name, Image: c.Image, Status: c.Status, CreatedAt: c.CreatedAt})
+	if err := s.enforceLimits(&opts.Resources, opts.Security); err != nil {
+		return Container{}, err
+	}
+	if opts.Build != nil {
+		res, err := s.Build(ctx, *opts.Build, func(string) {})
+		if err != nil {
+			return Container{}, err
+		}
+		opts.Image = res.Image
+	}
+	c, err := s.provider.Create(ctx, opts)
+	if err != nil {
+		return Container{}, err
+	}
+	_ = s.repo.Create(context.WithoutCancel(ctx), storage.ContainerRecord{ID: c.ID, Name: c.Name, Image: c.Image, Status: c.Status, CreatedAt: c.CreatedAt})
 	return c, nil
 }
 
-func (s *Service) Start(id string) error {
-	if err := s.provider.Start(id); err != nil { return err }
-	_ = s.repo.UpdateStatus(id, "running")
+func (s *Service) Start(ctx context.Context, id string) error {
+	if err := s.provider.Start(ctx, id); err != nil { return err }
+	_ = s.repo.UpdateStatus(context.WithoutCancel(ctx), id, "running")
 	return nil
 }
 
-func (s *Service) Stop(id string) error {
-	if err := s.provider.Stop(id); err != nil { return err }
-	_ = s.repo.UpdateStatus(id, "stopped")
+func (s *Service) Stop(ctx context.Context, id string) error {
+	if err := s.provider.Stop(ctx, id); err != nil { return err }
+	_ = s.repo.UpdateStatus(context.WithoutCancel(ctx), id, "stopped")
 	return nil
 }
 
-func (s *Service) Delete(id string) error {
-	if err := s.provider.Delete(id); err != nil { return err }
-	_ = s.repo.UpdateStatus(id, "deleted")
+func (s *Service) Delete(ctx context.Context, id string) error {
+	if err := s.provider.Delete(ctx, id); err != nil { return err }
+	_ = s.repo.UpdateStatus(context.WithoutCancel(ctx), id, "deleted")
 	return nil
 }
 
+// List 依條件列出容器，並以 offset/limit 分頁；回傳該頁資料與符合條件的總數。
+// limit <= 0 代表不限制筆數。
+func (s *Service) List(ctx context.Context, filter ListFilter, limit, offset int) ([]Container, int, error) {
+	all, err := s.provider.List(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := len(all)
+	if offset > total {
+		offset = total
+	}
+	end := total
+	if limit > 0 && offset+limit < total {
+		end = offset + limit
+	}
+	return all[offset:end], total, nil
+}
+
+// Inspect 查詢單一容器的目前狀態。
+func (s *Service) Inspect(ctx context.Context, id string) (Container, error) {
+	return s.provider.Inspect(ctx, id)
+}
+
+// History 從資料庫讀取容器紀錄（含已刪除者），不經過 provider。
+// 回傳該頁資料、下一頁游標與符合條件的總數。
+func (s *Service) History(ctx context.Context, filter storage.ContainerFilter) ([]Container, string, int, error) {
+	recs, next, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	out := make([]Container, 0, len(recs))
+	for _, r := range recs {
+		out = append(out, Container{ID: r.ID, Name: r.Name, Image: r.Image, Status: r.Status, CreatedAt: r.CreatedAt})
+	}
+	return out, next, total, nil
+}
+
 // Exec runs command inside an existing container. No DB write by default.
-func (s *Service) Exec(id string, cmd []string) (int, string, error) {
-    return s.provider.Exec(id, cmd)
+func (s *Service) Exec(ctx context.Context, id string, cmd []string) (ExecResult, error) {
+    return s.provider.Exec(ctx, id, cmd)
+}
+
+// Logs 如果底層 provider 支援 LogStreamer，則開啟容器日誌串流。
+func (s *Service) Logs(ctx context.Context, id string, opts LogsOptions) (LogReader, error) {
+	if ls, ok := s.provider.(LogStreamer); ok {
+		return ls.Logs(ctx, id, opts)
+	}
+	return nil, ErrNotSupported
+}
+
+// Attach 如果底層 provider 支援 Attacher，則開啟互動式 TTY exec。
+func (s *Service) Attach(ctx context.Context, id string, opts AttachOptions) (TTYSession, error) {
+	if a, ok := s.provider.(Attacher); ok {
+		return a.Attach(ctx, id, opts)
+	}
+	return nil, ErrNotSupported
+}
+
+// PrepareJob 驗證作業參數並套用伺服器上限與預設逾時；排隊前先呼叫以便立即回報參數錯誤。
+func (s *Service) PrepareJob(opts *JobOptions) error {
+    if err := opts.Validate(); err != nil {
+        return err
+    }
+    if err := s.enforceLimits(&opts.Resources, opts.Security); err != nil {
+        return err
+    }
+    if _, ok := s.provider.(JobRunner); !ok {
+        return ErrNotSupported
+    }
+    if _, ok := s.provider.(ImageBuilder); opts.Build != nil && !ok {
+        return ErrNotSupported
+    }
+    return s.jobLimits.Apply(opts)
 }
 
-// RunJob 如果底層 provider 支援 JobRunner，則執行一次性作業。
-func (s *Service) RunJob(opts JobOptions) (int64, string, error) {
-    if jr, ok := s.provider.(JobRunner); ok {
-        return jr.RunJob(opts)
+// RunJob 如果底層 provider 支援 JobRunner，則執行一次性作業；設定 Build 時先建置映像，建置同樣受逾時限制。
+func (s *Service) RunJob(ctx context.Context, opts JobOptions) (JobResult, error) {
+    if err := s.PrepareJob(&opts); err != nil {
+        return JobResult{}, err
     }
-    return 0, "", ErrNotFound // 使用通用錯誤；也可改為自定義錯誤
+    if opts.Build != nil {
+        buildCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+        res, err := s.Build(buildCtx, *opts.Build, func(string) {})
+        cancel()
+        if err != nil {
+            return JobResult{}, err
+        }
+        opts.Image = res.Image
+    }
+    return s.provider.(JobRunner).RunJob(ctx, opts)
+}
+
+// Build 如果底層 provider 支援 ImageBuilder，則以 opts.ContextDir 為 context 建置映像。
+func (s *Service) Build(ctx context.Context, opts BuildOptions, progress func(line string)) (BuildResult, error) {
+	if err := opts.Validate(); err != nil {
+		return BuildResult{}, err
+	}
+	b, ok := s.provider.(ImageBuilder)
+	if !ok {
+		return BuildResult{}, ErrNotSupported
+	}
+	return b.BuildImage(ctx, opts, progress)
+}
+
+// imageManager 回傳底層 provider 的 ImageManager；不支援時回傳 ErrNotSupported。
+func (s *Service) imageManager() (ImageManager, error) {
+	if m, ok := s.provider.(ImageManager); ok {
+		return m, nil
+	}
+	return nil, ErrNotSupported
+}
+
+// PullImage 拉取映像並回報進度；ref 省略 tag 時拉取 latest。建置的映像只存在本機，不可拉取。
+func (s *Service) PullImage(ctx context.Context, ref string, progress func(PullProgress)) (Image, error) {
+	ref, err := NormalizeImageRef(ref)
+	if err != nil {
+		return Image{}, err
+	}
+	if builtImage(ref) {
+		return Image{}, fmt.Errorf("%w: %s is a built image and cannot be pulled", ErrInvalidOptions, ref)
+	}
+	m, err := s.imageManager()
+	if err != nil {
+		return Image{}, err
+	}
+	return m.PullImage(ctx, ref, progress)
+}
+
+// ListImages 列出本機映像。
+func (s *Service) ListImages(ctx context.Context, filter ImageFilter) ([]Image, error) {
+	m, err := s.imageManager()
+	if err != nil {
+		return nil, err
+	}
+	return m.ListImages(ctx, filter)
+}
+
+// InspectImage 以名稱或 ID 查詢本機映像。
+func (s *Service) InspectImage(ctx context.Context, ref string) (Image, error) {
+	if strings.TrimSpace(ref) == "" {
+		return Image{}, fmt.Errorf("%w: image reference is required", ErrInvalidOptions)
+	}
+	m, err := s.imageManager()
+	if err != nil {
+		return Image{}, err
+	}
+	return m.InspectImage(ctx, ref)
+}
+
+// RemoveImage 以名稱或 ID 刪除本機映像；force 時即使有容器使用也刪除。
+func (s *Service) RemoveImage(ctx context.Context, ref string, force bool) ([]ImageDeleted, error) {
+	if strings.TrimSpace(ref) == "" {
+		return nil, fmt.Errorf("%w: image reference is required", ErrInvalidOptions)
+	}
+	m, err := s.imageManager()
+	if err != nil {
+		return nil, err
+	}
+	return m.RemoveImage(ctx, ref, force)
+}
+
+// PruneImages 清除沒有容器使用的映像。
+func (s *Service) PruneImages(ctx context.Context, opts PruneImagesOptions) (PruneReport, error) {
+	if opts.Until < 0 {
+		return PruneReport{}, fmt.Errorf("%w: until must not be negative", ErrInvalidOptions)
+	}
+	m, err := s.imageManager()
+	if err != nil {
+		return PruneReport{}, err
+	}
+	return m.PruneImages(ctx, opts)
 }
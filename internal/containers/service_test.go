@@ -1,6 +1,7 @@
 package containers
 
 import (
+	"context"
 	"regexp"
 	"testing"
 
@@ -18,12 +19,13 @@ func newRepoWithMock(t *testing.T) (*storage.ContainerRepository, sqlmock.Sqlmoc
 }
 
 func TestService_Create_RecordsToRepo(t *testing.T) {
+	ctx := context.Background()
 	repo, mock := newRepoWithMock(t)
 	s := &Service{provider: NewMockProvider(), repo: repo}
 
 	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO containers(id,name,image,status,created_at) VALUES($1,$2,$3,$4,$5)")).WithArgs(sqlmock.AnyArg(), "demo", "alpine:3.20", "created", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
-	c, err := s.Create(CreateOptions{Name: "demo", Image: "alpine:3.20"})
+	c, err := s.Create(ctx, CreateOptions{Name: "demo", Image: "alpine:3.20"})
 	if err != nil {
 		t.Fatalf("create: %v", err)
 	}
@@ -37,28 +39,29 @@ func TestService_Create_RecordsToRepo(t *testing.T) {
 }
 
 func TestService_Start_Stop_Delete_UpdateRepo(t *testing.T) {
+	ctx := context.Background()
 	repo, mock := newRepoWithMock(t)
 	s := &Service{provider: NewMockProvider(), repo: repo}
 
 	// Prepare one container to operate on
 	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO containers(id,name,image,status,created_at) VALUES($1,$2,$3,$4,$5)")).WithArgs(sqlmock.AnyArg(), "demo", "alpine:3.20", "created", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
-	c, err := s.Create(CreateOptions{Name: "demo", Image: "alpine:3.20"})
+	c, err := s.Create(ctx, CreateOptions{Name: "demo", Image: "alpine:3.20"})
 	if err != nil {
 		t.Fatalf("create: %v", err)
 	}
 
 	mock.ExpectExec(regexp.QuoteMeta("UPDATE containers SET status=$1 WHERE id=$2")).WithArgs("running", c.ID).WillReturnResult(sqlmock.NewResult(1, 1))
-	if err := s.Start(c.ID); err != nil {
+	if err := s.Start(ctx, c.ID); err != nil {
 		t.Fatalf("start: %v", err)
 	}
 
 	mock.ExpectExec(regexp.QuoteMeta("UPDATE containers SET status=$1 WHERE id=$2")).WithArgs("stopped", c.ID).WillReturnResult(sqlmock.NewResult(1, 1))
-	if err := s.Stop(c.ID); err != nil {
+	if err := s.Stop(ctx, c.ID); err != nil {
 		t.Fatalf("stop: %v", err)
 	}
 
 	mock.ExpectExec(regexp.QuoteMeta("UPDATE containers SET status=$1 WHERE id=$2")).WithArgs("deleted", c.ID).WillReturnResult(sqlmock.NewResult(1, 1))
-	if err := s.Delete(c.ID); err != nil {
+	if err := s.Delete(ctx, c.ID); err != nil {
 		t.Fatalf("delete: %v", err)
 	}
 
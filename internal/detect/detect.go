@@ -0,0 +1,231 @@
+// Package detect 依上傳資料夾中的檔案推斷作業的映像與命令。規則包含內建規則與
+// DETECTORS_FILE 指定的設定檔，依優先序由高到低比對，第一個符合的規則決定要執行的內容。
+package detect
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Detector 一條偵測規則。
+type Detector struct {
+	Name string `json:"name"`
+	// Patterns 相對於資料夾的 glob，任一符合即選用；第一個符合的檔案為 ${file}
+	Patterns []string `json:"patterns"`
+	// Requires 另外必須存在的檔案（glob），全部符合才選用
+	Requires []string `json:"requires,omitempty"`
+	// Executable 符合的檔案須具有執行權限
+	Executable bool   `json:"executable,omitempty"`
+	Image      string `json:"image,omitempty"`
+	// Cmd 命令範本：${dir} 替換為容器內資料夾，${file} 替換為符合的檔案在容器內的路徑；
+	// 為空時使用映像預設的命令
+	Cmd      []string `json:"cmd,omitempty"`
+	Priority int      `json:"priority"`
+	// Build 符合的檔案為 Dockerfile，映像須由其建置而非指定
+	Build bool `json:"build,omitempty"`
+	// Disabled 僅用於設定檔：停用同名的內建規則
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// Match 偵測結果；Cmd 已完成替換。
+type Match struct {
+	Detector string   `json:"detector"`
+	Priority int      `json:"priority"`
+	File     string   `json:"file,omitempty"` // 符合的檔案，相對於資料夾
+	Image    string   `json:"image,omitempty"`
+	Cmd      []string `json:"cmd"`
+	Build    bool     `json:"build,omitempty"`
+}
+
+// Fallback 沒有任何規則符合時使用：列出資料夾內容。
+var Fallback = Detector{Name: "fallback", Image: "alpine:3.20", Cmd: []string{"sh", "-c", "ls -la ${dir}"}}
+
+// Builtins 回傳內建規則；Dockerfile 明確描述執行環境，優先於其他依檔案猜測的規則，
+// 其餘保留原本的順序：app 可執行 > run.sh > app.py > app.go。
+func Builtins() []Detector {
+	return []Detector{
+		{Name: "dockerfile", Patterns: []string{"Dockerfile"}, Build: true, Priority: 110},
+		{Name: "binary", Patterns: []string{"app"}, Executable: true, Image: "alpine:3.20", Cmd: []string{"sh", "-c", "chmod +x ${file} && ${file}"}, Priority: 100},
+		{Name: "shell", Patterns: []string{"run.sh"}, Image: "alpine:3.20", Cmd: []string{"sh", "-c", "chmod +x ${file} && ${file}"}, Priority: 90},
+		{Name: "python-requirements", Patterns: []string{"requirements.txt"}, Requires: []string{"app.py"}, Image: "python:3.11-slim",
+			Cmd: []string{"sh", "-c", "cd ${dir} && pip install --no-cache-dir -r requirements.txt && python app.py"}, Priority: 85},
+		{Name: "python-pyproject", Patterns: []string{"pyproject.toml"}, Requires: []string{"app.py"}, Image: "python:3.11-slim",
+			Cmd: []string{"sh", "-c", "cd ${dir} && pip install --no-cache-dir . && python app.py"}, Priority: 84},
+		{Name: "python", Patterns: []string{"app.py"}, Image: "python:3.11-slim", Cmd: []string{"python", "${file}"}, Priority: 80},
+		{Name: "go", Patterns: []string{"app.go"}, Image: "golang:1.21-alpine", Cmd: []string{"sh", "-c", "cd ${dir} && go run app.go"}, Priority: 70},
+		{Name: "node", Patterns: []string{"package.json"}, Image: "node:20-alpine", Cmd: []string{"sh", "-c", "cd ${dir} && npm install && npm start"}, Priority: 60},
+		{Name: "rust", Patterns: []string{"Cargo.toml"}, Image: "rust:1-slim", Cmd: []string{"sh", "-c", "cd ${dir} && cargo run --release"}, Priority: 50},
+		{Name: "maven", Patterns: []string{"pom.xml"}, Image: "maven:3.9-eclipse-temurin-21",
+			Cmd: []string{"sh", "-c", "cd ${dir} && mvn -B -q package -DskipTests && java -jar $(ls target/*.jar | head -n 1)"}, Priority: 45},
+		{Name: "jar", Patterns: []string{"*.jar"}, Image: "eclipse-temurin:21-jre", Cmd: []string{"java", "-jar", "${file}"}, Priority: 40},
+		{Name: "make", Patterns: []string{"Makefile", "makefile", "GNUmakefile"}, Image: "gcc:14", Cmd: []string{"make", "-C", "${dir}"}, Priority: 30},
+	}
+}
+
+// Registry 依優先序排列的規則；建立後唯讀，可同時使用。
+type Registry struct {
+	detectors []Detector
+}
+
+// NewRegistry 驗證規則並依優先序由高到低排列；優先序相同時維持原本的順序。
+func NewRegistry(detectors []Detector) (*Registry, error) {
+	seen := map[string]bool{}
+	out := make([]Detector, 0, len(detectors))
+	for i, d := range detectors {
+		if err := d.validate(); err != nil {
+			return nil, fmt.Errorf("detector %d: %w", i, err)
+		}
+		if seen[d.Name] {
+			return nil, fmt.Errorf("duplicate detector %q", d.Name)
+		}
+		seen[d.Name] = true
+		if !d.Disabled {
+			out = append(out, d)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Priority > out[j].Priority })
+	return &Registry{detectors: out}, nil
+}
+
+// Default 回傳只含內建規則的 Registry。
+func Default() *Registry {
+	r, err := NewRegistry(Builtins())
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// config 設定檔格式。
+type config struct {
+	Detectors []Detector `json:"detectors"`
+}
+
+// Load 讀取 JSON 設定檔並與內建規則合併：與內建規則同名的規則取代之（disabled 時停用之），其餘規則新增。
+func Load(file string) (*Registry, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	detectors := Builtins()
+	index := make(map[string]int, len(detectors))
+	for i, d := range detectors {
+		index[d.Name] = i
+	}
+	seen := map[string]bool{}
+	for _, d := range cfg.Detectors {
+		if seen[d.Name] {
+			return nil, fmt.Errorf("%s: duplicate detector %q", file, d.Name)
+		}
+		seen[d.Name] = true
+		if i, ok := index[d.Name]; ok {
+			detectors[i] = d
+			continue
+		}
+		index[d.Name] = len(detectors)
+		detectors = append(detectors, d)
+	}
+	r, err := NewRegistry(detectors)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return r, nil
+}
+
+func (d Detector) validate() error {
+	if d.Name == "" {
+		return errors.New("name is required")
+	}
+	if d.Disabled {
+		return nil
+	}
+	if len(d.Patterns) == 0 {
+		return fmt.Errorf("%q: at least one pattern is required", d.Name)
+	}
+	for _, p := range append(append([]string{}, d.Patterns...), d.Requires...) {
+		if !fs.ValidPath(p) || p == "." {
+			return fmt.Errorf("%q: pattern %q must be a relative path inside the directory", d.Name, p)
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%q: pattern %q: %w", d.Name, p, err)
+		}
+	}
+	if d.Image == "" && !d.Build {
+		return fmt.Errorf("%q: image is required", d.Name)
+	}
+	return nil
+}
+
+// Detect 回傳 hostDir 中第一個符合的規則；沒有規則符合時回傳 Fallback。
+func (r *Registry) Detect(hostDir, containerDir string) Match {
+	if m := r.match(os.DirFS(hostDir), containerDir, true); len(m) > 0 {
+		return m[0]
+	}
+	return Fallback.expand("", containerDir)
+}
+
+// Candidates 依優先序回傳所有符合的規則，第一個即 Detect 選用的規則。
+func (r *Registry) Candidates(hostDir, containerDir string) []Match {
+	return r.match(os.DirFS(hostDir), containerDir, false)
+}
+
+func (r *Registry) match(fsys fs.FS, containerDir string, first bool) []Match {
+	var out []Match
+	for _, d := range r.detectors {
+		file, ok := d.find(fsys)
+		if !ok {
+			continue
+		}
+		out = append(out, d.expand(file, containerDir))
+		if first {
+			break
+		}
+	}
+	return out
+}
+
+// find 回傳第一個符合 Patterns 的檔案；Requires 未全部符合時不選用。
+func (d Detector) find(fsys fs.FS) (string, bool) {
+	for _, p := range d.Requires {
+		if matches, _ := fs.Glob(fsys, p); len(matches) == 0 {
+			return "", false
+		}
+	}
+	for _, p := range d.Patterns {
+		matches, _ := fs.Glob(fsys, p)
+		for _, name := range matches {
+			fi, err := fs.Stat(fsys, name)
+			if err != nil || fi.IsDir() {
+				continue
+			}
+			if d.Executable && fi.Mode()&0o111 == 0 {
+				continue
+			}
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// expand 替換命令範本中的 ${dir} 與 ${file}；其他 ${...} 保留給 shell。
+func (d Detector) expand(file, containerDir string) Match {
+	r := strings.NewReplacer("${dir}", containerDir, "${file}", path.Join(containerDir, file))
+	cmd := make([]string, len(d.Cmd))
+	for i, arg := range d.Cmd {
+		cmd[i] = r.Replace(arg)
+	}
+	return Match{Detector: d.Name, Priority: d.Priority, File: file, Image: d.Image, Cmd: cmd, Build: d.Build}
+}
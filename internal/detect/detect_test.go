@@ -0,0 +1,111 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]os.FileMode) {
+	t.Helper()
+	for name, mode := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestRegistry_DetectBuiltins(t *testing.T) {
+	cases := []struct {
+		name  string
+		files map[string]os.FileMode
+		want  Match
+	}{
+		{"binary", map[string]os.FileMode{"app": 0o755, "app.py": 0o644},
+			Match{Detector: "binary", Priority: 100, File: "app", Image: "alpine:3.20", Cmd: []string{"sh", "-c", "chmod +x /workspace/app && /workspace/app"}}},
+		{"non-executable app", map[string]os.FileMode{"app": 0o644, "app.py": 0o644},
+			Match{Detector: "python", Priority: 80, File: "app.py", Image: "python:3.11-slim", Cmd: []string{"python", "/workspace/app.py"}}},
+		{"requirements", map[string]os.FileMode{"app.py": 0o644, "requirements.txt": 0o644},
+			Match{Detector: "python-requirements", Priority: 85, File: "requirements.txt", Image: "python:3.11-slim",
+				Cmd: []string{"sh", "-c", "cd /workspace && pip install --no-cache-dir -r requirements.txt && python app.py"}}},
+		{"requirements without app.py", map[string]os.FileMode{"requirements.txt": 0o644, "Makefile": 0o644},
+			Match{Detector: "make", Priority: 30, File: "Makefile", Image: "gcc:14", Cmd: []string{"make", "-C", "/workspace"}}},
+		{"jar", map[string]os.FileMode{"b.jar": 0o644, "a.jar": 0o644},
+			Match{Detector: "jar", Priority: 40, File: "a.jar", Image: "eclipse-temurin:21-jre", Cmd: []string{"java", "-jar", "/workspace/a.jar"}}},
+		{"dockerfile", map[string]os.FileMode{"Dockerfile": 0o644},
+			Match{Detector: "dockerfile", Priority: 110, File: "Dockerfile", Cmd: []string{}, Build: true}},
+		{"dockerfile with app", map[string]os.FileMode{"Dockerfile": 0o644, "app": 0o755, "app.py": 0o644},
+			Match{Detector: "dockerfile", Priority: 110, File: "Dockerfile", Cmd: []string{}, Build: true}},
+		{"fallback", map[string]os.FileMode{"README": 0o644},
+			Match{Detector: "fallback", Image: "alpine:3.20", Cmd: []string{"sh", "-c", "ls -la /workspace"}}},
+	}
+	r := Default()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tc.files)
+			if got := r.Detect(dir, "/workspace"); !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Candidates(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]os.FileMode{"package.json": 0o644, "run.sh": 0o644, "Dockerfile": 0o644})
+	var names []string
+	for _, m := range Default().Candidates(dir, "/w") {
+		names = append(names, m.Detector)
+	}
+	if want := []string{"dockerfile", "shell", "node"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "detectors.json")
+	write := func(s string) {
+		if err := os.WriteFile(file, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"detectors": [
+		{"name": "go", "patterns": ["go.mod"], "image": "golang:1.24", "cmd": ["go", "run", "${dir}"], "priority": 95},
+		{"name": "shell", "disabled": true},
+		{"name": "deno", "patterns": ["*.ts"], "image": "denoland/deno", "cmd": ["deno", "run", "${file}"], "priority": 10}
+	]}`)
+	r, err := Load(file)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	work := t.TempDir()
+	writeFiles(t, work, map[string]os.FileMode{"go.mod": 0o644, "run.sh": 0o644, "main.ts": 0o644})
+	var names []string
+	for _, m := range r.Candidates(work, "/w") {
+		names = append(names, m.Detector)
+	}
+	if want := []string{"go", "deno"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("got %v, want %v", names, want)
+	}
+	if m := r.Detect(work, "/w"); !reflect.DeepEqual(m.Cmd, []string{"go", "run", "/w"}) {
+		t.Fatalf("cmd %v", m.Cmd)
+	}
+
+	for _, bad := range []struct{ body, want string }{
+		{`{"detectors": [{"name": "x", "image": "alpine"}]}`, "pattern is required"},
+		{`{"detectors": [{"name": "x", "patterns": ["../etc/passwd"], "image": "alpine"}]}`, "inside the directory"},
+		{`{"detectors": [{"name": "x", "patterns": ["[a"], "image": "alpine"}]}`, "syntax error"},
+		{`{"detectors": [{"name": "x", "patterns": ["a"]}]}`, "image is required"},
+		{`{"detectors": [{"name": "x", "patterns": ["a"], "image": "alpine"}, {"name": "x", "patterns": ["b"], "image": "alpine"}]}`, "duplicate"},
+		{`{"detectors": [{"name": "x", "pattern": "a"}]}`, "unknown field"},
+	} {
+		write(bad.body)
+		if _, err := Load(file); err == nil || !strings.Contains(err.Error(), bad.want) {
+			t.Fatalf("%s: got %v, want %q", bad.body, err, bad.want)
+		}
+	}
+}
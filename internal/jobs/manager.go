@@ -0,0 +1,350 @@
+// Package jobs 執行一次性作業並記錄於 jobs 表。排隊作業存放在 Postgres，
+// 由各實例的 worker 以 SELECT ... FOR UPDATE SKIP LOCKED 領取，並以心跳偵測中斷的執行。
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/storage"
+)
+
+// RunFunc 實際執行作業的函式，通常為 containers.Service.RunJob。
+type RunFunc func(ctx context.Context, opts containers.JobOptions) (containers.JobResult, error)
+
+// Config worker pool 設定；上限為零代表不限制。
+type Config struct {
+	Workers           int           // 本實例同時執行的作業數
+	MaxRunning        int           // 所有實例合計的執行中作業數
+	MaxPerUser        int           // 每位使用者的執行中作業數
+	PollInterval      time.Duration // 沒有可領取作業時的輪詢間隔
+	HeartbeatInterval time.Duration
+	StaleAfter        time.Duration // 心跳逾此時間的執行中作業視為 owner 已終止
+}
+
+// ConfigFromEnv 讀取 JOB_WORKERS（預設 2）、JOB_MAX_RUNNING 與 JOB_MAX_PER_USER（預設不限制）。
+func ConfigFromEnv() Config {
+	return Config{
+		Workers:    envInt("JOB_WORKERS", 2),
+		MaxRunning: envInt("JOB_MAX_RUNNING", 0),
+		MaxPerUser: envInt("JOB_MAX_PER_USER", 0),
+	}
+}
+
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = 1
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = time.Second
+	}
+	if c.HeartbeatInterval <= 0 {
+		c.HeartbeatInterval = 10 * time.Second
+	}
+	if c.StaleAfter <= 0 {
+		c.StaleAfter = 3 * c.HeartbeatInterval
+	}
+	return c
+}
+
+// Manager 執行同步作業並管理排隊作業的 worker pool。
+type Manager struct {
+	repo  *storage.JobRepository
+	run   RunFunc
+	cfg   Config
+	owner string        // 本實例的識別，寫入執行中作業的 owner 欄位
+	wake  chan struct{} // 有新作業或名額釋出時喚醒 worker
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewManager(repo *storage.JobRepository, run RunFunc, cfg Config) *Manager {
+	cfg = cfg.withDefaults()
+	host, _ := os.Hostname()
+	return &Manager{
+		repo:  repo,
+		run:   run,
+		cfg:   cfg,
+		owner: host + "-" + uuid.NewString()[:8],
+		wake:  make(chan struct{}, cfg.Workers),
+	}
+}
+
+// Start 先處理其他實例遺留的執行中作業，再啟動 worker；ctx 結束時 worker 停止領取新作業。
+func (m *Manager) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		return
+	}
+	ctx, m.cancel = context.WithCancel(ctx)
+	_, _ = m.repo.RecoverStale(ctx, time.Now().Add(-m.cfg.StaleAfter))
+	for i := 0; i < m.cfg.Workers; i++ {
+		m.wg.Add(1)
+		go m.worker(ctx)
+	}
+	m.wg.Add(1)
+	go m.recoverLoop(ctx)
+}
+
+// Close 停止 worker 並等待其結束；被中斷的作業放回 pending，由其他實例或下次啟動時接手。
+func (m *Manager) Close() {
+	m.mu.Lock()
+	cancel := m.cancel
+	m.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	m.wg.Wait()
+}
+
+// Submit 驗證過的作業寫入 pending 並喚醒 worker，回傳作業 ID。
+func (m *Manager) Submit(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, error) {
+	b, err := json.Marshal(opts)
+	if err != nil {
+		return "", err
+	}
+	rec.OptionsJSON, rec.Mode, rec.Status = string(b), storage.JobQueued, storage.JobPending
+	id, err := m.repo.Insert(ctx, rec)
+	if err != nil {
+		return "", err
+	}
+	m.notify()
+	return id, nil
+}
+
+// MatrixJob 矩陣的一個組合與替換後的作業參數。
+type MatrixJob struct {
+	Values  map[string]string
+	Options containers.JobOptions
+}
+
+// SubmitMatrix 將各組合寫入為 parent 之下排隊的子作業並喚醒 worker，回傳父作業與子作業的 ID。
+// 同一矩陣同時執行的子作業不超過 maxParallel；maxParallel 為零代表只受 worker 與全域上限限制。
+func (m *Manager) SubmitMatrix(ctx context.Context, parent storage.JobRecord, maxParallel int, children []MatrixJob) (string, []string, error) {
+	if maxParallel <= 0 || maxParallel > len(children) {
+		maxParallel = len(children)
+	}
+	recs := make([]storage.MatrixChild, 0, len(children))
+	for i, c := range children {
+		b, err := json.Marshal(c.Options)
+		if err != nil {
+			return "", nil, err
+		}
+		recs = append(recs, storage.MatrixChild{Index: i, Values: c.Values, Job: storage.JobRecord{
+			Image:        c.Options.Image,
+			Cmd:          c.Options.Cmd,
+			HostDir:      c.Options.HostDir,
+			ContainerDir: c.Options.ContainerDir,
+			OptionsJSON:  string(b),
+		}})
+	}
+	id, ids, err := m.repo.InsertMatrix(ctx, parent, maxParallel, recs)
+	if err != nil {
+		return "", nil, err
+	}
+	for i := 0; i < maxParallel; i++ {
+		m.notify()
+	}
+	return id, ids, nil
+}
+
+// MatrixChildren 依組合順序列出矩陣作業的子作業。
+func (m *Manager) MatrixChildren(ctx context.Context, id string) ([]storage.MatrixChild, error) {
+	return m.repo.MatrixChildren(ctx, id)
+}
+
+// Run 在呼叫端的 ctx 中同步執行作業並記錄結果。
+// 紀錄寫入失敗不影響作業本身，與同步 exec 的 task 紀錄一致。
+func (m *Manager) Run(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error) {
+	rec.Mode, rec.Status, rec.Owner = storage.JobSync, storage.JobRunning, m.owner
+	id, _ := m.repo.Insert(ctx, rec)
+	stop := m.heartbeat(id)
+	res, err := m.attempts(ctx, id, opts)
+	stop()
+	m.finish(context.WithoutCancel(ctx), id, res, err)
+	return id, res, err
+}
+
+// RunLimited 與 Run 相同在呼叫端的 ctx 中執行，但與排隊作業共用 MaxRunning 與 MaxPerUser：
+// 已達上限時每隔 PollInterval 重試，直到取得名額或 ctx 結束。用於排程觸發等不經 HTTP 請求的執行。
+func (m *Manager) RunLimited(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error) {
+	rec.Mode, rec.Owner = storage.JobSync, m.owner
+	limits := storage.ClaimLimits{MaxRunning: m.cfg.MaxRunning, MaxPerUser: m.cfg.MaxPerUser}
+	for {
+		id, ok, err := m.repo.InsertRunning(ctx, rec, limits)
+		if err != nil {
+			return "", containers.JobResult{}, err
+		}
+		if ok {
+			stop := m.heartbeat(id)
+			res, err := m.attempts(ctx, id, opts)
+			stop()
+			m.finish(context.WithoutCancel(ctx), id, res, err)
+			// 名額釋出，讓 worker 立即重新領取
+			m.notify()
+			return id, res, err
+		}
+		t := time.NewTimer(m.cfg.PollInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return "", containers.JobResult{}, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (m *Manager) Get(ctx context.Context, id string) (storage.JobRecord, error) {
+	return m.repo.Get(ctx, id)
+}
+
+func (m *Manager) List(ctx context.Context, f storage.JobFilter) ([]storage.JobRecord, string, error) {
+	return m.repo.List(ctx, f)
+}
+
+// Attempts 依執行順序列出作業的每次執行。
+func (m *Manager) Attempts(ctx context.Context, id string) ([]storage.JobAttempt, error) {
+	return m.repo.Attempts(ctx, id)
+}
+
+func (m *Manager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) worker(ctx context.Context) {
+	defer m.wg.Done()
+	limits := storage.ClaimLimits{MaxRunning: m.cfg.MaxRunning, MaxPerUser: m.cfg.MaxPerUser}
+	for {
+		job, ok, err := m.repo.Claim(ctx, m.owner, limits)
+		if err == nil && ok {
+			m.execute(ctx, job)
+			// 名額釋出，讓其他 worker 立即重新領取
+			m.notify()
+			continue
+		}
+		t := time.NewTimer(m.cfg.PollInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return
+		case <-m.wake:
+		case <-t.C:
+		}
+		t.Stop()
+	}
+}
+
+func (m *Manager) execute(ctx context.Context, job storage.JobRecord) {
+	var opts containers.JobOptions
+	if err := json.Unmarshal([]byte(job.OptionsJSON), &opts); err != nil {
+		m.finish(context.WithoutCancel(ctx), job.ID, containers.JobResult{}, err)
+		_ = m.repo.FinishParent(context.WithoutCancel(ctx), job.ID)
+		return
+	}
+	stop := m.heartbeat(job.ID)
+	res, err := m.attempts(ctx, job.ID, opts)
+	stop()
+	if ctx.Err() != nil {
+		// 服務關閉而中斷：放回佇列，不記錄為失敗
+		_ = m.repo.Requeue(context.WithoutCancel(ctx), job.ID, m.owner)
+		return
+	}
+	m.finish(ctx, job.ID, res, err)
+	// 矩陣的最後一個子作業結束時彙整父作業的結果
+	_ = m.repo.FinishParent(ctx, job.ID)
+}
+
+// attempts 依 opts.Retry 執行作業並將每次執行寫入 job_attempts，回傳最後一次的結果。
+// 等待重試期間作業維持 running 並持續心跳。
+func (m *Manager) attempts(ctx context.Context, id string, opts containers.JobOptions) (containers.JobResult, error) {
+	for n := 1; ; n++ {
+		attemptID, _ := m.repo.StartAttempt(ctx, id)
+		res, err := m.run(ctx, opts)
+		status, code, errMsg := storage.JobStatus(res.Status), res.ExitCode, ""
+		if err != nil {
+			status, code, errMsg = storage.JobFailed, -1, err.Error()
+		}
+		_ = m.repo.FinishAttempt(context.WithoutCancel(ctx), attemptID, status, code, res.OOMKilled, res.Logs, errMsg)
+		if n >= opts.Retry.Attempts() || ctx.Err() != nil || !opts.Retry.Retryable(res, err) {
+			return res, err
+		}
+		t := time.NewTimer(opts.Retry.Backoff(n))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return res, err
+		case <-t.C:
+		}
+	}
+}
+
+func (m *Manager) finish(ctx context.Context, id string, res containers.JobResult, err error) {
+	if err != nil {
+		_ = m.repo.Finish(ctx, id, storage.JobFailed, -1, res.Logs, err.Error())
+		return
+	}
+	_ = m.repo.Finish(ctx, id, storage.JobStatus(res.Status), res.ExitCode, res.Logs, "")
+}
+
+// heartbeat 定期更新作業心跳直到回傳的 stop 被呼叫。
+func (m *Manager) heartbeat(id string) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(m.cfg.HeartbeatInterval)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				_ = m.repo.Heartbeat(context.Background(), id)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// recoverLoop 定期接手心跳逾時的作業，處理其他實例異常終止的情況。
+func (m *Manager) recoverLoop(ctx context.Context) {
+	defer m.wg.Done()
+	t := time.NewTicker(m.cfg.StaleAfter)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if n, err := m.repo.RecoverStale(ctx, time.Now().Add(-m.cfg.StaleAfter)); err == nil && n > 0 {
+				m.notify()
+			}
+		}
+	}
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			return n
+		}
+	}
+	return def
+}
@@ -0,0 +1,266 @@
+package jobs
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/storage"
+)
+
+var (
+	insertSQL    = regexp.QuoteMeta(`INSERT INTO jobs(id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, owner, status, created_at, started_at, heartbeat_at)`)
+	recoverSQL   = regexp.QuoteMeta(`UPDATE jobs SET status=$1, owner=NULL, started_at=NULL, heartbeat_at=NULL WHERE status=$2 AND mode=$3`)
+	interruptSQL = regexp.QuoteMeta(`UPDATE jobs SET status=$1, error=$2, finished_at=$3 WHERE status=$4 AND mode=$5`)
+	lockSQL      = regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)
+	countSQL     = regexp.QuoteMeta(`SELECT COUNT(*) FROM jobs WHERE status=$1 AND mode<>$2`)
+	claimSQL     = regexp.QuoteMeta(`FROM jobs j WHERE status=$1 AND (SELECT COUNT(*) FROM jobs r WHERE r.status=$2 AND r.mode<>$3 AND r.submitter=j.submitter) < $4 AND (j.parent_id IS NULL OR (SELECT COUNT(*) FROM jobs s WHERE s.parent_id=j.parent_id AND s.status=$5) < (SELECT p.max_parallel FROM jobs p WHERE p.id=j.parent_id)) ORDER BY created_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`)
+	markSQL      = regexp.QuoteMeta(`UPDATE jobs SET status=$1, owner=$2, started_at=$3, heartbeat_at=$3 WHERE id=$4`)
+	finishSQL    = regexp.QuoteMeta(`UPDATE jobs SET status=$1, exit_code=$2, logs=$3, error=$4, finished_at=$5 WHERE id=$6`)
+	requeueSQL   = regexp.QuoteMeta(`UPDATE jobs SET status=$1, owner=NULL, started_at=NULL, heartbeat_at=NULL WHERE id=$2 AND owner=$3 AND status=$4`)
+	attemptSQL   = regexp.QuoteMeta(`INSERT INTO job_attempts(id, job_id, attempt, status, started_at)`)
+	attemptDone  = regexp.QuoteMeta(`UPDATE job_attempts SET status=$1, exit_code=$2, oom_killed=$3, logs=$4, error=$5, finished_at=$6 WHERE id=$7`)
+	parentSQL    = regexp.QuoteMeta(`UPDATE jobs p SET`)
+)
+
+var jobCols = []string{"id", "submitter", "image", "cmd_json", "host_dir", "container_dir", "options_json", "mode", "status", "exit_code", "logs", "error", "created_at", "started_at", "finished_at"}
+
+// expectClaim 設定一次成功領取 id 的交易。
+func expectClaim(mock sqlmock.Sqlmock, id, options string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(lockSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(countSQL).WithArgs("running", "matrix").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(claimSQL).WithArgs("pending", "running", "matrix", 1, "running").
+		WillReturnRows(sqlmock.NewRows(jobCols).AddRow(id, "alice", "alpine", `["make"]`, "/data/a", "/w", options, "queued", "pending", nil, nil, nil, 100, nil, nil))
+	mock.ExpectExec(markSQL).WithArgs("running", sqlmock.AnyArg(), sqlmock.AnyArg(), id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func waitExpectations(t *testing.T, mock sqlmock.Sqlmock) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		err := mock.ExpectationsWereMet()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unmet expectations: %v", err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestManager_RunsQueuedJob(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	var got containers.JobOptions
+	m := NewManager(storage.NewJobRepository(db), func(ctx context.Context, opts containers.JobOptions) (containers.JobResult, error) {
+		got = opts
+		return containers.JobResult{ExitCode: 2, Logs: "FAIL\n", Status: containers.JobFailed}, nil
+	}, Config{Workers: 1, MaxRunning: 4, MaxPerUser: 1, PollInterval: time.Hour, HeartbeatInterval: time.Hour})
+
+	mock.ExpectExec(insertSQL).
+		WithArgs(sqlmock.AnyArg(), "alice", "alpine", `["make"]`, "/data/a", "/w", sqlmock.AnyArg(), "queued", nil, "pending", sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	opts := containers.JobOptions{Image: "alpine", HostDir: "/data/a", ContainerDir: "/w", Cmd: []string{"make"}, Env: map[string]string{"CI": "1"}, Timeout: time.Minute}
+	if _, err := m.Submit(context.Background(), storage.JobRecord{Submitter: "alice", Image: "alpine", Cmd: []string{"make"}, HostDir: "/data/a", ContainerDir: "/w"}, opts); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	// 啟動時先處理遺留的執行中作業，再由 worker 領取
+	mock.ExpectExec(recoverSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(interruptSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectClaim(mock, "j1", `{"image":"alpine","hostDir":"/data/a","containerDir":"/w","cmd":["make"],"env":{"CI":"1"},"timeout":60000000000}`)
+	mock.ExpectExec(attemptSQL).WithArgs(sqlmock.AnyArg(), "j1", "running", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptDone).WithArgs("failed", int64(2), false, "FAIL\n", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(finishSQL).WithArgs("failed", int64(2), "FAIL\n", "", sqlmock.AnyArg(), "j1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(parentSQL).WithArgs("succeeded", "failed", sqlmock.AnyArg(), "j1", "running", "pending").WillReturnResult(sqlmock.NewResult(0, 0))
+	m.Start(context.Background())
+	waitExpectations(t, mock)
+	m.Close()
+
+	if got.Env["CI"] != "1" || got.Timeout != time.Minute || got.Cmd[0] != "make" {
+		t.Fatalf("options not restored: %+v", got)
+	}
+}
+
+func TestManager_RequeuesOnShutdown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	started := make(chan struct{})
+	m := NewManager(storage.NewJobRepository(db), func(ctx context.Context, opts containers.JobOptions) (containers.JobResult, error) {
+		close(started)
+		<-ctx.Done()
+		return containers.JobResult{}, ctx.Err()
+	}, Config{Workers: 1, MaxRunning: 4, MaxPerUser: 1, PollInterval: time.Hour, HeartbeatInterval: time.Hour})
+
+	mock.ExpectExec(recoverSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(interruptSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectClaim(mock, "j1", `{"image":"alpine"}`)
+	mock.ExpectExec(attemptSQL).WithArgs(sqlmock.AnyArg(), "j1", "running", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	m.Start(context.Background())
+	<-started
+
+	// 中斷的作業放回 pending，不寫入失敗結果；中斷的那次執行仍保留紀錄
+	mock.ExpectExec(attemptDone).WithArgs("failed", int64(-1), false, "", context.Canceled.Error(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(requeueSQL).WithArgs("pending", "j1", m.owner, "running").WillReturnResult(sqlmock.NewResult(0, 1))
+	m.Close()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestManager_RunSyncRecordsResult(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	m := NewManager(storage.NewJobRepository(db), func(ctx context.Context, opts containers.JobOptions) (containers.JobResult, error) {
+		return containers.JobResult{}, containers.ErrNotSupported
+	}, Config{HeartbeatInterval: time.Hour})
+
+	mock.ExpectExec(insertSQL).
+		WithArgs(sqlmock.AnyArg(), "", "alpine", "null", "", "", "", "sync", m.owner, "running", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptDone).WithArgs("failed", int64(-1), false, "", containers.ErrNotSupported.Error(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(finishSQL).WithArgs("failed", int64(-1), "", containers.ErrNotSupported.Error(), sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	id, _, err := m.Run(context.Background(), storage.JobRecord{Image: "alpine"}, containers.JobOptions{Image: "alpine"})
+	if err != containers.ErrNotSupported || id == "" {
+		t.Fatalf("run: id=%q err=%v", id, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestManager_RunLimitedWaitsForSlot(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	m := NewManager(storage.NewJobRepository(db), func(ctx context.Context, opts containers.JobOptions) (containers.JobResult, error) {
+		return containers.JobResult{Status: containers.JobSucceeded}, nil
+	}, Config{MaxRunning: 1, MaxPerUser: 1, PollInterval: time.Millisecond, HeartbeatInterval: time.Hour})
+	userCountSQL := regexp.QuoteMeta(`SELECT COUNT(*) FROM jobs WHERE status=$1 AND mode<>$2 AND submitter=$3`)
+
+	// 第一次已達全域上限，等待後再取得名額
+	mock.ExpectBegin()
+	mock.ExpectExec(lockSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(countSQL).WithArgs("running", "matrix").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(lockSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(countSQL).WithArgs("running", "matrix").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(userCountSQL).WithArgs("running", "matrix", "alice").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectExec(insertSQL).
+		WithArgs(sqlmock.AnyArg(), "alice", "alpine", "null", "", "", "", "sync", m.owner, "running", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec(attemptSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptDone).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(finishSQL).WithArgs("succeeded", int64(0), "", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	id, res, err := m.RunLimited(context.Background(), storage.JobRecord{Submitter: "alice", Image: "alpine"}, containers.JobOptions{Image: "alpine"})
+	if err != nil || id == "" || res.Status != containers.JobSucceeded {
+		t.Fatalf("run limited: id=%q res=%+v err=%v", id, res, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+
+}
+
+func TestManager_RetriesRecordEachAttempt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	// 依序：記憶體不足、映像拉取失敗、成功
+	results := []struct {
+		res containers.JobResult
+		err error
+	}{
+		{containers.JobResult{ExitCode: 137, Logs: "killed\n", Status: containers.JobFailed, OOMKilled: true}, nil},
+		{containers.JobResult{}, &containers.Error{Kind: containers.ErrImagePull, Op: "pull alpine"}},
+		{containers.JobResult{Logs: "ok\n", Status: containers.JobSucceeded}, nil},
+	}
+	calls := 0
+	m := NewManager(storage.NewJobRepository(db), func(ctx context.Context, opts containers.JobOptions) (containers.JobResult, error) {
+		r := results[calls]
+		calls++
+		return r.res, r.err
+	}, Config{HeartbeatInterval: time.Hour})
+
+	mock.ExpectExec(insertSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptDone).WithArgs("failed", int64(137), true, "killed\n", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(attemptSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptDone).WithArgs("failed", int64(-1), false, "", "pull alpine: image pull failed", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(attemptSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptDone).WithArgs("succeeded", int64(0), false, "ok\n", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(finishSQL).WithArgs("succeeded", int64(0), "ok\n", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	opts := containers.JobOptions{Image: "alpine", Retry: &containers.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, OOMKilled: true, ProviderError: true}}
+	_, res, err := m.Run(context.Background(), storage.JobRecord{Image: "alpine"}, opts)
+	if err != nil || res.Status != containers.JobSucceeded || calls != 3 {
+		t.Fatalf("run: res=%+v err=%v calls=%d", res, err, calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+
+	// 不符合條件或次數用盡時回傳最後一次的結果
+	calls = 0
+	opts.Retry = &containers.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, ExitCodes: []int64{75}}
+	mock.ExpectExec(insertSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(attemptDone).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(finishSQL).WithArgs("failed", int64(137), "killed\n", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, res, _ := m.Run(context.Background(), storage.JobRecord{Image: "alpine"}, opts); !res.OOMKilled || calls != 1 {
+		t.Fatalf("non-matching failure retried: res=%+v calls=%d", res, calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestManager_SubmitMatrix(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	m := NewManager(storage.NewJobRepository(db), nil, Config{Workers: 2})
+
+	// 父紀錄與子作業在同一個交易中寫入；maxParallel 超過子作業數時以子作業數為上限
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO jobs(id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, status, max_parallel, created_at, started_at)`)).
+		WithArgs(sqlmock.AnyArg(), "alice", "python", `["train","${matrix.lr}"]`, "/d", "/w", `{"axes":{"lr":["1","2"]}}`, "matrix", "running", 2, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	for i, lr := range []string{"1", "2"} {
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO jobs(id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, status, parent_id, matrix_index, matrix_values, created_at)`)).
+			WithArgs(sqlmock.AnyArg(), "alice", "python", `["train","`+lr+`"]`, "/d", "/w", sqlmock.AnyArg(), "queued", "pending", sqlmock.AnyArg(), i, `{"lr":"`+lr+`"}`, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+	parent := storage.JobRecord{Submitter: "alice", Image: "python", Cmd: []string{"train", "${matrix.lr}"}, HostDir: "/d", ContainerDir: "/w", OptionsJSON: `{"axes":{"lr":["1","2"]}}`}
+	children := []MatrixJob{
+		{Values: map[string]string{"lr": "1"}, Options: containers.JobOptions{Image: "python", Cmd: []string{"train", "1"}, HostDir: "/d", ContainerDir: "/w"}},
+		{Values: map[string]string{"lr": "2"}, Options: containers.JobOptions{Image: "python", Cmd: []string{"train", "2"}, HostDir: "/d", ContainerDir: "/w"}},
+	}
+	id, ids, err := m.SubmitMatrix(context.Background(), parent, 8, children)
+	if err != nil || id == "" || len(ids) != 2 {
+		t.Fatalf("submit: id=%q ids=%v err=%v", id, ids, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
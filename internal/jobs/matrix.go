@@ -0,0 +1,85 @@
+package jobs
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"container-manager/internal/containers"
+)
+
+// MaxMatrixJobs 單一矩陣展開後的子作業數上限。
+const MaxMatrixJobs = 256
+
+var (
+	axisName    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	placeholder = regexp.MustCompile(`\$\{matrix\.([^}]*)\}`)
+)
+
+// Matrix 參數掃描的軸：軸名稱 -> 依序嘗試的值。
+type Matrix map[string][]string
+
+// Expand 回傳所有軸的值的組合；軸依名稱排序，最後一個軸變化最快。
+func (m Matrix) Expand() ([]map[string]string, error) {
+	if len(m) == 0 {
+		return nil, fmt.Errorf("%w: matrix has no axes", containers.ErrInvalidOptions)
+	}
+	names := make([]string, 0, len(m))
+	total := 1
+	for name, values := range m {
+		if !axisName.MatchString(name) {
+			return nil, fmt.Errorf("%w: invalid matrix axis name %q", containers.ErrInvalidOptions, name)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("%w: matrix axis %q has no values", containers.ErrInvalidOptions, name)
+		}
+		total *= len(values)
+		if total > MaxMatrixJobs {
+			return nil, fmt.Errorf("%w: matrix expands to more than %d jobs", containers.ErrInvalidOptions, MaxMatrixJobs)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]map[string]string, 0, total)
+	for i := 0; i < total; i++ {
+		combo := make(map[string]string, len(names))
+		rest := i
+		for k := len(names) - 1; k >= 0; k-- {
+			values := m[names[k]]
+			combo[names[k]] = values[rest%len(values)]
+			rest /= len(values)
+		}
+		out = append(out, combo)
+	}
+	return out, nil
+}
+
+// Substitute 將 cmd 與 env 值中的 ${matrix.<軸>} 替換為 values 中的值；引用不存在的軸時回傳錯誤。
+func Substitute(opts containers.JobOptions, values map[string]string) (containers.JobOptions, error) {
+	var missing string
+	replace := func(s string) string {
+		return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+			name := placeholder.FindStringSubmatch(m)[1]
+			v, ok := values[name]
+			if !ok && missing == "" {
+				missing = name
+			}
+			return v
+		})
+	}
+	out := opts
+	out.Cmd = make([]string, len(opts.Cmd))
+	for i, arg := range opts.Cmd {
+		out.Cmd[i] = replace(arg)
+	}
+	if opts.Env != nil {
+		out.Env = make(map[string]string, len(opts.Env))
+		for k, v := range opts.Env {
+			out.Env[k] = replace(v)
+		}
+	}
+	if missing != "" {
+		return containers.JobOptions{}, fmt.Errorf("%w: unknown matrix axis %q in ${matrix.%s}", containers.ErrInvalidOptions, missing, missing)
+	}
+	return out, nil
+}
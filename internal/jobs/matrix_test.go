@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"container-manager/internal/containers"
+)
+
+func TestMatrix_Expand(t *testing.T) {
+	combos, err := Matrix{"lr": {"0.1", "0.01"}, "file": {"a.csv", "b.csv", "c.csv"}}.Expand()
+	if err != nil {
+		t.Fatalf("expand: %v", err)
+	}
+	// 軸依名稱排序，最後一個軸（lr）變化最快
+	want := []map[string]string{
+		{"file": "a.csv", "lr": "0.1"}, {"file": "a.csv", "lr": "0.01"},
+		{"file": "b.csv", "lr": "0.1"}, {"file": "b.csv", "lr": "0.01"},
+		{"file": "c.csv", "lr": "0.1"}, {"file": "c.csv", "lr": "0.01"},
+	}
+	if !reflect.DeepEqual(combos, want) {
+		t.Fatalf("combos=%v", combos)
+	}
+
+	big := Matrix{"a": make([]string, 16), "b": make([]string, 16), "c": make([]string, 2)}
+	for name, m := range map[string]Matrix{"empty": {}, "no values": {"lr": nil}, "bad name": {"l-r": {"1"}}, "too many": big} {
+		if _, err := m.Expand(); !errors.Is(err, containers.ErrInvalidOptions) {
+			t.Fatalf("%s: expected ErrInvalidOptions, got %v", name, err)
+		}
+	}
+}
+
+func TestSubstitute(t *testing.T) {
+	tmpl := containers.JobOptions{
+		Image: "python:3.11",
+		Cmd:   []string{"python", "train.py", "--lr=${matrix.lr}", "${matrix.file}"},
+		Env:   map[string]string{"RUN": "${matrix.file}-${matrix.lr}", "CI": "1"},
+	}
+	got, err := Substitute(tmpl, map[string]string{"lr": "0.1", "file": "a.csv"})
+	if err != nil {
+		t.Fatalf("substitute: %v", err)
+	}
+	if !reflect.DeepEqual(got.Cmd, []string{"python", "train.py", "--lr=0.1", "a.csv"}) || got.Env["RUN"] != "a.csv-0.1" || got.Env["CI"] != "1" {
+		t.Fatalf("unexpected options %+v", got)
+	}
+	if tmpl.Cmd[2] != "--lr=${matrix.lr}" || tmpl.Env["RUN"] != "${matrix.file}-${matrix.lr}" {
+		t.Fatalf("template modified: %+v", tmpl)
+	}
+	if _, err := Substitute(tmpl, map[string]string{"lr": "0.1"}); !errors.Is(err, containers.ErrInvalidOptions) {
+		t.Fatalf("expected ErrInvalidOptions for unknown axis, got %v", err)
+	}
+}
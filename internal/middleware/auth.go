@@ -10,12 +10,16 @@ import (
     jwt "github.com/golang-jwt/jwt/v5"
 )
 
+// WebSocketTokenProtocolPrefix 瀏覽器無法設定 Authorization header，
+// WebSocket 用戶端可改以 Sec-WebSocket-Protocol: bearer.<token> 傳遞 JWT。
+const WebSocketTokenProtocolPrefix = "bearer."
+
 // Auth 驗證 Authorization: Bearer <token>，預設 token 為 devtoken（可由環境變數 AUTH_TOKEN 覆蓋）。
+// WebSocket 升級請求另可使用 access_token 查詢參數或 bearer.<token> 子協定。
 func Auth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-        header := c.GetHeader("Authorization")
-        parts := strings.SplitN(header, " ", 2)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		raw, ok := bearerToken(c)
+		if !ok {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
 			return
 		}
@@ -23,7 +27,7 @@ func Auth() gin.HandlerFunc {
         // 僅支援 JWT
         secret := strings.TrimSpace(os.Getenv("JWT_SECRET"))
         if secret == "" { secret = "devsecret" }
-        token, err := jwt.Parse(parts[1], func(t *jwt.Token) (interface{}, error) {
+        token, err := jwt.Parse(raw, func(t *jwt.Token) (interface{}, error) {
             if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
                 return nil, jwt.ErrTokenSignatureInvalid
             }
@@ -33,10 +37,43 @@ func Auth() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
+		if sub, err := token.Claims.GetSubject(); err == nil {
+			c.Set(subjectKey, sub)
+		}
 		c.Next()
 	}
 }
 
+const subjectKey = "auth.subject"
+
+// Subject 回傳通過 Auth 驗證的 JWT sub；未經驗證的請求回傳空字串。
+func Subject(c *gin.Context) string {
+	return c.GetString(subjectKey)
+}
+
+// bearerToken 依序從 Authorization header、（僅限 WebSocket 升級）查詢參數與子協定取得 token。
+func bearerToken(c *gin.Context) (string, bool) {
+	if header := c.GetHeader("Authorization"); header != "" {
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+			return "", false
+		}
+		return parts[1], true
+	}
+	if !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		return "", false
+	}
+	if t := c.Query("access_token"); t != "" {
+		return t, true
+	}
+	for _, p := range strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",") {
+		if t, ok := strings.CutPrefix(strings.TrimSpace(p), WebSocketTokenProtocolPrefix); ok && t != "" {
+			return t, true
+		}
+	}
+	return "", false
+}
+
 func getenvDefault(key, def string) string {
 	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
 		return v
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader 用戶端可自帶請求 ID，回應一律回傳同名 header。
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDKey = "requestId"
+
+// RequestID 沿用用戶端提供的 X-Request-ID，未提供時產生新的 ID。
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		RequestIDFrom(c)
+		c.Next()
+	}
+}
+
+// RequestIDFrom 回傳目前請求的 ID；未經過 RequestID 中介層時（例如測試）即時指派一個。
+func RequestIDFrom(c *gin.Context) string {
+	if id := c.GetString(requestIDKey); id != "" {
+		return id
+	}
+	id := c.GetHeader(RequestIDHeader)
+	if id == "" || len(id) > 128 {
+		id = uuid.NewString()
+	}
+	c.Set(requestIDKey, id)
+	c.Header(RequestIDHeader, id)
+	return id
+}
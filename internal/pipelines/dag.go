@@ -0,0 +1,95 @@
+// Package pipelines 執行由多個作業步驟組成的 DAG。所有步驟掛載同一個上傳資料夾，
+// 步驟在相依的步驟全部成功後才開始；每次執行與各步驟的狀態記錄於 pipeline_runs 與 pipeline_steps。
+package pipelines
+
+import (
+	"fmt"
+
+	"container-manager/internal/containers"
+)
+
+// Policy 步驟失敗時的處理方式。
+type Policy string
+
+const (
+	FailFast Policy = "fail-fast" // 取消執行中的步驟並略過其餘步驟
+	Continue Policy = "continue"  // 只略過依賴失敗步驟的步驟，其他分支繼續執行
+)
+
+// MaxSteps 單一管線的步驟數上限。
+const MaxSteps = 100
+
+// Step 管線中的一個作業；DependsOn 列出須先成功的步驟名稱。
+type Step struct {
+	Name      string
+	DependsOn []string
+	Options   containers.JobOptions
+}
+
+// Validate 檢查失敗策略與步驟：名稱不可為空或重複、相依的步驟須存在且不可形成循環。
+// 回傳依相依關係排序的步驟：每個步驟都排在其相依的步驟之後。
+func Validate(steps []Step, p Policy) ([]Step, error) {
+	if p != FailFast && p != Continue {
+		return nil, fmt.Errorf("%w: onFailure must be fail-fast or continue", containers.ErrInvalidOptions)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: pipeline has no steps", containers.ErrInvalidOptions)
+	}
+	if len(steps) > MaxSteps {
+		return nil, fmt.Errorf("%w: pipeline has more than %d steps", containers.ErrInvalidOptions, MaxSteps)
+	}
+	index := make(map[string]int, len(steps))
+	for i, s := range steps {
+		if s.Name == "" {
+			return nil, fmt.Errorf("%w: step %d has no name", containers.ErrInvalidOptions, i)
+		}
+		if _, dup := index[s.Name]; dup {
+			return nil, fmt.Errorf("%w: duplicate step %q", containers.ErrInvalidOptions, s.Name)
+		}
+		index[s.Name] = i
+	}
+	// Kahn：反覆取出相依皆已排入的步驟；剩下的步驟即位於循環中
+	indegree := make([]int, len(steps))
+	dependents := make([][]int, len(steps))
+	for i, s := range steps {
+		seen := map[string]bool{}
+		for _, d := range s.DependsOn {
+			j, ok := index[d]
+			if !ok {
+				return nil, fmt.Errorf("%w: step %q depends on unknown step %q", containers.ErrInvalidOptions, s.Name, d)
+			}
+			if j == i {
+				return nil, fmt.Errorf("%w: step %q depends on itself", containers.ErrInvalidOptions, s.Name)
+			}
+			if seen[d] {
+				continue
+			}
+			seen[d] = true
+			indegree[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+	order := make([]Step, 0, len(steps))
+	placed := make([]bool, len(steps))
+	for len(order) < len(steps) {
+		progressed := false
+		for i := range steps {
+			if placed[i] || indegree[i] > 0 {
+				continue
+			}
+			placed[i], progressed = true, true
+			order = append(order, steps[i])
+			for _, k := range dependents[i] {
+				indegree[k]--
+			}
+		}
+		if !progressed {
+			for i := range steps {
+				if !placed[i] {
+					return nil, fmt.Errorf("%w: dependency cycle involving step %q", containers.ErrInvalidOptions, steps[i].Name)
+				}
+			}
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,48 @@
+package pipelines
+
+import (
+	"errors"
+	"testing"
+
+	"container-manager/internal/containers"
+)
+
+func TestValidate_OrdersByDependencies(t *testing.T) {
+	steps := []Step{
+		{Name: "deploy", DependsOn: []string{"test", "lint"}},
+		{Name: "test", DependsOn: []string{"build"}},
+		{Name: "build"},
+		{Name: "lint", DependsOn: []string{"build", "build"}},
+	}
+	order, err := Validate(steps, FailFast)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	pos := map[string]int{}
+	for i, s := range order {
+		pos[s.Name] = i
+	}
+	if len(order) != 4 || pos["build"] > pos["test"] || pos["build"] > pos["lint"] || pos["deploy"] != 3 {
+		t.Fatalf("unexpected order %v", pos)
+	}
+}
+
+func TestValidate_Rejects(t *testing.T) {
+	cases := map[string]struct {
+		steps  []Step
+		policy Policy
+	}{
+		"policy":    {[]Step{{Name: "a"}}, Policy("retry")},
+		"empty":     {nil, FailFast},
+		"no name":   {[]Step{{}}, FailFast},
+		"duplicate": {[]Step{{Name: "a"}, {Name: "a"}}, FailFast},
+		"unknown":   {[]Step{{Name: "a", DependsOn: []string{"b"}}}, FailFast},
+		"self":      {[]Step{{Name: "a", DependsOn: []string{"a"}}}, Continue},
+		"cycle":     {[]Step{{Name: "a", DependsOn: []string{"c"}}, {Name: "b", DependsOn: []string{"a"}}, {Name: "c", DependsOn: []string{"b"}}, {Name: "d"}}, Continue},
+	}
+	for name, tc := range cases {
+		if _, err := Validate(tc.steps, tc.policy); !errors.Is(err, containers.ErrInvalidOptions) {
+			t.Fatalf("%s: expected ErrInvalidOptions, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package pipelines
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/storage"
+)
+
+// RunFunc 執行並記錄一次作業，回傳作業 ID；通常為 jobs.Manager.Run。
+type RunFunc func(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error)
+
+// Runner 在本實例中執行管線，並定期更新心跳；心跳逾時的執行視為其實例已終止。
+type Runner struct {
+	repo       *storage.PipelineRepository
+	run        RunFunc
+	heartbeat  time.Duration
+	staleAfter time.Duration
+
+	mu     sync.Mutex      // 讓 Submit 的 wg.Add 不與 Close 交錯
+	ctx    context.Context // 所有執行共用；Close 時取消
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRunner(repo *storage.PipelineRepository, run RunFunc) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{repo: repo, run: run, heartbeat: 10 * time.Second, staleAfter: 30 * time.Second, ctx: ctx, cancel: cancel}
+}
+
+// Start 將其他實例遺留的執行標記為中斷，並定期重複檢查；ctx 結束時等同 Close。
+func (r *Runner) Start(ctx context.Context) {
+	context.AfterFunc(ctx, r.cancel)
+	_, _ = r.repo.RecoverStale(ctx, time.Now().Add(-r.staleAfter))
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		t := time.NewTicker(r.staleAfter)
+		defer t.Stop()
+		for {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-t.C:
+				_, _ = r.repo.RecoverStale(r.ctx, time.Now().Add(-r.staleAfter))
+			}
+		}
+	}()
+}
+
+// Close 取消執行中的管線並等待其寫入結果；未完成的步驟記錄為取消，管線記錄為失敗。
+func (r *Runner) Close() {
+	r.mu.Lock()
+	r.cancel()
+	r.mu.Unlock()
+	r.wg.Wait()
+}
+
+// Submit 驗證並記錄管線後在背景執行，回傳執行 ID；p.OnFailure 為空時使用 fail-fast。
+// 步驟參數應已經過 containers.Service.PrepareJob 的檢查。
+func (r *Runner) Submit(ctx context.Context, p storage.PipelineRun, steps []Step) (string, error) {
+	if p.OnFailure == "" {
+		p.OnFailure = string(FailFast)
+	}
+	order, err := Validate(steps, Policy(p.OnFailure))
+	if err != nil {
+		return "", err
+	}
+	p.Steps = make([]storage.PipelineStep, 0, len(steps))
+	for _, s := range steps {
+		b, err := json.Marshal(s.Options)
+		if err != nil {
+			return "", err
+		}
+		p.Steps = append(p.Steps, storage.PipelineStep{Name: s.Name, DependsOn: s.DependsOn, OptionsJSON: string(b)})
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx.Err() != nil {
+		return "", fmt.Errorf("pipeline runner stopped: %w", r.ctx.Err())
+	}
+	id, err := r.repo.Insert(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	p.ID = id
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.execute(r.ctx, p, order)
+	}()
+	return id, nil
+}
+
+func (r *Runner) Get(ctx context.Context, id string) (storage.PipelineRun, error) {
+	return r.repo.Get(ctx, id)
+}
+
+func (r *Runner) List(ctx context.Context, f storage.PipelineFilter) ([]storage.PipelineRun, string, error) {
+	return r.repo.List(ctx, f)
+}
+
+// stepResult 一個步驟執行結束後回報給排程迴圈的結果。
+type stepResult struct {
+	name     string
+	status   storage.PipelineStatus
+	jobID    string
+	exitCode int64
+	err      string
+}
+
+// execute 依相依關係執行步驟：相依皆成功的步驟並行執行；依賴未成功步驟的步驟略過。
+func (r *Runner) execute(ctx context.Context, p storage.PipelineRun, order []Step) {
+	stop := r.beat(p.ID)
+	defer stop()
+	store := context.WithoutCancel(ctx)
+
+	stepCtx, cancelSteps := context.WithCancel(ctx)
+	defer cancelSteps()
+	results := make(chan stepResult)
+	state := make(map[string]storage.PipelineStatus, len(order))
+	for _, s := range order {
+		state[s.Name] = storage.PipelinePending
+	}
+	running, firstFailure := 0, ""
+	for {
+		if firstFailure == "" || Policy(p.OnFailure) == Continue {
+			// order 為拓撲順序，單次掃描即可讓略過沿相依關係傳遞
+			for _, s := range order {
+				if state[s.Name] != storage.PipelinePending || stepCtx.Err() != nil {
+					continue
+				}
+				ready, blocked := true, ""
+				for _, d := range s.DependsOn {
+					switch state[d] {
+					case storage.PipelineSucceeded:
+					case storage.PipelinePending, storage.PipelineRunning:
+						ready = false
+					default:
+						blocked = d
+					}
+				}
+				if blocked != "" {
+					state[s.Name] = storage.PipelineSkipped
+					_ = r.repo.FinishStep(store, p.ID, s.Name, storage.PipelineSkipped, "", 0, fmt.Sprintf("dependency %q did not succeed", blocked))
+					continue
+				}
+				if !ready {
+					continue
+				}
+				state[s.Name] = storage.PipelineRunning
+				_ = r.repo.StartStep(store, p.ID, s.Name)
+				running++
+				go func(s Step) { results <- r.step(stepCtx, p, s) }(s)
+			}
+		}
+		if running == 0 {
+			break
+		}
+		res := <-results
+		running--
+		if stepCtx.Err() != nil && res.status != storage.PipelineSucceeded {
+			// 被 fail-fast 或服務關閉中止，而不是步驟本身失敗
+			res.status, res.err = storage.PipelineCancelled, "cancelled: "+r.reason(ctx, firstFailure)
+		}
+		state[res.name] = res.status
+		_ = r.repo.FinishStep(store, p.ID, res.name, res.status, res.jobID, res.exitCode, res.err)
+		if res.status != storage.PipelineSucceeded && res.status != storage.PipelineCancelled && firstFailure == "" {
+			firstFailure = res.name
+			if Policy(p.OnFailure) == FailFast {
+				cancelSteps()
+			}
+		}
+	}
+
+	// fail-fast 或服務關閉時尚未開始的步驟
+	for _, s := range order {
+		if state[s.Name] == storage.PipelinePending {
+			_ = r.repo.FinishStep(store, p.ID, s.Name, storage.PipelineSkipped, "", 0, "skipped: "+r.reason(ctx, firstFailure))
+		}
+	}
+	switch {
+	case ctx.Err() != nil:
+		_ = r.repo.Finish(store, p.ID, storage.PipelineFailed, "interrupted: server stopped")
+	case firstFailure != "":
+		_ = r.repo.Finish(store, p.ID, storage.PipelineFailed, fmt.Sprintf("step %q failed", firstFailure))
+	default:
+		_ = r.repo.Finish(store, p.ID, storage.PipelineSucceeded, "")
+	}
+}
+
+// reason 說明步驟為何被取消或略過。
+func (r *Runner) reason(ctx context.Context, firstFailure string) string {
+	if ctx.Err() != nil {
+		return "server stopped"
+	}
+	return fmt.Sprintf("step %q failed", firstFailure)
+}
+
+// step 執行單一步驟；作業本身的紀錄（含日誌）由 RunFunc 寫入 jobs 表。
+func (r *Runner) step(ctx context.Context, p storage.PipelineRun, s Step) stepResult {
+	rec := storage.JobRecord{
+		Submitter:    p.Submitter,
+		Image:        s.Options.Image,
+		Cmd:          s.Options.Cmd,
+		HostDir:      s.Options.HostDir,
+		ContainerDir: s.Options.ContainerDir,
+	}
+	jobID, res, err := r.run(ctx, rec, s.Options)
+	if err != nil {
+		return stepResult{name: s.Name, status: storage.PipelineFailed, jobID: jobID, exitCode: -1, err: err.Error()}
+	}
+	return stepResult{name: s.Name, status: storage.PipelineStatus(res.Status), jobID: jobID, exitCode: res.ExitCode}
+}
+
+// beat 定期更新管線心跳直到回傳的 stop 被呼叫。
+func (r *Runner) beat(id string) (stop func()) {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		t := time.NewTicker(r.heartbeat)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				_ = r.repo.Heartbeat(context.Background(), id)
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
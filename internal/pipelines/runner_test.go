@@ -0,0 +1,111 @@
+package pipelines
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/storage"
+)
+
+var (
+	insertRunSQL  = regexp.QuoteMeta(`INSERT INTO pipeline_runs(`)
+	insertStepSQL = regexp.QuoteMeta(`INSERT INTO pipeline_steps(`)
+	startStepSQL  = regexp.QuoteMeta(`UPDATE pipeline_steps SET status=$1, started_at=$2 WHERE run_id=$3 AND name=$4`)
+	finishStepSQL = regexp.QuoteMeta(`UPDATE pipeline_steps SET status=$1, job_id=$2, exit_code=$3, error=$4, finished_at=$5 WHERE run_id=$6 AND name=$7`)
+	finishSQL     = regexp.QuoteMeta(`UPDATE pipeline_runs SET status=$1, error=$2, finished_at=$3 WHERE id=$4`)
+)
+
+// newTestRunner 建立使用 sqlmock 的 Runner；步驟並行執行，因此不檢查 SQL 的先後順序。
+func newTestRunner(t *testing.T, run RunFunc) (*Runner, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	mock.MatchExpectationsInOrder(false)
+	return NewRunner(storage.NewPipelineRepository(db), run), mock
+}
+
+func expectInsert(mock sqlmock.Sqlmock, steps int) {
+	mock.ExpectBegin()
+	mock.ExpectExec(insertRunSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	for i := 0; i < steps; i++ {
+		mock.ExpectExec(insertStepSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func expectStep(mock sqlmock.Sqlmock, name, status, jobID string, code int64, errMsg string) {
+	if jobID != "" {
+		mock.ExpectExec(startStepSQL).WithArgs("running", sqlmock.AnyArg(), sqlmock.AnyArg(), name).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	var job any
+	if jobID != "" {
+		job = jobID
+	}
+	mock.ExpectExec(finishStepSQL).WithArgs(status, job, code, errMsg, sqlmock.AnyArg(), sqlmock.AnyArg(), name).WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func step(name string, deps ...string) Step {
+	return Step{Name: name, DependsOn: deps, Options: containers.JobOptions{Image: "alpine", Cmd: []string{name}}}
+}
+
+func TestRunner_FailFastCancelsAndSkips(t *testing.T) {
+	lintStarted := make(chan struct{})
+	r, mock := newTestRunner(t, func(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error) {
+		switch opts.Cmd[0] {
+		case "test":
+			<-lintStarted
+			return "job-test", containers.JobResult{Status: containers.JobFailed, ExitCode: 1}, nil
+		case "lint":
+			close(lintStarted)
+			<-ctx.Done()
+			return "job-lint", containers.JobResult{}, ctx.Err()
+		}
+		return "job-" + opts.Cmd[0], containers.JobResult{Status: containers.JobSucceeded}, nil
+	})
+	expectInsert(mock, 4)
+	expectStep(mock, "build", "succeeded", "job-build", 0, "")
+	expectStep(mock, "test", "failed", "job-test", 1, "")
+	expectStep(mock, "lint", "cancelled", "job-lint", -1, `cancelled: step "test" failed`)
+	expectStep(mock, "deploy", "skipped", "", 0, `skipped: step "test" failed`)
+	mock.ExpectExec(finishSQL).WithArgs("failed", `step "test" failed`, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := r.Submit(context.Background(), storage.PipelineRun{HostDir: "/srv/u1", ContainerDir: "/workspace"},
+		[]Step{step("build"), step("test", "build"), step("lint", "build"), step("deploy", "test", "lint")})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	r.wg.Wait() // 未呼叫 Start，只等待管線執行完畢
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRunner_ContinueRunsIndependentSteps(t *testing.T) {
+	r, mock := newTestRunner(t, func(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error) {
+		if opts.Cmd[0] == "unit" {
+			return "job-unit", containers.JobResult{}, containers.ErrImagePull
+		}
+		return "job-" + opts.Cmd[0], containers.JobResult{Status: containers.JobSucceeded}, nil
+	})
+	expectInsert(mock, 3)
+	expectStep(mock, "unit", "failed", "job-unit", -1, containers.ErrImagePull.Error())
+	expectStep(mock, "report", "skipped", "", 0, `dependency "unit" did not succeed`)
+	expectStep(mock, "docs", "succeeded", "job-docs", 0, "")
+	mock.ExpectExec(finishSQL).WithArgs("failed", `step "unit" failed`, sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := r.Submit(context.Background(), storage.PipelineRun{OnFailure: string(Continue)},
+		[]Step{step("unit"), step("report", "unit"), step("docs")})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	r.wg.Wait() // 未呼叫 Start，只等待管線執行完畢
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
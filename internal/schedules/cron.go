@@ -0,0 +1,59 @@
+package schedules
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"container-manager/internal/containers"
+)
+
+// Policy 上一次觸發仍在執行時的處理方式。
+type Policy string
+
+const (
+	PolicySkip    Policy = "skip"    // 略過本次觸發
+	PolicyQueue   Policy = "queue"   // 待上一次結束後執行；最多排隊一次
+	PolicyReplace Policy = "replace" // 取消上一次並改為執行本次
+)
+
+func (p Policy) valid() bool {
+	return p == PolicySkip || p == PolicyQueue || p == PolicyReplace
+}
+
+// Validate 檢查 cron 表示式（標準五欄位或 @hourly 等描述）、時區與重疊策略。
+func Validate(expr, tz string, p Policy) error {
+	if _, _, err := parse(expr, tz); err != nil {
+		return err
+	}
+	if !p.valid() {
+		return fmt.Errorf("%w: overlap must be skip, queue or replace", containers.ErrInvalidOptions)
+	}
+	return nil
+}
+
+// Next 回傳 after 之後的下一個觸發時間；tz 為空時使用 UTC。
+func Next(expr, tz string, after time.Time) (time.Time, error) {
+	sched, loc, err := parse(expr, tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := sched.Next(after.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("%w: cron %q never fires", containers.ErrInvalidOptions, expr)
+	}
+	return next, nil
+}
+
+func parse(expr, tz string) (cron.Schedule, *time.Location, error) {
+	sched, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: cron %q: %v", containers.ErrInvalidOptions, expr, err)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: timezone %q: %v", containers.ErrInvalidOptions, tz, err)
+	}
+	return sched, loc, nil
+}
@@ -0,0 +1,44 @@
+package schedules
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"container-manager/internal/containers"
+)
+
+func TestNext_UsesTimezone(t *testing.T) {
+	after := time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)
+	// 台北 09:00 即 UTC 01:00
+	next, err := Next("0 9 * * *", "Asia/Taipei", after)
+	if err != nil {
+		t.Fatalf("next: %v", err)
+	}
+	if want := time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC); !next.Equal(want) {
+		t.Fatalf("next=%v want %v", next.UTC(), want)
+	}
+	next, err = Next("@hourly", "", after)
+	if err != nil || !next.Equal(time.Date(2024, 3, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Fatalf("@hourly next=%v err=%v", next, err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		expr, tz string
+		policy   Policy
+	}{
+		{"61 * * * *", "UTC", PolicySkip},
+		{"* * * * *", "Mars/Olympus", PolicySkip},
+		{"* * * * *", "UTC", Policy("parallel")},
+	}
+	for _, tc := range cases {
+		if err := Validate(tc.expr, tc.tz, tc.policy); !errors.Is(err, containers.ErrInvalidOptions) {
+			t.Fatalf("Validate(%q,%q,%q) err=%v, want ErrInvalidOptions", tc.expr, tc.tz, tc.policy, err)
+		}
+	}
+	if err := Validate("*/5 * * * *", "Europe/Berlin", PolicyReplace); err != nil {
+		t.Fatalf("valid schedule rejected: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+// Package schedules 依 cron 表示式定期執行作業範本。排程存放在 Postgres，
+// 多個實例中只有取得 advisory lock 的一個執行排程迴圈；每次觸發都寫入 schedule_runs。
+package schedules
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/storage"
+)
+
+// RunFunc 執行並記錄一次作業，回傳作業 ID；通常為 jobs.Manager.Run。
+type RunFunc func(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error)
+
+// Scheduler 定期檢查到期的排程並依重疊策略觸發作業。
+type Scheduler struct {
+	repo *storage.ScheduleRepository
+	run  RunFunc
+	tick time.Duration
+	now  func() time.Time
+
+	mu     sync.Mutex
+	active map[string]*activeRun // schedule ID -> 執行中的觸發
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// activeRun 某個排程目前執行中的觸發，以及等待其結束的下一次觸發。
+type activeRun struct {
+	runID    string
+	cancel   context.CancelFunc
+	replaced bool        // 被 replace 策略取消
+	next     *pendingRun // overlap=queue 或 replace 時等待執行
+}
+
+type pendingRun struct {
+	runID string
+	sched storage.ScheduleRecord
+	opts  containers.JobOptions
+}
+
+func NewScheduler(repo *storage.ScheduleRepository, run RunFunc) *Scheduler {
+	return &Scheduler{repo: repo, run: run, tick: time.Second, now: time.Now, active: map[string]*activeRun{}}
+}
+
+// Start 啟動排程迴圈；ctx 結束或 Close 時停止並取消執行中的觸發。
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.wg.Add(1)
+	go s.loop(ctx)
+}
+
+// Close 停止排程迴圈並等待執行中的觸發結束。
+func (s *Scheduler) Close() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// Create 驗證並新增排程，回傳含下次執行時間的紀錄。
+func (s *Scheduler) Create(ctx context.Context, rec storage.ScheduleRecord) (storage.ScheduleRecord, error) {
+	if err := s.prepare(&rec); err != nil {
+		return storage.ScheduleRecord{}, err
+	}
+	id, err := s.repo.Insert(ctx, rec)
+	if err != nil {
+		return storage.ScheduleRecord{}, err
+	}
+	return s.repo.Get(ctx, id)
+}
+
+// Update 以新的設定覆寫排程並重新計算下次執行時間；執行中的觸發不受影響。
+func (s *Scheduler) Update(ctx context.Context, rec storage.ScheduleRecord) (storage.ScheduleRecord, error) {
+	if err := s.prepare(&rec); err != nil {
+		return storage.ScheduleRecord{}, err
+	}
+	if err := s.repo.Update(ctx, rec); err != nil {
+		return storage.ScheduleRecord{}, err
+	}
+	return s.repo.Get(ctx, rec.ID)
+}
+
+func (s *Scheduler) Get(ctx context.Context, id string) (storage.ScheduleRecord, error) {
+	return s.repo.Get(ctx, id)
+}
+
+func (s *Scheduler) List(ctx context.Context, limit int) ([]storage.ScheduleRecord, error) {
+	return s.repo.List(ctx, limit)
+}
+
+func (s *Scheduler) Delete(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// Runs 列出排程的觸發紀錄；排程不存在時回傳 storage.ErrNotFound。
+func (s *Scheduler) Runs(ctx context.Context, id string, limit int) ([]storage.ScheduleRun, error) {
+	if _, err := s.repo.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.repo.ListRuns(ctx, id, limit)
+}
+
+// prepare 套用預設值（時區 UTC、overlap=skip）、驗證並計算下次執行時間。
+func (s *Scheduler) prepare(rec *storage.ScheduleRecord) error {
+	if rec.Timezone == "" {
+		rec.Timezone = "UTC"
+	}
+	if rec.Overlap == "" {
+		rec.Overlap = string(PolicySkip)
+	}
+	if err := Validate(rec.CronExpr, rec.Timezone, Policy(rec.Overlap)); err != nil {
+		return err
+	}
+	next, err := Next(rec.CronExpr, rec.Timezone, s.now())
+	if err != nil {
+		return err
+	}
+	rec.NextRunAt = sql.NullTime{Time: next, Valid: true}
+	return nil
+}
+
+func (s *Scheduler) loop(ctx context.Context) {
+	defer s.wg.Done()
+	var conn leaderConn
+	defer conn.release(s.repo)
+	t := time.NewTicker(s.tick)
+	defer t.Stop()
+	for {
+		// 只有持有 lock 的實例觸發排程，避免多個實例重複執行
+		if conn.acquire(ctx, s.repo) {
+			s.RunDue(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunDue 觸發所有到期的排程，並將下次執行時間推進到現在之後；
+// 服務停機期間錯過的多次觸發只補執行一次。
+func (s *Scheduler) RunDue(ctx context.Context) {
+	now := s.now()
+	due, err := s.repo.Due(ctx, now)
+	if err != nil {
+		return
+	}
+	for _, sched := range due {
+		at := sched.NextRunAt.Time
+		next, err := Next(sched.CronExpr, sched.Timezone, now)
+		if err != nil {
+			// 表示式在建立時已驗證；時區資料缺失等無法計算的情況略過不觸發
+			continue
+		}
+		if err := s.repo.Advance(ctx, sched.ID, next, at); err != nil {
+			continue
+		}
+		s.trigger(ctx, sched, at)
+	}
+}
+
+func (s *Scheduler) trigger(ctx context.Context, sched storage.ScheduleRecord, at time.Time) {
+	run := storage.ScheduleRun{ScheduleID: sched.ID, ScheduledAt: at}
+	var opts containers.JobOptions
+	if err := json.Unmarshal([]byte(sched.OptionsJSON), &opts); err != nil {
+		run.Status, run.Error = storage.ScheduleRunStatus(storage.JobFailed), err.Error()
+		_, _ = s.repo.InsertRun(ctx, run)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	a := s.active[sched.ID]
+	if a == nil {
+		run.Status = storage.RunRunning
+		id, err := s.repo.InsertRun(ctx, run)
+		if err != nil {
+			return
+		}
+		s.start(ctx, pendingRun{runID: id, sched: sched, opts: opts})
+		return
+	}
+	switch Policy(sched.Overlap) {
+	case PolicyQueue:
+		if a.next != nil {
+			run.Status, run.Error = storage.RunSkipped, "a run is already queued"
+			_, _ = s.repo.InsertRun(ctx, run)
+			return
+		}
+	case PolicyReplace:
+		a.replaced = true
+		a.cancel()
+		if a.next != nil {
+			// 尚未開始的排隊觸發由本次取代
+			_ = s.repo.FinishRun(ctx, a.next.runID, storage.RunCancelled, "", "replaced by a newer run")
+		}
+	default:
+		run.Status, run.Error = storage.RunSkipped, "previous run still in progress"
+		_, _ = s.repo.InsertRun(ctx, run)
+		return
+	}
+	run.Status = storage.RunQueued
+	id, err := s.repo.InsertRun(ctx, run)
+	if err != nil {
+		return
+	}
+	a.next = &pendingRun{runID: id, sched: sched, opts: opts}
+}
+
+// start 執行觸發；呼叫端須持有 s.mu。
+func (s *Scheduler) start(ctx context.Context, p pendingRun) {
+	runCtx, cancel := context.WithCancel(ctx)
+	a := &activeRun{runID: p.runID, cancel: cancel}
+	s.active[p.sched.ID] = a
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		s.execute(runCtx, a, p)
+		s.done(ctx, p.sched.ID, a)
+	}()
+}
+
+func (s *Scheduler) execute(ctx context.Context, a *activeRun, p pendingRun) {
+	rec := storage.JobRecord{
+		Submitter:    p.sched.Submitter,
+		Image:        p.opts.Image,
+		Cmd:          p.opts.Cmd,
+		HostDir:      p.opts.HostDir,
+		ContainerDir: p.opts.ContainerDir,
+	}
+	jobID, res, err := s.run(ctx, rec, p.opts)
+	status, errMsg := storage.ScheduleRunStatus(res.Status), ""
+	if err != nil {
+		status, errMsg = storage.ScheduleRunStatus(storage.JobFailed), err.Error()
+	}
+	if ctx.Err() != nil {
+		s.mu.Lock()
+		replaced := a.replaced
+		s.mu.Unlock()
+		status, errMsg = storage.RunCancelled, "scheduler stopped"
+		if replaced {
+			errMsg = "replaced by a newer run"
+		}
+	}
+	_ = s.repo.FinishRun(context.WithoutCancel(ctx), p.runID, status, jobID, errMsg)
+}
+
+// done 於觸發結束後啟動排隊中的下一次觸發；排程迴圈已停止時將其標記為取消。
+func (s *Scheduler) done(ctx context.Context, scheduleID string, a *activeRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.active, scheduleID)
+	next := a.next
+	if next == nil {
+		return
+	}
+	if ctx.Err() != nil {
+		_ = s.repo.FinishRun(context.WithoutCancel(ctx), next.runID, storage.RunCancelled, "", "scheduler stopped")
+		return
+	}
+	if err := s.repo.StartRun(ctx, next.runID); err != nil {
+		return
+	}
+	s.start(ctx, *next)
+}
+
+// leaderConn 持有排程迴圈 advisory lock 的連線。
+type leaderConn struct{ conn *sql.Conn }
+
+func (l *leaderConn) acquire(ctx context.Context, repo *storage.ScheduleRepository) bool {
+	if l.conn != nil {
+		if l.conn.PingContext(ctx) == nil {
+			return true
+		}
+		// 連線中斷時 lock 已隨 session 釋放，重新競爭
+		l.release(repo)
+	}
+	conn, ok, err := repo.TryLeaderLock(ctx)
+	if err != nil || !ok {
+		return false
+	}
+	l.conn = conn
+	return true
+}
+
+func (l *leaderConn) release(repo *storage.ScheduleRepository) {
+	if l.conn != nil {
+		repo.ReleaseLeaderLock(l.conn)
+		l.conn = nil
+	}
+}
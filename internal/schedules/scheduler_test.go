@@ -0,0 +1,143 @@
+package schedules
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/storage"
+)
+
+var (
+	dueSQL       = regexp.QuoteMeta(`FROM schedules WHERE enabled AND next_run_at<=$1 ORDER BY next_run_at`)
+	advanceSQL   = regexp.QuoteMeta(`UPDATE schedules SET next_run_at=$1, last_run_at=$2 WHERE id=$3`)
+	insertRunSQL = regexp.QuoteMeta(`INSERT INTO schedule_runs(id, schedule_id, status, error, scheduled_at, started_at, finished_at)`)
+	startRunSQL  = regexp.QuoteMeta(`UPDATE schedule_runs SET status=$1, started_at=$2 WHERE id=$3`)
+	finishRunSQL = regexp.QuoteMeta(`UPDATE schedule_runs SET status=$1, job_id=$2, error=$3, finished_at=$4 WHERE id=$5`)
+)
+
+var scheduleCols = []string{"id", "name", "cron_expr", "timezone", "options_json", "overlap", "enabled", "submitter", "created_at", "updated_at", "next_run_at", "last_run_at"}
+
+// expectDue 設定一次到期查詢，回傳排定於 at 的排程 s1 並推進下次執行時間。
+func expectDue(mock sqlmock.Sqlmock, overlap string, at time.Time) {
+	mock.ExpectQuery(dueSQL).WillReturnRows(sqlmock.NewRows(scheduleCols).
+		AddRow("s1", "nightly", "* * * * *", "UTC", `{"image":"alpine","cmd":["make"]}`, overlap, true, "alice", 1, 1, at.Unix(), nil))
+	mock.ExpectExec(advanceSQL).WithArgs(sqlmock.AnyArg(), at.Unix(), "s1").WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// blockingRun 第一次執行等待 release 或被取消，之後的執行立即成功。
+func blockingRun(started chan<- string, release <-chan struct{}) RunFunc {
+	calls := 0
+	return func(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error) {
+		calls++
+		id := "job" + string(rune('0'+calls))
+		started <- id
+		if calls == 1 {
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return id, containers.JobResult{}, ctx.Err()
+			}
+		}
+		return id, containers.JobResult{Status: containers.JobSucceeded}, nil
+	}
+}
+
+func newTestScheduler(t *testing.T, run RunFunc) (*Scheduler, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	s := NewScheduler(storage.NewScheduleRepository(db), run)
+	now := time.Date(2024, 3, 1, 12, 0, 30, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	return s, mock
+}
+
+func TestScheduler_SkipWhileRunning(t *testing.T) {
+	started, release := make(chan string, 2), make(chan struct{})
+	s, mock := newTestScheduler(t, blockingRun(started, release))
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	expectDue(mock, "skip", t0)
+	mock.ExpectExec(insertRunSQL).WithArgs(sqlmock.AnyArg(), "s1", "running", "", t0.Unix(), sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.RunDue(ctx)
+	<-started
+
+	// 上一次仍在執行：略過並記錄
+	expectDue(mock, "skip", t0.Add(time.Minute))
+	mock.ExpectExec(insertRunSQL).WithArgs(sqlmock.AnyArg(), "s1", "skipped", "previous run still in progress", t0.Add(time.Minute).Unix(), nil, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.RunDue(ctx)
+
+	mock.ExpectExec(finishRunSQL).WithArgs("succeeded", "job1", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	close(release)
+	s.Close()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestScheduler_QueueRunsAfterPrevious(t *testing.T) {
+	started, release := make(chan string, 2), make(chan struct{})
+	s, mock := newTestScheduler(t, blockingRun(started, release))
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	expectDue(mock, "queue", t0)
+	mock.ExpectExec(insertRunSQL).WithArgs(sqlmock.AnyArg(), "s1", "running", "", t0.Unix(), sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.RunDue(ctx)
+	<-started
+
+	expectDue(mock, "queue", t0.Add(time.Minute))
+	mock.ExpectExec(insertRunSQL).WithArgs(sqlmock.AnyArg(), "s1", "queued", "", t0.Add(time.Minute).Unix(), nil, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.RunDue(ctx)
+	// 已有一次排隊：之後的觸發略過
+	expectDue(mock, "queue", t0.Add(2*time.Minute))
+	mock.ExpectExec(insertRunSQL).WithArgs(sqlmock.AnyArg(), "s1", "skipped", "a run is already queued", t0.Add(2*time.Minute).Unix(), nil, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.RunDue(ctx)
+
+	// 第一次結束後才開始排隊的觸發，兩者不重疊
+	mock.ExpectExec(finishRunSQL).WithArgs("succeeded", "job1", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(startRunSQL).WithArgs("running", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(finishRunSQL).WithArgs("succeeded", "job2", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	close(release)
+	if id := <-started; id != "job2" {
+		t.Fatalf("second run=%s", id)
+	}
+	s.Close()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestScheduler_ReplaceCancelsPrevious(t *testing.T) {
+	started := make(chan string, 2)
+	s, mock := newTestScheduler(t, blockingRun(started, make(chan struct{})))
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	expectDue(mock, "replace", t0)
+	mock.ExpectExec(insertRunSQL).WithArgs(sqlmock.AnyArg(), "s1", "running", "", t0.Unix(), sqlmock.AnyArg(), nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	s.RunDue(ctx)
+	<-started
+
+	expectDue(mock, "replace", t0.Add(time.Minute))
+	mock.ExpectExec(insertRunSQL).WithArgs(sqlmock.AnyArg(), "s1", "queued", "", t0.Add(time.Minute).Unix(), nil, nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(finishRunSQL).WithArgs("cancelled", "job1", "replaced by a newer run", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(startRunSQL).WithArgs("running", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(finishRunSQL).WithArgs("succeeded", "job2", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+	s.RunDue(ctx)
+	if id := <-started; id != "job2" {
+		t.Fatalf("replacement run=%s", id)
+	}
+	s.Close()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -1,16 +1,24 @@
 package server
 
 import (
+	"context"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"container-manager/internal/api/handlers"
+	"container-manager/internal/detect"
 	"container-manager/internal/middleware"
 )
 
 func Run(addr string) error {
 	engine := gin.New()
+	engine.Use(middleware.RequestID())
 	engine.Use(middleware.Logger())
 	engine.Use(middleware.Recover())
 	engine.Use(middleware.Cors())
@@ -27,11 +35,37 @@ func Run(addr string) error {
 	{
 		v1.POST("/uploads", handlers.Upload)
 		v1.POST("/containers", handlers.CreateContainer)
+		v1.GET("/containers", handlers.ListContainers)
+		v1.GET("/containers/history", handlers.ContainerHistory)
+		v1.GET("/containers/:id", handlers.GetContainer)
 		v1.POST("/containers/:id/start", handlers.StartContainer)
 		v1.POST("/containers/:id/stop", handlers.StopContainer)
 		v1.POST("/containers/:id/exec", handlers.ExecInContainer)
+		v1.GET("/containers/:id/logs", handlers.ContainerLogs)
+		v1.GET("/containers/:id/attach", handlers.AttachContainer)
 		v1.DELETE("/containers/:id", handlers.DeleteContainer)
 		v1.POST("/jobs", handlers.RunJob)
+		v1.POST("/jobs/detect", handlers.DetectJob)
+		v1.GET("/jobs", handlers.ListJobs)
+		v1.GET("/jobs/:id", handlers.GetJob)
+		v1.POST("/schedules", handlers.CreateSchedule)
+		v1.GET("/schedules", handlers.ListSchedules)
+		v1.GET("/schedules/:id", handlers.GetSchedule)
+		v1.PUT("/schedules/:id", handlers.UpdateSchedule)
+		v1.DELETE("/schedules/:id", handlers.DeleteSchedule)
+		v1.GET("/schedules/:id/runs", handlers.ListScheduleRuns)
+		v1.POST("/pipelines", handlers.RunPipeline)
+		v1.GET("/pipelines", handlers.ListPipelines)
+		v1.GET("/pipelines/:id", handlers.GetPipeline)
+		v1.POST("/images/build", handlers.BuildImage)
+		v1.POST("/images/pull", handlers.PullImage)
+		v1.POST("/images/prune", handlers.PruneImages)
+		v1.GET("/images", handlers.ListImages)
+		v1.GET("/images/*ref", handlers.GetImage)
+		v1.DELETE("/images/*ref", handlers.DeleteImage)
+		v1.GET("/tasks", handlers.ListTasks)
+		v1.GET("/tasks/:id", handlers.GetTask)
+		v1.POST("/tasks/:id/cancel", handlers.CancelTask)
 	}
 
 	// 靜態檔案（上傳存放根目錄由 DATA_DIR 控制）
@@ -42,5 +76,40 @@ func Run(addr string) error {
 	_ = os.MkdirAll(dataDir, 0o755)
 	engine.Static("/static", dataDir)
 
-	return engine.Run(addr)
+	// 作業程式的偵測規則；設定檔有誤時不啟動
+	if file := os.Getenv("DETECTORS_FILE"); file != "" {
+		reg, err := detect.Load(file)
+		if err != nil {
+			return err
+		}
+		handlers.Detectors = reg
+	}
+
+	// 收到 SIGINT/SIGTERM 時取消所有請求的 ctx，讓進行中的映像拉取、exec 與作業等待盡快結束
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	srv := &http.Server{
+		Addr:        addr,
+		Handler:     engine,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	// 排隊作業的 worker；關閉時中斷的作業放回佇列
+	handlers.Jobs.Start(ctx)
+	defer handlers.Jobs.Close()
+	// 排程迴圈；多個實例時只有取得 lock 的一個觸發排程
+	handlers.Schedules.Start(ctx)
+	defer handlers.Schedules.Close()
+	// 管線在本實例中執行；關閉時中斷的管線記錄為失敗
+	handlers.Pipelines.Start(ctx)
+	defer handlers.Pipelines.Close()
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServe() }()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
 }
@@ -1,9 +1,21 @@
 package storage
 
 import (
+	"context"
 	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 )
 
+// ErrNotFound 查無資料列。
+var ErrNotFound = errors.New("record not found")
+
+// ErrInvalidCursor 分頁游標格式錯誤。
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type ContainerRecord struct {
 	ID        string
 	Name      string
@@ -12,16 +24,151 @@ type ContainerRecord struct {
 	CreatedAt int64
 }
 
+// ContainerFilter 查詢 containers 表的條件；零值欄位代表不篩選。
+type ContainerFilter struct {
+	Status        string
+	Image         string
+	NamePrefix    string
+	CreatedAfter  int64  // 含，unix 秒
+	CreatedBefore int64  // 不含，unix 秒
+	Ascending     bool   // 預設依 created_at 由新到舊
+	Cursor        string // 上一頁回傳的 nextCursor
+	Limit         int
+}
+
 type ContainerRepository struct{ db *sql.DB }
 
 func NewContainerRepository(db *sql.DB) *ContainerRepository { return &ContainerRepository{db: db} }
 
-func (r *ContainerRepository) Create(rec ContainerRecord) error {
-    _, err := r.db.Exec(`INSERT INTO containers(id,name,image,status,created_at) VALUES($1,$2,$3,$4,$5)`, rec.ID, rec.Name, rec.Image, rec.Status, rec.CreatedAt)
+func (r *ContainerRepository) Create(ctx context.Context, rec ContainerRecord) error {
+    _, err := r.db.ExecContext(ctx, `INSERT INTO containers(id,name,image,status,created_at) VALUES($1,$2,$3,$4,$5)`, rec.ID, rec.Name, rec.Image, rec.Status, rec.CreatedAt)
 	return err
 }
 
-func (r *ContainerRepository) UpdateStatus(id, status string) error {
-    _, err := r.db.Exec(`UPDATE containers SET status=$1 WHERE id=$2`, status, id)
+func (r *ContainerRepository) UpdateStatus(ctx context.Context, id, status string) error {
+    _, err := r.db.ExecContext(ctx, `UPDATE containers SET status=$1 WHERE id=$2`, status, id)
 	return err
 }
+
+func (r *ContainerRepository) Get(ctx context.Context, id string) (ContainerRecord, error) {
+	var rec ContainerRecord
+	err := r.db.QueryRowContext(ctx, `SELECT id,name,image,status,created_at FROM containers WHERE id=$1`, id).
+		Scan(&rec.ID, &rec.Name, &rec.Image, &rec.Status, &rec.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ContainerRecord{}, ErrNotFound
+	}
+	return rec, err
+}
+
+// List 以 (created_at, id) 做 keyset 分頁；回傳的 nextCursor 為空字串代表沒有下一頁。
+func (r *ContainerRepository) List(ctx context.Context, f ContainerFilter) ([]ContainerRecord, string, error) {
+	where, args := f.where()
+	order := "DESC"
+	cmp := "<"
+	if f.Ascending {
+		order, cmp = "ASC", ">"
+	}
+	if f.Cursor != "" {
+		createdAt, id, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, createdAt, id)
+		where = append(where, fmt.Sprintf("(created_at,id) %s ($%d,$%d)", cmp, len(args)-1, len(args)))
+	}
+	q := `SELECT id,name,image,status,created_at FROM containers` + joinWhere(where) +
+		fmt.Sprintf(` ORDER BY created_at %s, id %s`, order, order)
+	if f.Limit > 0 {
+		// 多取一筆以判斷是否還有下一頁
+		args = append(args, f.Limit+1)
+		q += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	out := []ContainerRecord{}
+	for rows.Next() {
+		var rec ContainerRecord
+		if err := rows.Scan(&rec.ID, &rec.Name, &rec.Image, &rec.Status, &rec.CreatedAt); err != nil {
+			return nil, "", err
+		}
+		out = append(out, rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+		last := out[len(out)-1]
+		next = encodeCursor(last.CreatedAt, last.ID)
+	}
+	return out, next, nil
+}
+
+// Count 回傳符合條件的總筆數（忽略 Cursor 與 Limit）。
+func (r *ContainerRepository) Count(ctx context.Context, f ContainerFilter) (int, error) {
+	where, args := f.where()
+	var n int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM containers`+joinWhere(where), args...).Scan(&n)
+	return n, err
+}
+
+func (f ContainerFilter) where() ([]string, []any) {
+	var where []string
+	var args []any
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Status != "" {
+		add("status=$%d", f.Status)
+	}
+	if f.Image != "" {
+		add("image=$%d", f.Image)
+	}
+	if f.NamePrefix != "" {
+		add("name LIKE $%d", escapeLike(f.NamePrefix)+"%")
+	}
+	if f.CreatedAfter > 0 {
+		add("created_at>=$%d", f.CreatedAfter)
+	}
+	if f.CreatedBefore > 0 {
+		add("created_at<$%d", f.CreatedBefore)
+	}
+	return where, args
+}
+
+func joinWhere(where []string) string {
+	if len(where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(where, " AND ")
+}
+
+// escapeLike 跳脫 LIKE 的萬用字元（PostgreSQL 預設跳脫字元為反斜線）。
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func encodeCursor(createdAt int64, id string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt, 10) + ":" + id))
+}
+
+func decodeCursor(cursor string) (int64, string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return 0, "", ErrInvalidCursor
+	}
+	createdAt, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	return createdAt, id, nil
+}
@@ -40,6 +40,101 @@ CREATE TABLE IF NOT EXISTS container_tasks (
     created_at BIGINT,
     finished_at BIGINT
 );
+ALTER TABLE container_tasks ADD COLUMN IF NOT EXISTS stdout TEXT;
+ALTER TABLE container_tasks ADD COLUMN IF NOT EXISTS stderr TEXT;
+CREATE TABLE IF NOT EXISTS jobs (
+    id TEXT PRIMARY KEY,
+    submitter TEXT,
+    image TEXT,
+    cmd_json TEXT,
+    host_dir TEXT,
+    container_dir TEXT,
+    status TEXT,
+    exit_code INT,
+    logs TEXT,
+    error TEXT,
+    created_at BIGINT,
+    started_at BIGINT,
+    finished_at BIGINT
+);
+CREATE INDEX IF NOT EXISTS jobs_created_at_idx ON jobs (created_at DESC, id DESC);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS options_json TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS mode TEXT NOT NULL DEFAULT 'sync';
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS owner TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS heartbeat_at BIGINT;
+CREATE INDEX IF NOT EXISTS jobs_status_idx ON jobs (status, created_at);
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS parent_id TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS matrix_index INT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS matrix_values TEXT;
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS max_parallel INT;
+CREATE INDEX IF NOT EXISTS jobs_parent_idx ON jobs (parent_id, matrix_index);
+CREATE TABLE IF NOT EXISTS job_attempts (
+    id TEXT PRIMARY KEY,
+    job_id TEXT NOT NULL,
+    attempt INT NOT NULL,
+    status TEXT,
+    exit_code INT,
+    oom_killed BOOLEAN,
+    logs TEXT,
+    error TEXT,
+    started_at BIGINT,
+    finished_at BIGINT,
+    UNIQUE (job_id, attempt)
+);
+CREATE TABLE IF NOT EXISTS schedules (
+    id TEXT PRIMARY KEY,
+    name TEXT,
+    cron_expr TEXT NOT NULL,
+    timezone TEXT,
+    options_json TEXT,
+    overlap TEXT,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    submitter TEXT,
+    created_at BIGINT,
+    updated_at BIGINT,
+    next_run_at BIGINT,
+    last_run_at BIGINT
+);
+CREATE TABLE IF NOT EXISTS schedule_runs (
+    id TEXT PRIMARY KEY,
+    schedule_id TEXT NOT NULL,
+    job_id TEXT,
+    status TEXT,
+    error TEXT,
+    scheduled_at BIGINT,
+    started_at BIGINT,
+    finished_at BIGINT
+);
+CREATE INDEX IF NOT EXISTS schedule_runs_schedule_idx ON schedule_runs (schedule_id, scheduled_at DESC);
+CREATE TABLE IF NOT EXISTS pipeline_runs (
+    id TEXT PRIMARY KEY,
+    name TEXT,
+    submitter TEXT,
+    host_dir TEXT,
+    container_dir TEXT,
+    on_failure TEXT,
+    status TEXT,
+    error TEXT,
+    created_at BIGINT,
+    started_at BIGINT,
+    finished_at BIGINT,
+    heartbeat_at BIGINT
+);
+CREATE INDEX IF NOT EXISTS pipeline_runs_created_at_idx ON pipeline_runs (created_at DESC, id DESC);
+CREATE TABLE IF NOT EXISTS pipeline_steps (
+    run_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    position INT NOT NULL,
+    depends_on_json TEXT,
+    options_json TEXT,
+    status TEXT,
+    job_id TEXT,
+    exit_code INT,
+    error TEXT,
+    started_at BIGINT,
+    finished_at BIGINT,
+    PRIMARY KEY (run_id, name)
+);
 `)
 	return err
 }
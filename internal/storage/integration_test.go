@@ -64,6 +64,7 @@ func withPostgres(t *testing.T) (*sql.DB, func()) {
 }
 
 func TestRepository_WithRealPostgres(t *testing.T) {
+	ctx := context.Background()
 	db, cleanup := withPostgres(t)
 	defer cleanup()
 
@@ -72,10 +73,347 @@ func TestRepository_WithRealPostgres(t *testing.T) {
 	}
 	repo := NewContainerRepository(db)
 	rec := ContainerRecord{ID: "id1", Name: "n1", Image: "alpine", Status: "created", CreatedAt: time.Now().Unix()}
-	if err := repo.Create(rec); err != nil {
+	if err := repo.Create(ctx, rec); err != nil {
 		t.Fatalf("create: %v", err)
 	}
-	if err := repo.UpdateStatus("id1", "running"); err != nil {
+	if err := repo.UpdateStatus(ctx, "id1", "running"); err != nil {
 		t.Fatalf("update: %v", err)
 	}
 }
+
+func TestRepository_ReadSide_WithRealPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := withPostgres(t)
+	defer cleanup()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewContainerRepository(db)
+	for i, st := range []string{"running", "deleted", "deleted", "deleted"} {
+		rec := ContainerRecord{ID: fmt.Sprintf("id%d", i), Name: fmt.Sprintf("job_%d", i), Image: "alpine", Status: st, CreatedAt: int64(100 + i)}
+		if err := repo.Create(ctx, rec); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	got, err := repo.Get(ctx, "id1")
+	if err != nil || got.Status != "deleted" {
+		t.Fatalf("get: %+v %v", got, err)
+	}
+	if _, err := repo.Get(ctx, "nope"); err != ErrNotFound {
+		t.Fatalf("get missing: %v", err)
+	}
+
+	f := ContainerFilter{Status: "deleted", NamePrefix: "job_", Limit: 2}
+	page1, next, err := repo.List(ctx, f)
+	if err != nil || len(page1) != 2 || next == "" || page1[0].ID != "id3" {
+		t.Fatalf("page1: %+v next=%q err=%v", page1, next, err)
+	}
+	f.Cursor = next
+	page2, next, err := repo.List(ctx, f)
+	if err != nil || len(page2) != 1 || next != "" || page2[0].ID != "id1" {
+		t.Fatalf("page2: %+v next=%q err=%v", page2, next, err)
+	}
+	n, err := repo.Count(ctx, ContainerFilter{CreatedAfter: 101, CreatedBefore: 103})
+	if err != nil || n != 2 {
+		t.Fatalf("count: %d %v", n, err)
+	}
+}
+
+func TestJobRepository_WithRealPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := withPostgres(t)
+	defer cleanup()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewJobRepository(db)
+	id, err := repo.Insert(ctx, JobRecord{Submitter: "alice", Image: "alpine", Cmd: []string{"sh", "-c", "exit 3"}, HostDir: "/data/a", ContainerDir: "/w", Status: JobRunning})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := repo.Finish(ctx, id, JobFailed, 3, "boom\n", ""); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	got, err := repo.Get(ctx, id)
+	if err != nil || got.Status != JobFailed || got.ExitCode != 3 || got.Cmd[2] != "exit 3" || !got.StartedAt.Valid || !got.FinishedAt.Valid {
+		t.Fatalf("get: %+v %v", got, err)
+	}
+	if _, err := repo.Get(ctx, "nope"); err != ErrNotFound {
+		t.Fatalf("get missing: %v", err)
+	}
+	list, next, err := repo.List(ctx, JobFilter{Submitter: "alice", Limit: 10})
+	if err != nil || len(list) != 1 || next != "" {
+		t.Fatalf("list: %+v next=%q err=%v", list, next, err)
+	}
+	// 執行次數依序累計
+	for i, oom := range []bool{true, false} {
+		aid, err := repo.StartAttempt(ctx, id)
+		if err != nil {
+			t.Fatalf("start attempt %d: %v", i+1, err)
+		}
+		if err := repo.FinishAttempt(ctx, aid, JobFailed, 137, oom, "", ""); err != nil {
+			t.Fatalf("finish attempt %d: %v", i+1, err)
+		}
+	}
+	attempts, err := repo.Attempts(ctx, id)
+	if err != nil || len(attempts) != 2 || attempts[1].Attempt != 2 || !attempts[0].OOMKilled || !attempts[1].FinishedAt.Valid {
+		t.Fatalf("attempts: %+v %v", attempts, err)
+	}
+}
+
+func TestJobRepository_Claim_WithRealPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := withPostgres(t)
+	defer cleanup()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewJobRepository(db)
+	for _, sub := range []string{"alice", "alice", "bob"} {
+		if _, err := repo.Insert(ctx, JobRecord{Submitter: sub, Image: "alpine", Mode: JobQueued, Status: JobPending}); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	// alice 已有一個執行中作業，第二次領取應略過她的作業改領 bob 的
+	lim := ClaimLimits{MaxRunning: 2, MaxPerUser: 1}
+	first, ok, err := repo.Claim(ctx, "w1", lim)
+	if err != nil || !ok || first.Submitter != "alice" {
+		t.Fatalf("first claim: %+v %v %v", first, ok, err)
+	}
+	second, ok, err := repo.Claim(ctx, "w1", lim)
+	if err != nil || !ok || second.Submitter != "bob" {
+		t.Fatalf("second claim: %+v %v %v", second, ok, err)
+	}
+	if _, ok, err := repo.Claim(ctx, "w1", ClaimLimits{MaxRunning: 2}); err != nil || ok {
+		t.Fatalf("global limit not enforced: %v %v", ok, err)
+	}
+
+	// owner 心跳逾時：排隊作業回到 pending，可再次被領取
+	if _, err := db.ExecContext(ctx, `UPDATE jobs SET heartbeat_at=1 WHERE id=$1`, first.ID); err != nil {
+		t.Fatalf("age heartbeat: %v", err)
+	}
+	if n, err := repo.RecoverStale(ctx, time.Now().Add(-time.Minute)); err != nil || n != 1 {
+		t.Fatalf("recover: %d %v", n, err)
+	}
+	if got, _ := repo.Get(ctx, first.ID); got.Status != JobPending {
+		t.Fatalf("recovered status = %s", got.Status)
+	}
+	if err := repo.Finish(ctx, second.ID, JobSucceeded, 0, "", ""); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	third, ok, err := repo.Claim(ctx, "w2", lim)
+	if err != nil || !ok || third.Submitter != "alice" || third.Owner != "w2" {
+		t.Fatalf("third claim: %+v %v %v", third, ok, err)
+	}
+
+	// 不經佇列執行的作業同樣受上限約束
+	if _, ok, err := repo.InsertRunning(ctx, JobRecord{Submitter: "alice", Image: "alpine", Owner: "w1"}, lim); err != nil || ok {
+		t.Fatalf("per-user limit not enforced for direct run: %v %v", ok, err)
+	}
+	id, ok, err := repo.InsertRunning(ctx, JobRecord{Submitter: "bob", Image: "alpine", Owner: "w1"}, lim)
+	if err != nil || !ok {
+		t.Fatalf("direct run for bob: %v %v", ok, err)
+	}
+	if got, _ := repo.Get(ctx, id); got.Status != JobRunning || got.Mode != JobSync {
+		t.Fatalf("direct run record: %+v", got)
+	}
+}
+
+func TestJobRepository_Matrix_WithRealPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := withPostgres(t)
+	defer cleanup()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewJobRepository(db)
+	children := []MatrixChild{
+		{Index: 0, Values: map[string]string{"lr": "0.1"}, Job: JobRecord{Image: "alpine", OptionsJSON: `{"image":"alpine"}`}},
+		{Index: 1, Values: map[string]string{"lr": "0.01"}, Job: JobRecord{Image: "alpine", OptionsJSON: `{"image":"alpine"}`}},
+	}
+	parentID, ids, err := repo.InsertMatrix(ctx, JobRecord{Submitter: "alice", Image: "alpine"}, 1, children)
+	if err != nil || len(ids) != 2 {
+		t.Fatalf("insert matrix: %v %v", ids, err)
+	}
+	// 父紀錄不執行也沒有心跳，不被當成中斷的作業處理
+	if n, err := repo.RecoverStale(ctx, time.Now().Add(time.Minute)); err != nil || n != 0 {
+		t.Fatalf("recover: %d %v", n, err)
+	}
+
+	// max_parallel=1：第一個子作業執行中時不領取第二個
+	first, ok, err := repo.Claim(ctx, "w1", ClaimLimits{})
+	if err != nil || !ok {
+		t.Fatalf("first claim: %v %v", ok, err)
+	}
+	if _, ok, err := repo.Claim(ctx, "w1", ClaimLimits{}); err != nil || ok {
+		t.Fatalf("max parallel not enforced: %v %v", ok, err)
+	}
+	if err := repo.Finish(ctx, first.ID, JobSucceeded, 0, "", ""); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if err := repo.FinishParent(ctx, first.ID); err != nil {
+		t.Fatalf("finish parent: %v", err)
+	}
+	if p, _ := repo.Get(ctx, parentID); p.Status != JobRunning {
+		t.Fatalf("parent finished early: %s", p.Status)
+	}
+	second, ok, err := repo.Claim(ctx, "w1", ClaimLimits{})
+	if err != nil || !ok {
+		t.Fatalf("second claim: %v %v", ok, err)
+	}
+	_ = repo.Finish(ctx, second.ID, JobFailed, 3, "", "")
+	if err := repo.FinishParent(ctx, second.ID); err != nil {
+		t.Fatalf("finish parent: %v", err)
+	}
+	p, _ := repo.Get(ctx, parentID)
+	if p.Status != JobFailed || p.ExitCode != 3 || !p.FinishedAt.Valid || p.Mode != JobMatrix {
+		t.Fatalf("parent: %+v", p)
+	}
+	list, err := repo.MatrixChildren(ctx, parentID)
+	if err != nil || len(list) != 2 || list[1].Values["lr"] != "0.01" || list[1].Job.ExitCode != 3 {
+		t.Fatalf("children: %+v %v", list, err)
+	}
+}
+
+func TestJobRepository_MatrixLimits_WithRealPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := withPostgres(t)
+	defer cleanup()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewJobRepository(db)
+	children := []MatrixChild{
+		{Index: 0, Values: map[string]string{"n": "1"}, Job: JobRecord{Image: "alpine", OptionsJSON: `{"image":"alpine"}`}},
+		{Index: 1, Values: map[string]string{"n": "2"}, Job: JobRecord{Image: "alpine", OptionsJSON: `{"image":"alpine"}`}},
+	}
+	parentID, _, err := repo.InsertMatrix(ctx, JobRecord{Submitter: "alice", Image: "alpine"}, 2, children)
+	if err != nil {
+		t.Fatalf("insert matrix: %v", err)
+	}
+
+	// 父紀錄為 running 但不佔用全域與每位使用者的上限，子作業仍可逐一領取
+	lim := ClaimLimits{MaxRunning: 1, MaxPerUser: 1}
+	for i := range children {
+		child, ok, err := repo.Claim(ctx, "w1", lim)
+		if err != nil || !ok {
+			t.Fatalf("claim child %d: %v %v", i, ok, err)
+		}
+		if _, ok, err := repo.Claim(ctx, "w1", lim); err != nil || ok {
+			t.Fatalf("limit not enforced while child %d runs: %v %v", i, ok, err)
+		}
+		if err := repo.Finish(ctx, child.ID, JobSucceeded, 0, "", ""); err != nil {
+			t.Fatalf("finish: %v", err)
+		}
+		if err := repo.FinishParent(ctx, child.ID); err != nil {
+			t.Fatalf("finish parent: %v", err)
+		}
+	}
+	if p, _ := repo.Get(ctx, parentID); p.Status != JobSucceeded {
+		t.Fatalf("parent status = %s", p.Status)
+	}
+}
+
+func TestScheduleRepository_WithRealPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := withPostgres(t)
+	defer cleanup()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewScheduleRepository(db)
+	due := time.Now().Add(-time.Minute).Truncate(time.Second)
+	id, err := repo.Insert(ctx, ScheduleRecord{CronExpr: "* * * * *", Timezone: "UTC", OptionsJSON: `{"image":"alpine"}`, Overlap: "skip", Enabled: true, NextRunAt: sql.NullTime{Time: due, Valid: true}})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	list, err := repo.Due(ctx, time.Now())
+	if err != nil || len(list) != 1 || list[0].ID != id || !list[0].NextRunAt.Time.Equal(due) {
+		t.Fatalf("due: %+v %v", list, err)
+	}
+	if err := repo.Advance(ctx, id, due.Add(time.Hour), due); err != nil {
+		t.Fatalf("advance: %v", err)
+	}
+	if list, _ := repo.Due(ctx, time.Now()); len(list) != 0 {
+		t.Fatalf("still due after advance: %+v", list)
+	}
+
+	runID, err := repo.InsertRun(ctx, ScheduleRun{ScheduleID: id, Status: RunRunning, ScheduledAt: due})
+	if err != nil {
+		t.Fatalf("insert run: %v", err)
+	}
+	if err := repo.FinishRun(ctx, runID, ScheduleRunStatus(JobSucceeded), "j1", ""); err != nil {
+		t.Fatalf("finish run: %v", err)
+	}
+	runs, err := repo.ListRuns(ctx, id, 10)
+	if err != nil || len(runs) != 1 || runs[0].JobID != "j1" || !runs[0].StartedAt.Valid || !runs[0].FinishedAt.Valid {
+		t.Fatalf("runs: %+v %v", runs, err)
+	}
+
+	// 只有一個連線能持有排程迴圈的 lock
+	conn, ok, err := repo.TryLeaderLock(ctx)
+	if err != nil || !ok {
+		t.Fatalf("leader lock: ok=%v err=%v", ok, err)
+	}
+	if _, ok, _ := repo.TryLeaderLock(ctx); ok {
+		t.Fatalf("second leader lock acquired")
+	}
+	repo.ReleaseLeaderLock(conn)
+
+	if err := repo.Delete(ctx, id); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := repo.Delete(ctx, id); err != ErrNotFound {
+		t.Fatalf("delete missing: %v", err)
+	}
+}
+
+func TestPipelineRepository_WithRealPostgres(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := withPostgres(t)
+	defer cleanup()
+
+	if err := Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	repo := NewPipelineRepository(db)
+	id, err := repo.Insert(ctx, PipelineRun{Submitter: "alice", HostDir: "/srv/u1", ContainerDir: "/workspace", OnFailure: "fail-fast", Steps: []PipelineStep{
+		{Name: "build", OptionsJSON: `{"image":"alpine"}`},
+		{Name: "test", DependsOn: []string{"build"}, OptionsJSON: `{"image":"alpine"}`},
+	}})
+	if err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+	if err := repo.StartStep(ctx, id, "build"); err != nil {
+		t.Fatalf("start step: %v", err)
+	}
+	if err := repo.FinishStep(ctx, id, "build", PipelineSucceeded, "j1", 0, ""); err != nil {
+		t.Fatalf("finish step: %v", err)
+	}
+	p, err := repo.Get(ctx, id)
+	if err != nil || p.Status != PipelineRunning || len(p.Steps) != 2 || p.Steps[0].JobID != "j1" || p.Steps[1].DependsOn[0] != "build" || p.Steps[1].Status != PipelinePending {
+		t.Fatalf("get: %+v %v", p, err)
+	}
+
+	// 心跳逾時的執行由 RecoverStale 標記為失敗
+	n, err := repo.RecoverStale(ctx, time.Now().Add(time.Minute))
+	if err != nil || n != 1 {
+		t.Fatalf("recover: n=%d err=%v", n, err)
+	}
+	p, _ = repo.Get(ctx, id)
+	if p.Status != PipelineFailed || p.Steps[0].Status != PipelineSucceeded || p.Steps[1].Status != PipelineCancelled {
+		t.Fatalf("after recover: %+v", p)
+	}
+	list, next, err := repo.List(ctx, PipelineFilter{Submitter: "alice", Limit: 10})
+	if err != nil || len(list) != 1 || next != "" || list[0].ID != id {
+		t.Fatalf("list: %+v %q %v", list, next, err)
+	}
+}
@@ -0,0 +1,460 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobTimedOut  JobStatus = "timed_out"
+)
+
+// Finished 回傳狀態是否為終止狀態。
+func (s JobStatus) Finished() bool {
+	return s == JobSucceeded || s == JobFailed || s == JobTimedOut
+}
+
+// JobMode 作業的執行方式。
+type JobMode string
+
+const (
+	JobSync   JobMode = "sync"   // 在 HTTP 請求中執行
+	JobQueued JobMode = "queued" // 由 worker 從資料庫領取執行
+	JobMatrix JobMode = "matrix" // 矩陣作業的父紀錄，本身不執行；狀態彙整自子作業
+)
+
+// jobClaimLock 序列化各實例領取作業的 advisory lock 鍵值，
+// 確保全域與每位使用者的並行上限在多個實例間計數一致。
+const jobClaimLock int64 = 0x6a6f6273 // "jobs"
+
+// JobRecord 一次性作業（/v1/jobs）的執行紀錄。
+type JobRecord struct {
+	ID           string
+	Submitter    string // JWT 的 sub；未經驗證的呼叫為空字串
+	Image        string
+	Cmd          []string
+	HostDir      string
+	ContainerDir string
+	OptionsJSON  string // 排隊作業的完整執行參數，由 worker 還原
+	Mode         JobMode
+	Owner        string // 執行中作業所屬的 worker 實例
+	Status       JobStatus
+	ExitCode     int64
+	Logs         string
+	Error        string // 無法執行時的錯誤訊息
+	CreatedAt    time.Time
+	StartedAt    sql.NullTime
+	FinishedAt   sql.NullTime
+}
+
+// JobFilter 查詢 jobs 表的條件；零值欄位代表不篩選。
+type JobFilter struct {
+	Submitter string
+	Status    string
+	Image     string
+	Parent    string // 矩陣作業的父作業 ID
+	Cursor    string // 上一頁回傳的 nextCursor
+	Limit     int
+}
+
+type JobRepository struct{ db *sql.DB }
+
+func NewJobRepository(db *sql.DB) *JobRepository { return &JobRepository{db: db} }
+
+// Insert 新增作業紀錄並回傳 ID；Status 為 running 時同時記錄開始與心跳時間。
+func (r *JobRepository) Insert(ctx context.Context, j JobRecord) (string, error) {
+	return insertJob(ctx, r.db, j)
+}
+
+// InsertRunning 在未超過並行上限時新增 owner 執行中的作業並回傳 ID；已達上限時回傳 false。
+// 與 Claim 使用同一把 advisory lock，讓不經佇列執行的作業（例如排程觸發）同樣受上限約束。
+func (r *JobRepository) InsertRunning(ctx context.Context, j JobRecord, lim ClaimLimits) (string, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, jobClaimLock); err != nil {
+		return "", false, err
+	}
+	if lim.MaxRunning > 0 {
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE status=$1 AND mode<>$2`, string(JobRunning), string(JobMatrix)).Scan(&n); err != nil {
+			return "", false, err
+		}
+		if n >= lim.MaxRunning {
+			return "", false, nil
+		}
+	}
+	if lim.MaxPerUser > 0 {
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE status=$1 AND mode<>$2 AND submitter=$3`, string(JobRunning), string(JobMatrix), j.Submitter).Scan(&n); err != nil {
+			return "", false, err
+		}
+		if n >= lim.MaxPerUser {
+			return "", false, nil
+		}
+	}
+	j.Status = JobRunning
+	id, err := insertJob(ctx, tx, j)
+	if err != nil {
+		return "", false, err
+	}
+	return id, true, tx.Commit()
+}
+
+// execer 為 *sql.DB 與 *sql.Tx 共用的寫入方法。
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func insertJob(ctx context.Context, db execer, j JobRecord) (string, error) {
+	b, _ := json.Marshal(j.Cmd)
+	id := uuid.NewString()
+	now := time.Now().Unix()
+	var startedAt sql.NullInt64
+	if j.Status == JobRunning {
+		startedAt = sql.NullInt64{Int64: now, Valid: true}
+	}
+	if j.Mode == "" {
+		j.Mode = JobSync
+	}
+	_, err := db.ExecContext(ctx, `INSERT INTO jobs(id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, owner, status, created_at, started_at, heartbeat_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$12)`,
+		id, j.Submitter, j.Image, string(b), j.HostDir, j.ContainerDir, j.OptionsJSON, string(j.Mode), nullString(j.Owner), string(j.Status), now, startedAt)
+	return id, err
+}
+
+// ClaimLimits 領取作業時的並行上限；零值代表不限制。
+type ClaimLimits struct {
+	MaxRunning int // 所有實例合計的執行中作業數
+	MaxPerUser int // 每位 submitter 的執行中作業數
+}
+
+// Claim 以 SELECT ... FOR UPDATE SKIP LOCKED 領取最早的 pending 作業並標記為 owner 執行中；
+// 沒有可領取的作業或已達上限時回傳 false。矩陣的父紀錄本身不執行，不計入上限。
+func (r *JobRepository) Claim(ctx context.Context, owner string, lim ClaimLimits) (JobRecord, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return JobRecord{}, false, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, jobClaimLock); err != nil {
+		return JobRecord{}, false, err
+	}
+	if lim.MaxRunning > 0 {
+		var n int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM jobs WHERE status=$1 AND mode<>$2`, string(JobRunning), string(JobMatrix)).Scan(&n); err != nil {
+			return JobRecord{}, false, err
+		}
+		if n >= lim.MaxRunning {
+			return JobRecord{}, false, nil
+		}
+	}
+	q := `SELECT ` + jobColumns + ` FROM jobs j WHERE status=$1`
+	args := []any{string(JobPending)}
+	if lim.MaxPerUser > 0 {
+		q += ` AND (SELECT COUNT(*) FROM jobs r WHERE r.status=$2 AND r.mode<>$3 AND r.submitter=j.submitter) < $4`
+		args = append(args, string(JobRunning), string(JobMatrix), lim.MaxPerUser)
+	}
+	// 矩陣的子作業另受父作業的 max_parallel 限制
+	args = append(args, string(JobRunning))
+	q += fmt.Sprintf(` AND (j.parent_id IS NULL OR (SELECT COUNT(*) FROM jobs s WHERE s.parent_id=j.parent_id AND s.status=$%d) < (SELECT p.max_parallel FROM jobs p WHERE p.id=j.parent_id))`, len(args))
+	q += ` ORDER BY created_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`
+	j, err := scanJob(tx.QueryRowContext(ctx, q, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return JobRecord{}, false, nil
+	}
+	if err != nil {
+		return JobRecord{}, false, err
+	}
+	now := time.Now().Unix()
+	if _, err := tx.ExecContext(ctx, `UPDATE jobs SET status=$1, owner=$2, started_at=$3, heartbeat_at=$3 WHERE id=$4`, string(JobRunning), owner, now, j.ID); err != nil {
+		return JobRecord{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return JobRecord{}, false, err
+	}
+	j.Status, j.Owner = JobRunning, owner
+	j.StartedAt = sql.NullTime{Time: time.Unix(now, 0).UTC(), Valid: true}
+	return j, true, nil
+}
+
+// Heartbeat 更新執行中作業的心跳時間，供其他實例判斷 owner 是否仍存活。
+func (r *JobRepository) Heartbeat(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE jobs SET heartbeat_at=$1 WHERE id=$2 AND status=$3`, time.Now().Unix(), id, string(JobRunning))
+	return err
+}
+
+// Requeue 將 owner 執行中的作業放回 pending，例如服務關閉而中斷執行時。
+func (r *JobRepository) Requeue(ctx context.Context, id, owner string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE jobs SET status=$1, owner=NULL, started_at=NULL, heartbeat_at=NULL WHERE id=$2 AND owner=$3 AND status=$4`, string(JobPending), id, owner, string(JobRunning))
+	return err
+}
+
+// RecoverStale 處理心跳早於 before 的執行中作業（其 owner 已不存在）：
+// 排隊作業放回 pending 重新執行；同步作業的呼叫端已斷線，標記為 failed。
+// 矩陣的父紀錄不執行也不更新心跳，不在處理範圍內。回傳處理的筆數。
+func (r *JobRepository) RecoverStale(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `UPDATE jobs SET status=$1, owner=NULL, started_at=NULL, heartbeat_at=NULL WHERE status=$2 AND mode=$3 AND COALESCE(heartbeat_at, started_at) < $4`,
+		string(JobPending), string(JobRunning), string(JobQueued), before.Unix())
+	if err != nil {
+		return 0, err
+	}
+	requeued, _ := res.RowsAffected()
+	res, err = r.db.ExecContext(ctx, `UPDATE jobs SET status=$1, error=$2, finished_at=$3 WHERE status=$4 AND mode=$5 AND COALESCE(heartbeat_at, started_at) < $6`,
+		string(JobFailed), "interrupted: server stopped while the job was running", time.Now().Unix(), string(JobRunning), string(JobSync), before.Unix())
+	if err != nil {
+		return requeued, err
+	}
+	failed, _ := res.RowsAffected()
+	return requeued + failed, nil
+}
+
+// Finish 寫入作業結果與結束時間。
+func (r *JobRepository) Finish(ctx context.Context, id string, status JobStatus, exitCode int64, logs, errMsg string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE jobs SET status=$1, exit_code=$2, logs=$3, error=$4, finished_at=$5 WHERE id=$6`, string(status), exitCode, logs, errMsg, time.Now().Unix(), id)
+	return err
+}
+
+// MatrixChild 矩陣作業的一個組合與其子作業。
+type MatrixChild struct {
+	Index  int               // 組合的順序，從 0 起算
+	Values map[string]string // 軸名稱 -> 此組合的值
+	Job    JobRecord         // 新增時為子作業內容；查詢時只含 ID、Status、ExitCode 與 Error
+}
+
+// InsertMatrix 在同一個交易中新增矩陣的父紀錄（running）與 pending 的子作業，回傳父作業與各子作業的 ID。
+// 子作業由 worker 領取，同一矩陣同時執行的子作業不超過 maxParallel。
+func (r *JobRepository) InsertMatrix(ctx context.Context, parent JobRecord, maxParallel int, children []MatrixChild) (string, []string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", nil, err
+	}
+	defer tx.Rollback()
+	id := uuid.NewString()
+	now := time.Now().Unix()
+	b, _ := json.Marshal(parent.Cmd)
+	if _, err := tx.ExecContext(ctx, `INSERT INTO jobs(id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, status, max_parallel, created_at, started_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$11)`,
+		id, parent.Submitter, parent.Image, string(b), parent.HostDir, parent.ContainerDir, parent.OptionsJSON, string(JobMatrix), string(JobRunning), maxParallel, now); err != nil {
+		return "", nil, err
+	}
+	ids := make([]string, 0, len(children))
+	for _, c := range children {
+		childID := uuid.NewString()
+		cmd, _ := json.Marshal(c.Job.Cmd)
+		values, _ := json.Marshal(c.Values)
+		if _, err := tx.ExecContext(ctx, `INSERT INTO jobs(id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, status, parent_id, matrix_index, matrix_values, created_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)`,
+			childID, parent.Submitter, c.Job.Image, string(cmd), c.Job.HostDir, c.Job.ContainerDir, c.Job.OptionsJSON, string(JobQueued), string(JobPending), id, c.Index, string(values), now); err != nil {
+			return "", nil, err
+		}
+		ids = append(ids, childID)
+	}
+	return id, ids, tx.Commit()
+}
+
+// MatrixChildren 依組合順序列出矩陣的子作業。
+func (r *JobRepository) MatrixChildren(ctx context.Context, parentID string) ([]MatrixChild, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT matrix_index, matrix_values, id, status, exit_code, error FROM jobs WHERE parent_id=$1 ORDER BY matrix_index`, parentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []MatrixChild{}
+	for rows.Next() {
+		var c MatrixChild
+		var status string
+		var values, errMsg sql.NullString
+		var exitCode sql.NullInt64
+		if err := rows.Scan(&c.Index, &values, &c.Job.ID, &status, &exitCode, &errMsg); err != nil {
+			return nil, err
+		}
+		_ = json.Unmarshal([]byte(values.String), &c.Values)
+		c.Job.Status, c.Job.ExitCode, c.Job.Error = JobStatus(status), exitCode.Int64, errMsg.String
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// FinishParent 在子作業 childID 結束後，若同一矩陣的子作業皆已結束，寫入父紀錄的彙整結果：
+// 全部成功為 succeeded，否則為 failed 並以組合順序第一個未成功子作業的 exit code 為結束碼。
+// childID 不屬於矩陣或父紀錄已結束時不做任何事。
+func (r *JobRepository) FinishParent(ctx context.Context, childID string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE jobs p SET
+    status = CASE WHEN EXISTS (SELECT 1 FROM jobs c WHERE c.parent_id=p.id AND c.status<>$1) THEN $2 ELSE $1 END,
+    exit_code = COALESCE((SELECT c.exit_code FROM jobs c WHERE c.parent_id=p.id AND c.status<>$1 ORDER BY c.matrix_index LIMIT 1), 0),
+    finished_at = $3
+WHERE p.id=(SELECT parent_id FROM jobs WHERE id=$4) AND p.status=$5
+    AND NOT EXISTS (SELECT 1 FROM jobs c WHERE c.parent_id=p.id AND c.status IN ($6,$5))`,
+		string(JobSucceeded), string(JobFailed), time.Now().Unix(), childID, string(JobRunning), string(JobPending))
+	return err
+}
+
+// JobAttempt 作業的一次執行；設定重試時同一作業有多筆，依 Attempt 排序。
+type JobAttempt struct {
+	ID         string
+	JobID      string
+	Attempt    int // 從 1 起算
+	Status     JobStatus
+	ExitCode   int64
+	OOMKilled  bool
+	Logs       string
+	Error      string
+	StartedAt  time.Time
+	FinishedAt sql.NullTime
+}
+
+// StartAttempt 新增作業的下一次執行紀錄並回傳其 ID；次數接續既有紀錄，
+// 因此服務重啟後重新執行的排隊作業也會累計。
+func (r *JobRepository) StartAttempt(ctx context.Context, jobID string) (string, error) {
+	id := uuid.NewString()
+	_, err := r.db.ExecContext(ctx, `INSERT INTO job_attempts(id, job_id, attempt, status, started_at) SELECT $1, $2, COALESCE(MAX(attempt), 0) + 1, $3, $4 FROM job_attempts WHERE job_id=$2`,
+		id, jobID, string(JobRunning), time.Now().Unix())
+	return id, err
+}
+
+// FinishAttempt 寫入一次執行的結果。
+func (r *JobRepository) FinishAttempt(ctx context.Context, id string, status JobStatus, exitCode int64, oomKilled bool, logs, errMsg string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE job_attempts SET status=$1, exit_code=$2, oom_killed=$3, logs=$4, error=$5, finished_at=$6 WHERE id=$7`,
+		string(status), exitCode, oomKilled, logs, errMsg, time.Now().Unix(), id)
+	return err
+}
+
+// Attempts 依執行順序列出作業的每次執行。
+func (r *JobRepository) Attempts(ctx context.Context, jobID string) ([]JobAttempt, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, job_id, attempt, status, exit_code, oom_killed, logs, error, started_at, finished_at FROM job_attempts WHERE job_id=$1 ORDER BY attempt`, jobID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []JobAttempt{}
+	for rows.Next() {
+		var a JobAttempt
+		var status string
+		var logs, errMsg sql.NullString
+		var exitCode, startedAt, finishedAt sql.NullInt64
+		var oom sql.NullBool
+		if err := rows.Scan(&a.ID, &a.JobID, &a.Attempt, &status, &exitCode, &oom, &logs, &errMsg, &startedAt, &finishedAt); err != nil {
+			return nil, err
+		}
+		a.Status, a.ExitCode, a.OOMKilled = JobStatus(status), exitCode.Int64, oom.Bool
+		a.Logs, a.Error = logs.String, errMsg.String
+		a.StartedAt = time.Unix(startedAt.Int64, 0).UTC()
+		a.FinishedAt = nullUnix(finishedAt)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+const jobColumns = `id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, status, exit_code, logs, error, created_at, started_at, finished_at`
+
+func (r *JobRepository) Get(ctx context.Context, id string) (JobRecord, error) {
+	j, err := scanJob(r.db.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id=$1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return JobRecord{}, ErrNotFound
+	}
+	return j, err
+}
+
+// List 依建立時間由新到舊列出作業，以 (created_at, id) 做 keyset 分頁；
+// 回傳的 nextCursor 為空字串代表沒有下一頁。
+func (r *JobRepository) List(ctx context.Context, f JobFilter) ([]JobRecord, string, error) {
+	var where []string
+	var args []any
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Submitter != "" {
+		add("submitter=$%d", f.Submitter)
+	}
+	if f.Status != "" {
+		add("status=$%d", f.Status)
+	}
+	if f.Image != "" {
+		add("image=$%d", f.Image)
+	}
+	if f.Parent != "" {
+		add("parent_id=$%d", f.Parent)
+	}
+	if f.Cursor != "" {
+		createdAt, id, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, createdAt, id)
+		where = append(where, fmt.Sprintf("(created_at,id) < ($%d,$%d)", len(args)-1, len(args)))
+	}
+	q := `SELECT ` + jobColumns + ` FROM jobs` + joinWhere(where) + ` ORDER BY created_at DESC, id DESC`
+	if f.Limit > 0 {
+		// 多取一筆以判斷是否還有下一頁
+		args = append(args, f.Limit+1)
+		q += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	out := []JobRecord{}
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		out = append(out, j)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+		last := out[len(out)-1]
+		next = encodeCursor(last.CreatedAt.Unix(), last.ID)
+	}
+	return out, next, nil
+}
+
+func scanJob(row rowScanner) (JobRecord, error) {
+	var j JobRecord
+	var status string
+	var submitter, image, cmdJSON, hostDir, containerDir, options, mode, logs, errMsg sql.NullString
+	var exitCode, createdAt, startedAt, finishedAt sql.NullInt64
+	if err := row.Scan(&j.ID, &submitter, &image, &cmdJSON, &hostDir, &containerDir, &options, &mode, &status, &exitCode, &logs, &errMsg, &createdAt, &startedAt, &finishedAt); err != nil {
+		return JobRecord{}, err
+	}
+	j.Submitter, j.Image = submitter.String, image.String
+	_ = json.Unmarshal([]byte(cmdJSON.String), &j.Cmd)
+	j.HostDir, j.ContainerDir = hostDir.String, containerDir.String
+	j.OptionsJSON, j.Mode = options.String, JobMode(mode.String)
+	j.Status = JobStatus(status)
+	j.ExitCode = exitCode.Int64
+	j.Logs, j.Error = logs.String, errMsg.String
+	j.CreatedAt = time.Unix(createdAt.Int64, 0).UTC()
+	j.StartedAt = nullUnix(startedAt)
+	j.FinishedAt = nullUnix(finishedAt)
+	return j, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullUnix(v sql.NullInt64) sql.NullTime {
+	if !v.Valid {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: time.Unix(v.Int64, 0).UTC(), Valid: true}
+}
@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PipelineStatus 管線執行或其步驟的狀態。
+type PipelineStatus string
+
+const (
+	PipelinePending   PipelineStatus = "pending"
+	PipelineRunning   PipelineStatus = "running"
+	PipelineSucceeded PipelineStatus = "succeeded"
+	PipelineFailed    PipelineStatus = "failed"
+	PipelineTimedOut  PipelineStatus = "timed_out" // 僅用於步驟
+	PipelineSkipped   PipelineStatus = "skipped"   // 相依步驟失敗或 fail-fast 而未執行
+	PipelineCancelled PipelineStatus = "cancelled" // fail-fast 時被中止的執行中步驟
+)
+
+// PipelineRun 一次管線執行；所有步驟掛載同一個資料夾。
+type PipelineRun struct {
+	ID           string
+	Name         string
+	Submitter    string
+	HostDir      string
+	ContainerDir string
+	OnFailure    string // fail-fast|continue
+	Status       PipelineStatus
+	Error        string
+	CreatedAt    time.Time
+	StartedAt    sql.NullTime
+	FinishedAt   sql.NullTime
+	Steps        []PipelineStep // 依送出時的順序
+}
+
+// PipelineStep 管線中的一個步驟與其最後的執行結果。
+type PipelineStep struct {
+	Name        string
+	DependsOn   []string
+	OptionsJSON string // containers.JobOptions
+	Status      PipelineStatus
+	JobID       string // 對應的作業紀錄
+	ExitCode    int64
+	Error       string
+	StartedAt   sql.NullTime
+	FinishedAt  sql.NullTime
+}
+
+// PipelineFilter 查詢管線執行的條件；零值欄位代表不篩選。
+type PipelineFilter struct {
+	Submitter string
+	Status    string
+	Cursor    string
+	Limit     int
+}
+
+type PipelineRepository struct{ db *sql.DB }
+
+func NewPipelineRepository(db *sql.DB) *PipelineRepository { return &PipelineRepository{db: db} }
+
+// Insert 在同一個交易中寫入執行中的管線與 pending 的步驟，回傳 ID。
+func (r *PipelineRepository) Insert(ctx context.Context, p PipelineRun) (string, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	id := uuid.NewString()
+	now := time.Now().Unix()
+	if _, err := tx.ExecContext(ctx, `INSERT INTO pipeline_runs(id, name, submitter, host_dir, container_dir, on_failure, status, created_at, started_at, heartbeat_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$8,$8)`,
+		id, p.Name, p.Submitter, p.HostDir, p.ContainerDir, p.OnFailure, string(PipelineRunning), now); err != nil {
+		return "", err
+	}
+	for i, s := range p.Steps {
+		deps, _ := json.Marshal(s.DependsOn)
+		if _, err := tx.ExecContext(ctx, `INSERT INTO pipeline_steps(run_id, name, position, depends_on_json, options_json, status) VALUES($1,$2,$3,$4,$5,$6)`,
+			id, s.Name, i, string(deps), s.OptionsJSON, string(PipelinePending)); err != nil {
+			return "", err
+		}
+	}
+	return id, tx.Commit()
+}
+
+// StartStep 將步驟標記為執行中。
+func (r *PipelineRepository) StartStep(ctx context.Context, runID, name string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE pipeline_steps SET status=$1, started_at=$2 WHERE run_id=$3 AND name=$4`, string(PipelineRunning), time.Now().Unix(), runID, name)
+	return err
+}
+
+// FinishStep 寫入步驟結果；未執行而略過的步驟 jobID 為空字串。
+func (r *PipelineRepository) FinishStep(ctx context.Context, runID, name string, status PipelineStatus, jobID string, exitCode int64, errMsg string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE pipeline_steps SET status=$1, job_id=$2, exit_code=$3, error=$4, finished_at=$5 WHERE run_id=$6 AND name=$7`,
+		string(status), nullString(jobID), exitCode, errMsg, time.Now().Unix(), runID, name)
+	return err
+}
+
+// Finish 寫入管線的最終狀態。
+func (r *PipelineRepository) Finish(ctx context.Context, id string, status PipelineStatus, errMsg string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE pipeline_runs SET status=$1, error=$2, finished_at=$3 WHERE id=$4`, string(status), errMsg, time.Now().Unix(), id)
+	return err
+}
+
+// Heartbeat 更新執行中管線的心跳時間。
+func (r *PipelineRepository) Heartbeat(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE pipeline_runs SET heartbeat_at=$1 WHERE id=$2`, time.Now().Unix(), id)
+	return err
+}
+
+// RecoverStale 將心跳早於 before 的執行中管線（執行的實例已終止）標記為失敗、未完成的步驟標記為取消，回傳處理的管線數。
+func (r *PipelineRepository) RecoverStale(ctx context.Context, before time.Time) (int64, error) {
+	const msg = "interrupted: server stopped while the pipeline was running"
+	now := time.Now().Unix()
+	if _, err := r.db.ExecContext(ctx, `UPDATE pipeline_steps SET status=$1, error=$2, finished_at=$3 WHERE status IN ($4,$5) AND run_id IN (SELECT id FROM pipeline_runs WHERE status=$5 AND heartbeat_at < $6)`,
+		string(PipelineCancelled), msg, now, string(PipelinePending), string(PipelineRunning), before.Unix()); err != nil {
+		return 0, err
+	}
+	res, err := r.db.ExecContext(ctx, `UPDATE pipeline_runs SET status=$1, error=$2, finished_at=$3 WHERE status=$4 AND heartbeat_at < $5`,
+		string(PipelineFailed), msg, now, string(PipelineRunning), before.Unix())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+const pipelineColumns = `id, name, submitter, host_dir, container_dir, on_failure, status, error, created_at, started_at, finished_at`
+
+// Get 回傳管線執行與其步驟。
+func (r *PipelineRepository) Get(ctx context.Context, id string) (PipelineRun, error) {
+	p, err := scanPipeline(r.db.QueryRowContext(ctx, `SELECT `+pipelineColumns+` FROM pipeline_runs WHERE id=$1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return PipelineRun{}, ErrNotFound
+	}
+	if err != nil {
+		return PipelineRun{}, err
+	}
+	rows, err := r.db.QueryContext(ctx, `SELECT name, depends_on_json, options_json, status, job_id, exit_code, error, started_at, finished_at FROM pipeline_steps WHERE run_id=$1 ORDER BY position`, id)
+	if err != nil {
+		return PipelineRun{}, err
+	}
+	defer rows.Close()
+	p.Steps = []PipelineStep{}
+	for rows.Next() {
+		var s PipelineStep
+		var status string
+		var deps, options, jobID, errMsg sql.NullString
+		var exitCode, startedAt, finishedAt sql.NullInt64
+		if err := rows.Scan(&s.Name, &deps, &options, &status, &jobID, &exitCode, &errMsg, &startedAt, &finishedAt); err != nil {
+			return PipelineRun{}, err
+		}
+		_ = json.Unmarshal([]byte(deps.String), &s.DependsOn)
+		s.OptionsJSON, s.Status = options.String, PipelineStatus(status)
+		s.JobID, s.ExitCode, s.Error = jobID.String, exitCode.Int64, errMsg.String
+		s.StartedAt, s.FinishedAt = nullUnix(startedAt), nullUnix(finishedAt)
+		p.Steps = append(p.Steps, s)
+	}
+	return p, rows.Err()
+}
+
+// List 依建立時間由新到舊列出管線執行（不含步驟），以 (created_at, id) 做 keyset 分頁。
+func (r *PipelineRepository) List(ctx context.Context, f PipelineFilter) ([]PipelineRun, string, error) {
+	var where []string
+	var args []any
+	if f.Submitter != "" {
+		args = append(args, f.Submitter)
+		where = append(where, fmt.Sprintf("submitter=$%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		where = append(where, fmt.Sprintf("status=$%d", len(args)))
+	}
+	if f.Cursor != "" {
+		createdAt, id, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		args = append(args, createdAt, id)
+		where = append(where, fmt.Sprintf("(created_at,id) < ($%d,$%d)", len(args)-1, len(args)))
+	}
+	q := `SELECT ` + pipelineColumns + ` FROM pipeline_runs` + joinWhere(where) + ` ORDER BY created_at DESC, id DESC`
+	if f.Limit > 0 {
+		args = append(args, f.Limit+1)
+		q += fmt.Sprintf(` LIMIT $%d`, len(args))
+	}
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	out := []PipelineRun{}
+	for rows.Next() {
+		p, err := scanPipeline(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	next := ""
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+		last := out[len(out)-1]
+		next = encodeCursor(last.CreatedAt.Unix(), last.ID)
+	}
+	return out, next, nil
+}
+
+func scanPipeline(row rowScanner) (PipelineRun, error) {
+	var p PipelineRun
+	var status string
+	var name, submitter, hostDir, containerDir, onFailure, errMsg sql.NullString
+	var createdAt, startedAt, finishedAt sql.NullInt64
+	if err := row.Scan(&p.ID, &name, &submitter, &hostDir, &containerDir, &onFailure, &status, &errMsg, &createdAt, &startedAt, &finishedAt); err != nil {
+		return PipelineRun{}, err
+	}
+	p.Name, p.Submitter = name.String, submitter.String
+	p.HostDir, p.ContainerDir, p.OnFailure = hostDir.String, containerDir.String, onFailure.String
+	p.Status, p.Error = PipelineStatus(status), errMsg.String
+	p.CreatedAt = time.Unix(createdAt.Int64, 0).UTC()
+	p.StartedAt, p.FinishedAt = nullUnix(startedAt), nullUnix(finishedAt)
+	return p, nil
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// schedulerLock 選出唯一執行排程迴圈之實例的 advisory lock 鍵值。
+const schedulerLock int64 = 0x73636864 // "schd"
+
+// ScheduleRecord 以 cron 表示式定期觸發的作業範本。
+type ScheduleRecord struct {
+	ID          string
+	Name        string
+	CronExpr    string
+	Timezone    string // IANA 時區名稱，例如 Asia/Taipei
+	OptionsJSON string // containers.JobOptions
+	Overlap     string // skip|queue|replace
+	Enabled     bool
+	Submitter   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	NextRunAt   sql.NullTime
+	LastRunAt   sql.NullTime
+}
+
+type ScheduleRunStatus string
+
+const (
+	RunQueued    ScheduleRunStatus = "queued" // 等待前一次執行結束（overlap=queue）
+	RunRunning   ScheduleRunStatus = "running"
+	RunSkipped   ScheduleRunStatus = "skipped" // 前一次仍在執行而略過
+	RunCancelled ScheduleRunStatus = "cancelled"
+	// 作業結束後沿用 JobStatus：succeeded|failed|timed_out
+)
+
+// ScheduleRun 排程每次觸發的紀錄。
+type ScheduleRun struct {
+	ID          string
+	ScheduleID  string
+	JobID       string
+	Status      ScheduleRunStatus
+	Error       string
+	ScheduledAt time.Time
+	StartedAt   sql.NullTime
+	FinishedAt  sql.NullTime
+}
+
+type ScheduleRepository struct{ db *sql.DB }
+
+func NewScheduleRepository(db *sql.DB) *ScheduleRepository { return &ScheduleRepository{db: db} }
+
+func (r *ScheduleRepository) Insert(ctx context.Context, s ScheduleRecord) (string, error) {
+	id := uuid.NewString()
+	now := time.Now().Unix()
+	_, err := r.db.ExecContext(ctx, `INSERT INTO schedules(id, name, cron_expr, timezone, options_json, overlap, enabled, submitter, created_at, updated_at, next_run_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$9,$10)`,
+		id, s.Name, s.CronExpr, s.Timezone, s.OptionsJSON, s.Overlap, s.Enabled, s.Submitter, now, nullTimeUnix(s.NextRunAt))
+	return id, err
+}
+
+// Update 覆寫排程設定與下次執行時間；排程不存在時回傳 ErrNotFound。
+func (r *ScheduleRepository) Update(ctx context.Context, s ScheduleRecord) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE schedules SET name=$1, cron_expr=$2, timezone=$3, options_json=$4, overlap=$5, enabled=$6, updated_at=$7, next_run_at=$8 WHERE id=$9`,
+		s.Name, s.CronExpr, s.Timezone, s.OptionsJSON, s.Overlap, s.Enabled, time.Now().Unix(), nullTimeUnix(s.NextRunAt), s.ID)
+	return affectedOne(res, err)
+}
+
+// Delete 刪除排程；已觸發的執行紀錄保留供稽核。
+func (r *ScheduleRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM schedules WHERE id=$1`, id)
+	return affectedOne(res, err)
+}
+
+const scheduleColumns = `id, name, cron_expr, timezone, options_json, overlap, enabled, submitter, created_at, updated_at, next_run_at, last_run_at`
+
+func (r *ScheduleRepository) Get(ctx context.Context, id string) (ScheduleRecord, error) {
+	s, err := scanSchedule(r.db.QueryRowContext(ctx, `SELECT `+scheduleColumns+` FROM schedules WHERE id=$1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return ScheduleRecord{}, ErrNotFound
+	}
+	return s, err
+}
+
+// List 依建立時間由新到舊列出排程。
+func (r *ScheduleRepository) List(ctx context.Context, limit int) ([]ScheduleRecord, error) {
+	return r.query(ctx, `SELECT `+scheduleColumns+` FROM schedules ORDER BY created_at DESC, id DESC LIMIT $1`, limit)
+}
+
+// Due 列出啟用中且下次執行時間不晚於 now 的排程。
+func (r *ScheduleRepository) Due(ctx context.Context, now time.Time) ([]ScheduleRecord, error) {
+	return r.query(ctx, `SELECT `+scheduleColumns+` FROM schedules WHERE enabled AND next_run_at<=$1 ORDER BY next_run_at`, now.Unix())
+}
+
+// Advance 記錄本次觸發時間並設定下次執行時間。
+func (r *ScheduleRepository) Advance(ctx context.Context, id string, next, last time.Time) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE schedules SET next_run_at=$1, last_run_at=$2 WHERE id=$3`, next.Unix(), last.Unix(), id)
+	return err
+}
+
+func (r *ScheduleRepository) query(ctx context.Context, q string, args ...any) ([]ScheduleRecord, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ScheduleRecord{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// InsertRun 新增一筆觸發紀錄；running 同時記錄開始時間，skipped 等已結束的狀態記錄結束時間。
+func (r *ScheduleRepository) InsertRun(ctx context.Context, run ScheduleRun) (string, error) {
+	id := uuid.NewString()
+	var startedAt, finishedAt sql.NullInt64
+	now := sql.NullInt64{Int64: time.Now().Unix(), Valid: true}
+	switch run.Status {
+	case RunRunning:
+		startedAt = now
+	case RunQueued:
+	default:
+		finishedAt = now
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT INTO schedule_runs(id, schedule_id, status, error, scheduled_at, started_at, finished_at) VALUES($1,$2,$3,$4,$5,$6,$7)`,
+		id, run.ScheduleID, string(run.Status), run.Error, run.ScheduledAt.Unix(), startedAt, finishedAt)
+	return id, err
+}
+
+// StartRun 將排隊中的觸發紀錄標記為執行中。
+func (r *ScheduleRepository) StartRun(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE schedule_runs SET status=$1, started_at=$2 WHERE id=$3`, string(RunRunning), time.Now().Unix(), id)
+	return err
+}
+
+// FinishRun 寫入觸發紀錄的結果與對應的作業 ID。
+func (r *ScheduleRepository) FinishRun(ctx context.Context, id string, status ScheduleRunStatus, jobID, errMsg string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE schedule_runs SET status=$1, job_id=$2, error=$3, finished_at=$4 WHERE id=$5`, string(status), jobID, errMsg, time.Now().Unix(), id)
+	return err
+}
+
+// ListRuns 依排定時間由新到舊列出排程的觸發紀錄。
+func (r *ScheduleRepository) ListRuns(ctx context.Context, scheduleID string, limit int) ([]ScheduleRun, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, schedule_id, job_id, status, error, scheduled_at, started_at, finished_at FROM schedule_runs WHERE schedule_id=$1 ORDER BY scheduled_at DESC, id DESC LIMIT $2`, scheduleID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []ScheduleRun{}
+	for rows.Next() {
+		var run ScheduleRun
+		var status string
+		var jobID, errMsg sql.NullString
+		var scheduledAt, startedAt, finishedAt sql.NullInt64
+		if err := rows.Scan(&run.ID, &run.ScheduleID, &jobID, &status, &errMsg, &scheduledAt, &startedAt, &finishedAt); err != nil {
+			return nil, err
+		}
+		run.JobID, run.Error = jobID.String, errMsg.String
+		run.Status = ScheduleRunStatus(status)
+		run.ScheduledAt = time.Unix(scheduledAt.Int64, 0).UTC()
+		run.StartedAt, run.FinishedAt = nullUnix(startedAt), nullUnix(finishedAt)
+		out = append(out, run)
+	}
+	return out, rows.Err()
+}
+
+// TryLeaderLock 嘗試取得排程迴圈的 session advisory lock；成功時回傳持有該 lock 的連線，
+// 連線關閉即釋放，其他實例可接手。
+func (r *ScheduleRepository) TryLeaderLock(ctx context.Context) (*sql.Conn, bool, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	var ok bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, schedulerLock).Scan(&ok); err != nil || !ok {
+		_ = conn.Close()
+		return nil, false, err
+	}
+	return conn, true, nil
+}
+
+// ReleaseLeaderLock 釋放 TryLeaderLock 取得的 lock 並關閉連線。
+func (r *ScheduleRepository) ReleaseLeaderLock(conn *sql.Conn) {
+	_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, schedulerLock)
+	_ = conn.Close()
+}
+
+func scanSchedule(row rowScanner) (ScheduleRecord, error) {
+	var s ScheduleRecord
+	var name, tz, options, overlap, submitter sql.NullString
+	var createdAt, updatedAt, nextRunAt, lastRunAt sql.NullInt64
+	if err := row.Scan(&s.ID, &name, &s.CronExpr, &tz, &options, &overlap, &s.Enabled, &submitter, &createdAt, &updatedAt, &nextRunAt, &lastRunAt); err != nil {
+		return ScheduleRecord{}, err
+	}
+	s.Name, s.Timezone, s.OptionsJSON = name.String, tz.String, options.String
+	s.Overlap, s.Submitter = overlap.String, submitter.String
+	s.CreatedAt = time.Unix(createdAt.Int64, 0).UTC()
+	s.UpdatedAt = time.Unix(updatedAt.Int64, 0).UTC()
+	s.NextRunAt, s.LastRunAt = nullUnix(nextRunAt), nullUnix(lastRunAt)
+	return s, nil
+}
+
+func nullTimeUnix(t sql.NullTime) sql.NullInt64 {
+	if !t.Valid {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: t.Time.Unix(), Valid: true}
+}
+
+// affectedOne 將未影響任何資料列的更新轉為 ErrNotFound。
+func affectedOne(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
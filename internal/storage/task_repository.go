@@ -1,9 +1,14 @@
 package storage
 
 import (
+    "context"
     "database/sql"
     "encoding/json"
+    "errors"
+    "strconv"
     "time"
+
+    "github.com/google/uuid"
 )
 
 type TaskStatus string
@@ -13,33 +18,126 @@ const (
     TaskRunning   TaskStatus = "running"
     TaskSucceeded TaskStatus = "succeeded"
     TaskFailed    TaskStatus = "failed"
+    TaskCancelled TaskStatus = "cancelled"
 )
 
+// Finished 回傳狀態是否為終止狀態。
+func (s TaskStatus) Finished() bool {
+    return s == TaskSucceeded || s == TaskFailed || s == TaskCancelled
+}
+
 type ContainerTask struct {
     ID          string
     ContainerID string
     CmdJSON     string
     Status      TaskStatus
     ExitCode    int
-    Logs        string
+    Logs        string // stdout 與 stderr 合併，保留給舊資料
+    Stdout      string
+    Stderr      string
     CreatedAt   time.Time
     FinishedAt  sql.NullTime
 }
 
+// Cmd 解析 CmdJSON。
+func (t ContainerTask) Cmd() []string {
+    var cmd []string
+    _ = json.Unmarshal([]byte(t.CmdJSON), &cmd)
+    return cmd
+}
+
 type TaskRepository struct{ db *sql.DB }
 
 func NewTaskRepository(db *sql.DB) *TaskRepository { return &TaskRepository{db: db} }
 
-func (r *TaskRepository) Insert(containerID string, cmd []string) (string, error) {
+func (r *TaskRepository) Insert(ctx context.Context, containerID string, cmd []string) (string, error) {
     b, _ := json.Marshal(cmd)
-    id := time.Now().UTC().Format("20060102T150405.000Z0700")
-    _, err := r.db.Exec(`INSERT INTO container_tasks(id, container_id, cmd_json, status, created_at) VALUES($1,$2,$3,$4,$5)`, id, containerID, string(b), string(TaskPending), time.Now().Unix())
+    id := uuid.NewString()
+    _, err := r.db.ExecContext(ctx, `INSERT INTO container_tasks(id, container_id, cmd_json, status, created_at) VALUES($1,$2,$3,$4,$5)`, id, containerID, string(b), string(TaskPending), time.Now().Unix())
     return id, err
 }
 
-func (r *TaskRepository) UpdateResult(id string, status TaskStatus, exitCode int, logs string) error {
-    _, err := r.db.Exec(`UPDATE container_tasks SET status=$1, exit_code=$2, logs=$3, finished_at=$4 WHERE id=$5`, string(status), exitCode, logs, time.Now().Unix(), id)
+// UpdateResult 寫入執行結果；已取消的任務不會被覆寫。
+func (r *TaskRepository) UpdateResult(ctx context.Context, id string, status TaskStatus, exitCode int, stdout, stderr string) error {
+    _, err := r.db.ExecContext(ctx, `UPDATE container_tasks SET status=$1, exit_code=$2, logs=$3, stdout=$4, stderr=$5, finished_at=$6 WHERE id=$7 AND status<>$8`, string(status), exitCode, stdout+stderr, stdout, stderr, time.Now().Unix(), id, string(TaskCancelled))
     return err
 }
 
+// MarkRunning 將 pending 任務標記為 running；任務已不是 pending（例如已取消）時回傳 false。
+func (r *TaskRepository) MarkRunning(ctx context.Context, id string) (bool, error) {
+    res, err := r.db.ExecContext(ctx, `UPDATE container_tasks SET status=$1 WHERE id=$2 AND status=$3`, string(TaskRunning), id, string(TaskPending))
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
+
+// Cancel 將尚未結束的任務標記為 cancelled；任務已結束時回傳 false。
+func (r *TaskRepository) Cancel(ctx context.Context, id string) (bool, error) {
+    res, err := r.db.ExecContext(ctx, `UPDATE container_tasks SET status=$1, finished_at=$2 WHERE id=$3 AND status IN ($4,$5)`, string(TaskCancelled), time.Now().Unix(), id, string(TaskPending), string(TaskRunning))
+    if err != nil {
+        return false, err
+    }
+    n, err := res.RowsAffected()
+    return n > 0, err
+}
 
+const taskColumns = `id, container_id, cmd_json, status, exit_code, logs, stdout, stderr, created_at, finished_at`
+
+func (r *TaskRepository) Get(ctx context.Context, id string) (ContainerTask, error) {
+    t, err := scanTask(r.db.QueryRowContext(ctx, `SELECT `+taskColumns+` FROM container_tasks WHERE id=$1`, id))
+    if errors.Is(err, sql.ErrNoRows) {
+        return ContainerTask{}, ErrNotFound
+    }
+    return t, err
+}
+
+// List 依建立時間由新到舊列出任務；containerID 為空時列出全部。
+func (r *TaskRepository) List(ctx context.Context, containerID string, limit int) ([]ContainerTask, error) {
+    q := `SELECT ` + taskColumns + ` FROM container_tasks`
+    args := []any{}
+    if containerID != "" {
+        q += ` WHERE container_id=$1`
+        args = append(args, containerID)
+    }
+    q += ` ORDER BY created_at DESC, id DESC`
+    if limit > 0 {
+        args = append(args, limit)
+        q += ` LIMIT $` + strconv.Itoa(len(args))
+    }
+    rows, err := r.db.QueryContext(ctx, q, args...)
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+    out := []ContainerTask{}
+    for rows.Next() {
+        t, err := scanTask(rows)
+        if err != nil {
+            return nil, err
+        }
+        out = append(out, t)
+    }
+    return out, rows.Err()
+}
+
+type rowScanner interface{ Scan(dest ...any) error }
+
+func scanTask(row rowScanner) (ContainerTask, error) {
+    var t ContainerTask
+    var status string
+    var exitCode, createdAt, finishedAt sql.NullInt64
+    var logs, stdout, stderr sql.NullString
+    if err := row.Scan(&t.ID, &t.ContainerID, &t.CmdJSON, &status, &exitCode, &logs, &stdout, &stderr, &createdAt, &finishedAt); err != nil {
+        return ContainerTask{}, err
+    }
+    t.Status = TaskStatus(status)
+    t.ExitCode = int(exitCode.Int64)
+    t.Logs, t.Stdout, t.Stderr = logs.String, stdout.String, stderr.String
+    t.CreatedAt = time.Unix(createdAt.Int64, 0).UTC()
+    if finishedAt.Valid {
+        t.FinishedAt = sql.NullTime{Time: time.Unix(finishedAt.Int64, 0).UTC(), Valid: true}
+    }
+    return t, nil
+}
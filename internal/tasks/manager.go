@@ -0,0 +1,153 @@
+// Package tasks 以 worker pool 非同步執行容器內的 exec，狀態記錄於 container_tasks。
+package tasks
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/storage"
+)
+
+var (
+	ErrQueueFull = errors.New("task queue is full")
+	ErrFinished  = errors.New("task already finished")
+)
+
+// ExecFunc 實際執行 exec 的函式，通常為 containers.Service.Exec；任務被取消時 ctx 會被取消。
+type ExecFunc func(ctx context.Context, containerID string, cmd []string) (containers.ExecResult, error)
+
+type job struct {
+	id          string
+	containerID string
+	cmd         []string
+}
+
+// Manager 管理非同步 exec 任務。
+type Manager struct {
+	repo  *storage.TaskRepository
+	exec  ExecFunc
+	queue chan job
+	wg    sync.WaitGroup
+
+	mu      sync.Mutex
+	running map[string]context.CancelFunc // 執行中任務的取消函式
+}
+
+// NewManager 建立 Manager 並啟動 workers 個 worker；queueSize 為等待中任務的上限。
+func NewManager(repo *storage.TaskRepository, exec ExecFunc, workers, queueSize int) *Manager {
+	if workers <= 0 {
+		workers = 1
+	}
+	m := &Manager{repo: repo, exec: exec, queue: make(chan job, queueSize), running: map[string]context.CancelFunc{}}
+	for i := 0; i < workers; i++ {
+		m.wg.Add(1)
+		go m.worker()
+	}
+	return m
+}
+
+// NewManagerFromEnv 依 TASK_WORKERS（預設 4）與 TASK_QUEUE_SIZE（預設 100）建立 Manager。
+func NewManagerFromEnv(repo *storage.TaskRepository, exec ExecFunc) *Manager {
+	return NewManager(repo, exec, envInt("TASK_WORKERS", 4), envInt("TASK_QUEUE_SIZE", 100))
+}
+
+// Submit 寫入一筆 pending 任務並排入佇列，回傳任務 ID。
+// ctx 只用於寫入資料庫；任務本身在背景執行，不受呼叫端 ctx 影響。
+func (m *Manager) Submit(ctx context.Context, containerID string, cmd []string) (string, error) {
+	id, err := m.repo.Insert(ctx, containerID, cmd)
+	if err != nil {
+		return "", err
+	}
+	select {
+	case m.queue <- job{id: id, containerID: containerID, cmd: cmd}:
+		return id, nil
+	default:
+		_ = m.repo.UpdateResult(context.WithoutCancel(ctx), id, storage.TaskFailed, -1, "", ErrQueueFull.Error())
+		return "", ErrQueueFull
+	}
+}
+
+func (m *Manager) Get(ctx context.Context, id string) (storage.ContainerTask, error) {
+	return m.repo.Get(ctx, id)
+}
+
+// List 列出任務，containerID 為空時列出全部。
+func (m *Manager) List(ctx context.Context, containerID string, limit int) ([]storage.ContainerTask, error) {
+	return m.repo.List(ctx, containerID, limit)
+}
+
+// Cancel 取消尚未結束的任務。pending 任務不會被執行；
+// running 任務會被標記為 cancelled 並取消其 exec 的 ctx，其後的執行結果不再寫入。
+func (m *Manager) Cancel(ctx context.Context, id string) error {
+	ok, err := m.repo.Cancel(ctx, id)
+	if err != nil {
+		return err
+	}
+	if ok {
+		m.mu.Lock()
+		if cancel, found := m.running[id]; found {
+			cancel()
+		}
+		m.mu.Unlock()
+		return nil
+	}
+	if _, err := m.repo.Get(ctx, id); err != nil {
+		return err
+	}
+	return ErrFinished
+}
+
+// Close 停止接受新任務並等待佇列中的任務執行完畢。
+func (m *Manager) Close() {
+	close(m.queue)
+	m.wg.Wait()
+}
+
+func (m *Manager) worker() {
+	defer m.wg.Done()
+	for j := range m.queue {
+		m.run(j)
+	}
+}
+
+func (m *Manager) run(j job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.mu.Lock()
+	m.running[j.id] = cancel
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, j.id)
+		m.mu.Unlock()
+	}()
+
+	started, err := m.repo.MarkRunning(ctx, j.id)
+	if err != nil || !started {
+		// 已被取消或資料庫不可用
+		return
+	}
+	res, err := m.exec(ctx, j.containerID, j.cmd)
+	status := storage.TaskSucceeded
+	if err != nil || res.ExitCode != 0 {
+		status = storage.TaskFailed
+	}
+	if err != nil && res.Stderr == "" {
+		res.Stderr = err.Error()
+	}
+	// 取消後 ctx 已結束，結果仍以獨立的 ctx 寫入（已取消的任務不會被覆寫）
+	_ = m.repo.UpdateResult(context.WithoutCancel(ctx), j.id, status, res.ExitCode, res.Stdout, res.Stderr)
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
@@ -0,0 +1,117 @@
+package tasks
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+
+	"container-manager/internal/containers"
+	"container-manager/internal/storage"
+)
+
+var (
+	insertSQL  = regexp.QuoteMeta(`INSERT INTO container_tasks(id, container_id, cmd_json, status, created_at) VALUES($1,$2,$3,$4,$5)`)
+	runningSQL = regexp.QuoteMeta(`UPDATE container_tasks SET status=$1 WHERE id=$2 AND status=$3`)
+	resultSQL  = regexp.QuoteMeta(`UPDATE container_tasks SET status=$1, exit_code=$2, logs=$3, stdout=$4, stderr=$5, finished_at=$6 WHERE id=$7 AND status<>$8`)
+	cancelSQL  = regexp.QuoteMeta(`UPDATE container_tasks SET status=$1, finished_at=$2 WHERE id=$3 AND status IN ($4,$5)`)
+)
+
+func TestManager_RunsSubmittedTask(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	mock.ExpectExec(insertSQL).WithArgs(sqlmock.AnyArg(), "cid", `["echo","hi"]`, "pending", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(runningSQL).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(resultSQL).WithArgs("succeeded", 0, "hi\n", "hi\n", "", sqlmock.AnyArg(), sqlmock.AnyArg(), "cancelled").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	m := NewManager(storage.NewTaskRepository(db), func(ctx context.Context, id string, cmd []string) (containers.ExecResult, error) {
+		return containers.ExecResult{Stdout: "hi\n"}, nil
+	}, 1, 1)
+	if _, err := m.Submit(ctx, "cid", []string{"echo", "hi"}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	m.Close()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestManager_CancelPendingTaskIsSkipped(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	mock.MatchExpectationsInOrder(false)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	m := NewManager(storage.NewTaskRepository(db), func(ctx context.Context, id string, cmd []string) (containers.ExecResult, error) {
+		close(started)
+		<-release
+		return containers.ExecResult{}, nil
+	}, 1, 2)
+
+	// 第一個任務佔住唯一的 worker
+	mock.ExpectExec(insertSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(runningSQL).WillReturnResult(sqlmock.NewResult(0, 1))
+	if _, err := m.Submit(ctx, "cid", []string{"sleep", "10"}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	<-started
+
+	mock.ExpectExec(insertSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	second, err := m.Submit(ctx, "cid", []string{"echo", "never"})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	mock.ExpectExec(cancelSQL).WithArgs("cancelled", sqlmock.AnyArg(), second, "pending", "running").WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := m.Cancel(ctx, second); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+
+	// 第一個任務完成；第二個任務因已取消，MarkRunning 不影響任何資料列
+	mock.ExpectExec(resultSQL).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(runningSQL).WithArgs("running", second, "pending").WillReturnResult(sqlmock.NewResult(0, 0))
+	close(release)
+	m.Close()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestManager_CancelRunningTaskCancelsExec(t *testing.T) {
+	ctx := context.Background()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	started := make(chan struct{})
+	m := NewManager(storage.NewTaskRepository(db), func(ctx context.Context, id string, cmd []string) (containers.ExecResult, error) {
+		close(started)
+		<-ctx.Done()
+		return containers.ExecResult{ExitCode: -1}, ctx.Err()
+	}, 1, 1)
+
+	mock.ExpectExec(insertSQL).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(runningSQL).WillReturnResult(sqlmock.NewResult(0, 1))
+	id, err := m.Submit(ctx, "cid", []string{"sleep", "10"})
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	<-started
+
+	mock.ExpectExec(cancelSQL).WithArgs("cancelled", sqlmock.AnyArg(), id, "pending", "running").WillReturnResult(sqlmock.NewResult(0, 1))
+	// 已取消的任務不會被 UpdateResult 覆寫
+	mock.ExpectExec(resultSQL).WillReturnResult(sqlmock.NewResult(0, 0))
+	if err := m.Cancel(ctx, id); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	m.Close()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package tests
+
+import (
+    "context"
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "strings"
+    "testing"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/gorilla/websocket"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/middleware"
+)
+
+func loginToken(t *testing.T, r http.Handler) string {
+    t.Helper()
+    body, _ := json.Marshal(map[string]string{"username": "admin", "password": "admin"})
+    req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    var out struct{ Token string `json:"token"` }
+    _ = json.Unmarshal(w.Body.Bytes(), &out)
+    if out.Token == "" { t.Fatalf("login failed: %s", w.Body.String()) }
+    return out.Token
+}
+
+func TestAttachContainer_WebSocket(t *testing.T) {
+    ctx := context.Background()
+    gin.SetMode(gin.TestMode)
+    os.Setenv("JWT_SECRET", "devsecret")
+    prov := containers.NewMockProvider()
+    ctr, _ := prov.Create(ctx, containers.CreateOptions{Name: "shell", Image: "alpine:3.20"})
+    _ = prov.Start(ctx, ctr.ID)
+    handlers.Svc = containers.NewServiceWith(prov, nil)
+
+    r := gin.New()
+    r.POST("/login", handlers.Login)
+    v1 := r.Group("/v1")
+    v1.Use(middleware.Auth())
+    v1.GET("/containers/:id/attach", handlers.AttachContainer)
+    srv := httptest.NewServer(r)
+    defer srv.Close()
+    token := loginToken(t, r)
+    wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/containers/" + ctr.ID + "/attach"
+
+    // 沒有 token：拒絕
+    if _, resp, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+        t.Fatalf("expected 401 without token, err=%v", err)
+    }
+
+    // 查詢參數帶 token
+    conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?access_token="+token, nil)
+    if err != nil { t.Fatalf("dial with query token: %v", err) }
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"resize","cols":120,"rows":40}`)); err != nil { t.Fatalf("resize: %v", err) }
+    if err := conn.WriteMessage(websocket.BinaryMessage, []byte("echo hi\n")); err != nil { t.Fatalf("write: %v", err) }
+    mt, data, err := conn.ReadMessage()
+    if err != nil || mt != websocket.BinaryMessage || string(data) != "echo hi\n" { t.Fatalf("read: mt=%d data=%q err=%v", mt, data, err) }
+    conn.Close()
+
+    // 子協定帶 token，伺服器選用 tty
+    d := websocket.Dialer{Subprotocols: []string{"tty", middleware.WebSocketTokenProtocolPrefix + token}}
+    conn, resp, err := d.Dial(wsURL, nil)
+    if err != nil { t.Fatalf("dial with subprotocol token: %v", err) }
+    if resp.Header.Get("Sec-WebSocket-Protocol") != "tty" { t.Fatalf("subprotocol=%q", resp.Header.Get("Sec-WebSocket-Protocol")) }
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"stdin","data":"pwd\n"}`)); err != nil { t.Fatalf("write: %v", err) }
+    if _, data, err := conn.ReadMessage(); err != nil || string(data) != "pwd\n" { t.Fatalf("read: data=%q err=%v", data, err) }
+    conn.Close()
+
+    // 只帶 bearer 子協定時原樣回應，瀏覽器才會完成握手
+    d = websocket.Dialer{Subprotocols: []string{middleware.WebSocketTokenProtocolPrefix + token}}
+    conn, resp, err = d.Dial(wsURL, nil)
+    if err != nil { t.Fatalf("dial with bearer subprotocol only: %v", err) }
+    if resp.Header.Get("Sec-WebSocket-Protocol") != middleware.WebSocketTokenProtocolPrefix+token { t.Fatalf("subprotocol=%q", resp.Header.Get("Sec-WebSocket-Protocol")) }
+    conn.Close()
+
+    // 閒置逾時後伺服器主動關閉
+    t.Setenv("ATTACH_IDLE_TIMEOUT", "100ms")
+    conn, _, err = websocket.DefaultDialer.Dial(wsURL+"?access_token="+token, nil)
+    if err != nil { t.Fatalf("dial: %v", err) }
+    _ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    if _, _, err := conn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) { t.Fatalf("expected idle close, got %v", err) }
+    conn.Close()
+
+    if _, resp, err := websocket.DefaultDialer.Dial(strings.Replace(wsURL, ctr.ID, "missing", 1)+"?access_token="+token, nil); err == nil || resp.StatusCode != http.StatusNotFound {
+        t.Fatalf("expected 404 for missing container, err=%v", err)
+    }
+}
@@ -0,0 +1,96 @@
+package tests
+
+import (
+    "bytes"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/middleware"
+)
+
+type errorResponse struct {
+    Code      string `json:"code"`
+    Message   string `json:"message"`
+    RequestID string `json:"requestId"`
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorResponse {
+    t.Helper()
+    var e errorResponse
+    if err := json.Unmarshal(w.Body.Bytes(), &e); err != nil { t.Fatalf("decode: %v body=%s", err, w.Body.String()) }
+    return e
+}
+
+func TestErrors_NotFoundEchoesRequestID(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    handlers.Svc = containers.NewServiceWith(containers.NewMockProvider(), nil)
+
+    r := gin.New()
+    r.Use(middleware.RequestID())
+    r.GET("/v1/containers/:id", handlers.GetContainer)
+
+    req := httptest.NewRequest(http.MethodGet, "/v1/containers/missing", nil)
+    req.Header.Set("X-Request-ID", "req-123")
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusNotFound { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    e := decodeError(t, w)
+    if e.Code != "not_found" || e.Message == "" || e.RequestID != "req-123" { t.Fatalf("unexpected body: %+v", e) }
+    if w.Header().Get("X-Request-ID") != "req-123" { t.Fatalf("request id header not echoed") }
+}
+
+func TestErrors_StatusMapping(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    cases := []struct {
+        err    error
+        status int
+        code   string
+    }{
+        {&containers.Error{Kind: containers.ErrImagePull, Op: "pull alpine:latest", Err: errors.New("registry unavailable")}, http.StatusBadGateway, "image_pull_failed"},
+        {&containers.Error{Kind: containers.ErrImageNotFound, Op: "pull nope:latest", Err: errors.New("manifest unknown")}, http.StatusNotFound, "image_not_found"},
+        {&containers.Error{Kind: containers.ErrBuildFailed, Op: "build x", Err: errors.New("exit code 1")}, http.StatusUnprocessableEntity, "build_failed"},
+        {&containers.Error{Kind: containers.ErrConflict, Err: errors.New("name already in use")}, http.StatusConflict, "conflict"},
+        {&containers.Error{Kind: containers.ErrTimeout}, http.StatusGatewayTimeout, "timeout"},
+        {containers.ErrLimitExceeded, http.StatusUnprocessableEntity, "quota_exceeded"},
+        {errors.New("boom"), http.StatusInternalServerError, "internal"},
+    }
+    for _, tc := range cases {
+        mp := containers.NewMockProvider()
+        mp.FailNext("create", tc.err)
+        handlers.Svc = containers.NewServiceWith(mp, nil)
+
+        r := gin.New()
+        r.POST("/v1/containers", handlers.CreateContainer)
+        body, _ := json.Marshal(map[string]any{"image": "alpine"})
+        req := httptest.NewRequest(http.MethodPost, "/v1/containers", bytes.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        if w.Code != tc.status { t.Fatalf("%v: status=%d body=%s", tc.err, w.Code, w.Body.String()) }
+        e := decodeError(t, w)
+        if e.Code != tc.code || e.RequestID == "" { t.Fatalf("%v: unexpected body %+v", tc.err, e) }
+    }
+}
+
+func TestErrors_RunJobUnsupported(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    handlers.Svc = containers.NewServiceWith(execProviderMock{}, nil)
+
+    r := gin.New()
+    r.POST("/v1/jobs", handlers.RunJob)
+    body, _ := json.Marshal(map[string]any{"image": "alpine", "hostDir": t.TempDir(), "containerDir": "/workspace", "cmd": []string{"true"}})
+    req := httptest.NewRequest(http.MethodPost, "/v1/jobs", bytes.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusNotImplemented { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    if e := decodeError(t, w); e.Code != "unsupported" { t.Fatalf("unexpected body %+v", e) }
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+    "context"
     "bytes"
     "encoding/json"
     "net/http"
@@ -14,7 +15,7 @@ import (
 )
 
 type execProviderMock struct{ containers.Provider }
-func (execProviderMock) Exec(id string, cmd []string) (int, string, error) { return 0, "ok", nil }
+func (execProviderMock) Exec(ctx context.Context, id string, cmd []string) (containers.ExecResult, error) { return containers.ExecResult{Stdout: "ok", Stderr: "warn"}, nil }
 
 func TestExec_Handler(t *testing.T) {
     gin.SetMode(gin.TestMode)
@@ -29,6 +30,12 @@ func TestExec_Handler(t *testing.T) {
     w := httptest.NewRecorder()
     r.ServeHTTP(w, req)
     if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var out struct {
+        Stdout string `json:"stdout"`
+        Stderr string `json:"stderr"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &out)
+    if out.Stdout != "ok" || out.Stderr != "warn" { t.Fatalf("unexpected output: %s", w.Body.String()) }
 }
 
 
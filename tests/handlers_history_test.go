@@ -0,0 +1,70 @@
+package tests
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "regexp"
+    "testing"
+
+    sqlmock "github.com/DATA-DOG/go-sqlmock"
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/storage"
+)
+
+func TestContainerHistory_Handler(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock: %v", err) }
+    handlers.Svc = containers.NewServiceWith(containers.NewMockProvider(), storage.NewContainerRepository(db))
+
+    cols := []string{"id", "name", "image", "status", "created_at"}
+    mock.ExpectQuery(regexp.QuoteMeta(`SELECT id,name,image,status,created_at FROM containers WHERE status=$1 AND name LIKE $2 ORDER BY created_at DESC, id DESC LIMIT $3`)).
+        WithArgs("deleted", `demo\_%`, 3).
+        WillReturnRows(sqlmock.NewRows(cols).
+            AddRow("c3", "demo_3", "alpine:3.20", "deleted", 300).
+            AddRow("c2", "demo_2", "alpine:3.20", "deleted", 200).
+            AddRow("c1", "demo_1", "alpine:3.20", "deleted", 100))
+    mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM containers WHERE status=$1 AND name LIKE $2`)).
+        WithArgs("deleted", `demo\_%`).
+        WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+    r := gin.New()
+    r.GET("/v1/containers/history", handlers.ContainerHistory)
+    r.GET("/v1/containers/:id", handlers.GetContainer)
+
+    req := httptest.NewRequest(http.MethodGet, "/v1/containers/history?status=deleted&name=demo_&limit=2", nil)
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var page struct {
+        Items      []containers.Container `json:"items"`
+        Total      int                    `json:"total"`
+        NextCursor string                 `json:"nextCursor"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &page)
+    if len(page.Items) != 2 || page.Total != 3 || page.NextCursor == "" { t.Fatalf("unexpected page: %+v", page) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+
+    // 下一頁：游標轉為 keyset 條件
+    mock.ExpectQuery(regexp.QuoteMeta(`SELECT id,name,image,status,created_at FROM containers WHERE status=$1 AND name LIKE $2 AND (created_at,id) < ($3,$4) ORDER BY created_at DESC, id DESC LIMIT $5`)).
+        WithArgs("deleted", `demo\_%`, int64(200), "c2", 3).
+        WillReturnRows(sqlmock.NewRows(cols).AddRow("c1", "demo_1", "alpine:3.20", "deleted", 100))
+    mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM containers WHERE status=$1 AND name LIKE $2`)).
+        WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+    req = httptest.NewRequest(http.MethodGet, "/v1/containers/history?status=deleted&name=demo_&limit=2&cursor="+page.NextCursor, nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    page.NextCursor = ""
+    _ = json.Unmarshal(w.Body.Bytes(), &page)
+    if w.Code != http.StatusOK || len(page.Items) != 1 || page.NextCursor != "" { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+
+    req = httptest.NewRequest(http.MethodGet, "/v1/containers/history?cursor=!!", nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusBadRequest { t.Fatalf("bad cursor status=%d", w.Code) }
+}
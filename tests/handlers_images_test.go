@@ -0,0 +1,209 @@
+package tests
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "os"
+    "path/filepath"
+    "regexp"
+    "strings"
+    "testing"
+
+    sqlmock "github.com/DATA-DOG/go-sqlmock"
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/storage"
+)
+
+// uploadDockerfile 在 DATA_DIR/<user>/<batch> 寫入 Dockerfile，模擬上傳的資料夾。
+func uploadDockerfile(t *testing.T, user, batch, content string) string {
+    t.Helper()
+    dir := filepath.Join(os.Getenv("DATA_DIR"), user, batch)
+    if err := os.MkdirAll(dir, 0o755); err != nil { t.Fatal(err) }
+    if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(content), 0o644); err != nil { t.Fatal(err) }
+    return dir
+}
+
+func TestImages_BuildStreamsOutput(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("DATA_DIR", t.TempDir())
+    prov := containers.NewMockProvider()
+    handlers.Svc = containers.NewServiceWith(prov, nil)
+    r := gin.New()
+    r.POST("/v1/images/build", handlers.BuildImage)
+    dir := uploadDockerfile(t, "u1", "20260101T000000Z", "FROM alpine:3.20 AS base\nRUN echo hi\n")
+
+    w := sendJSON(r, http.MethodPost, "/v1/images/build", map[string]any{"dir": dir, "target": "base", "buildArgs": map[string]string{"A": "1"}, "noCache": true})
+    if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    if ct := w.Header().Get("Content-Type"); ct != "application/x-ndjson" { t.Fatalf("content-type %q", ct) }
+    var lines []map[string]string
+    sc := bufio.NewScanner(strings.NewReader(w.Body.String()))
+    for sc.Scan() {
+        var m map[string]string
+        if err := json.Unmarshal(sc.Bytes(), &m); err != nil { t.Fatalf("line %q: %v", sc.Text(), err) }
+        lines = append(lines, m)
+    }
+    if len(lines) != 4 || lines[0]["stream"] != "Step 1/2 : FROM alpine:3.20 AS base" { t.Fatalf("output %s", w.Body.String()) }
+    // 最後一行為建置結果，映像名稱依使用者與批次產生
+    if last := lines[len(lines)-1]; last["image"] != "container-manager/u1:20260101T000000Z-base" || !strings.HasPrefix(last["id"], "sha256:") {
+        t.Fatalf("result %v", last)
+    }
+    builds := prov.Builds()
+    if len(builds) != 1 || builds[0].ContextDir != dir || builds[0].BuildArgs["A"] != "1" || !builds[0].NoCache {
+        t.Fatalf("builds %+v", builds)
+    }
+
+    // 開始輸出前的建置失敗以一般的錯誤回應回報
+    prov.FailNext("build", containers.ErrBuildFailed)
+    if w := sendJSON(r, http.MethodPost, "/v1/images/build", map[string]any{"dir": dir}); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "build_failed") {
+        t.Fatalf("failed build status=%d body=%s", w.Code, w.Body.String())
+    }
+    for _, bad := range []map[string]any{
+        {},
+        {"dir": t.TempDir()},
+        {"dir": dir, "dockerfile": "../Dockerfile"},
+        {"dir": dir, "dockerfile": "Containerfile"},
+        {"dir": dir, "target": "-x"},
+    } {
+        if w := sendJSON(r, http.MethodPost, "/v1/images/build", bad); w.Code != http.StatusBadRequest {
+            t.Fatalf("%v: status=%d body=%s", bad, w.Code, w.Body.String())
+        }
+    }
+}
+
+func TestImages_CreateContainerFromBuild(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("DATA_DIR", t.TempDir())
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock: %v", err) }
+    prov := containers.NewMockProvider()
+    handlers.Svc = containers.NewServiceWith(prov, storage.NewContainerRepository(db))
+    r := gin.New()
+    r.POST("/v1/containers", handlers.CreateContainer)
+    dir := uploadDockerfile(t, "u1", "b1", "FROM alpine:3.20\n")
+
+    // 容器以建置的映像建立
+    mock.ExpectExec(regexp.QuoteMeta("INSERT INTO containers(id,name,image,status,created_at) VALUES($1,$2,$3,$4,$5)")).
+        WithArgs(sqlmock.AnyArg(), "built", "container-manager/u1:b1", "created", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+
+    w := sendJSON(r, http.MethodPost, "/v1/containers", map[string]any{"name": "built", "build": map[string]any{"dir": dir}})
+    if w.Code != http.StatusCreated { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var resp struct{ ID, Image string }
+    _ = json.Unmarshal(w.Body.Bytes(), &resp)
+    if opts, ok := prov.CreateOptions(resp.ID); !ok || opts.Image != "container-manager/u1:b1" {
+        t.Fatalf("create options %+v", opts)
+    }
+    if len(prov.Builds()) != 1 { t.Fatalf("builds %+v", prov.Builds()) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatal(err) }
+
+    for _, bad := range []map[string]any{
+        {"name": "x"},
+        {"name": "x", "image": "alpine", "build": map[string]any{"dir": dir}},
+        {"name": "x", "build": map[string]any{}},
+    } {
+        if w := sendJSON(r, http.MethodPost, "/v1/containers", bad); w.Code != http.StatusBadRequest {
+            t.Fatalf("%v: status=%d body=%s", bad, w.Code, w.Body.String())
+        }
+    }
+}
+
+// imagesRouter 與 server.Run 相同的映像路由。
+func imagesRouter() *gin.Engine {
+    r := gin.New()
+    r.POST("/v1/containers", handlers.CreateContainer)
+    r.POST("/v1/images/build", handlers.BuildImage)
+    r.POST("/v1/images/pull", handlers.PullImage)
+    r.POST("/v1/images/prune", handlers.PruneImages)
+    r.GET("/v1/images", handlers.ListImages)
+    r.GET("/v1/images/*ref", handlers.GetImage)
+    r.DELETE("/v1/images/*ref", handlers.DeleteImage)
+    return r
+}
+
+func TestImages_PullListInspectRemovePrune(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("DATA_DIR", t.TempDir())
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock: %v", err) }
+    prov := containers.NewMockProvider()
+    handlers.Svc = containers.NewServiceWith(prov, storage.NewContainerRepository(db))
+    r := imagesRouter()
+
+    // 拉取以 NDJSON 串流進度，最後一行為映像；省略 tag 時為 latest
+    w := sendJSON(r, http.MethodPost, "/v1/images/pull", map[string]any{"image": "alpine"})
+    if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" { t.Fatalf("pull status=%d body=%s", w.Code, w.Body.String()) }
+    lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+    var pulled containers.Image
+    _ = json.Unmarshal([]byte(lines[len(lines)-1]), &pulled)
+    if len(lines) < 2 || len(pulled.Tags) != 1 || pulled.Tags[0] != "alpine:latest" || !strings.Contains(lines[0], `"status"`) {
+        t.Fatalf("pull output %s", w.Body.String())
+    }
+
+    // registry 上沒有的映像與其他拉取失敗為不同的錯誤
+    prov.FailNext("pull", &containers.Error{Kind: containers.ErrImageNotFound, Op: "pull alpne:latest", Err: errors.New("manifest unknown")})
+    if w := sendJSON(r, http.MethodPost, "/v1/images/pull", map[string]any{"image": "alpne"}); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "image_not_found") {
+        t.Fatalf("missing image status=%d body=%s", w.Code, w.Body.String())
+    }
+    prov.FailNext("pull", &containers.Error{Kind: containers.ErrImagePull, Op: "pull redis:latest", Err: errors.New("registry unavailable")})
+    if w := sendJSON(r, http.MethodPost, "/v1/images/pull", map[string]any{"image": "redis"}); w.Code != http.StatusBadGateway || !strings.Contains(w.Body.String(), "image_pull_failed") {
+        t.Fatalf("pull failure status=%d body=%s", w.Code, w.Body.String())
+    }
+    for _, bad := range []map[string]any{{}, {"image": "Alpine"}, {"image": "container-manager/u1:b1"}} {
+        if w := sendJSON(r, http.MethodPost, "/v1/images/pull", bad); w.Code != http.StatusBadRequest {
+            t.Fatalf("%v: status=%d body=%s", bad, w.Code, w.Body.String())
+        }
+    }
+
+    // 建立容器時本機沒有的映像拉取失敗同樣回報
+    prov.FailNext("pull", &containers.Error{Kind: containers.ErrImageNotFound, Op: "pull nope:1", Err: errors.New("manifest unknown")})
+    if w := sendJSON(r, http.MethodPost, "/v1/containers", map[string]any{"name": "typo", "image": "nope:1"}); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "image_not_found") {
+        t.Fatalf("create with missing image status=%d body=%s", w.Code, w.Body.String())
+    }
+
+    dir := uploadDockerfile(t, "u1", "b1", "FROM alpine:3.20\n")
+    if w := sendJSON(r, http.MethodPost, "/v1/images/build", map[string]any{"dir": dir}); w.Code != http.StatusOK { t.Fatalf("build status=%d body=%s", w.Code, w.Body.String()) }
+    mock.ExpectExec(regexp.QuoteMeta("INSERT INTO containers")).WithArgs(sqlmock.AnyArg(), "web", "alpine:latest", "created", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+    if w := sendJSON(r, http.MethodPost, "/v1/containers", map[string]any{"name": "web", "image": "alpine:latest"}); w.Code != http.StatusCreated { t.Fatalf("create status=%d body=%s", w.Code, w.Body.String()) }
+
+    var list struct{ Items []containers.Image `json:"items"` }
+    w = sendJSON(r, http.MethodGet, "/v1/images?built=true", nil)
+    _ = json.Unmarshal(w.Body.Bytes(), &list)
+    if w.Code != http.StatusOK || len(list.Items) != 1 || list.Items[0].Tags[0] != "container-manager/u1:b1" || !list.Items[0].Built { t.Fatalf("list built %s", w.Body.String()) }
+    w = sendJSON(r, http.MethodGet, "/v1/images?reference=alp*", nil)
+    _ = json.Unmarshal(w.Body.Bytes(), &list)
+    if len(list.Items) != 1 || list.Items[0].ID != pulled.ID { t.Fatalf("list by reference %s", w.Body.String()) }
+
+    // 名稱包含 / 與 :，也可用 ID 查詢
+    var img containers.Image
+    w = sendJSON(r, http.MethodGet, "/v1/images/container-manager/u1:b1", nil)
+    _ = json.Unmarshal(w.Body.Bytes(), &img)
+    if w.Code != http.StatusOK || img.OS != "linux" || !img.Built { t.Fatalf("inspect status=%d body=%s", w.Code, w.Body.String()) }
+    if w := sendJSON(r, http.MethodGet, "/v1/images/"+pulled.ID, nil); w.Code != http.StatusOK { t.Fatalf("inspect by id status=%d", w.Code) }
+    if w := sendJSON(r, http.MethodGet, "/v1/images/nope:1", nil); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), `"not_found"`) {
+        t.Fatalf("inspect missing status=%d body=%s", w.Code, w.Body.String())
+    }
+
+    // 容器使用中的映像須 force 才能刪除
+    if w := sendJSON(r, http.MethodDelete, "/v1/images/alpine", nil); w.Code != http.StatusConflict { t.Fatalf("delete in use status=%d body=%s", w.Code, w.Body.String()) }
+    w = sendJSON(r, http.MethodDelete, "/v1/images/alpine?force=true", nil)
+    if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"untagged":"alpine:latest"`) || !strings.Contains(w.Body.String(), pulled.ID) {
+        t.Fatalf("force delete status=%d body=%s", w.Code, w.Body.String())
+    }
+
+    // 以相同 tag 重新建置後，舊的映像成為 dangling，預設的 prune 只清除它
+    _ = os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM alpine:3.20\nRUN true\n"), 0o644)
+    if w := sendJSON(r, http.MethodPost, "/v1/images/build", map[string]any{"dir": dir}); w.Code != http.StatusOK { t.Fatalf("rebuild status=%d", w.Code) }
+    var report containers.PruneReport
+    w = sendJSON(r, http.MethodPost, "/v1/images/prune", nil)
+    _ = json.Unmarshal(w.Body.Bytes(), &report)
+    if w.Code != http.StatusOK || len(report.Deleted) != 1 || report.Deleted[0].Deleted == "" || report.SpaceReclaimed == 0 { t.Fatalf("prune %s", w.Body.String()) }
+    w = sendJSON(r, http.MethodPost, "/v1/images/prune", map[string]any{"all": true, "built": true})
+    _ = json.Unmarshal(w.Body.Bytes(), &report)
+    if len(report.Deleted) != 2 || report.Deleted[0].Untagged != "container-manager/u1:b1" { t.Fatalf("prune all %s", w.Body.String()) }
+    if w := sendJSON(r, http.MethodPost, "/v1/images/prune", map[string]any{"until": "soon"}); w.Code != http.StatusBadRequest { t.Fatalf("invalid until status=%d", w.Code) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatal(err) }
+}
@@ -0,0 +1,430 @@
+package tests
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "regexp"
+    "testing"
+    "time"
+
+    sqlmock "github.com/DATA-DOG/go-sqlmock"
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/detect"
+    "container-manager/internal/jobs"
+    "container-manager/internal/middleware"
+    "container-manager/internal/storage"
+)
+
+var jobCols = []string{"id", "submitter", "image", "cmd_json", "host_dir", "container_dir", "options_json", "mode", "status", "exit_code", "logs", "error", "created_at", "started_at", "finished_at"}
+
+// useJobsDB 讓 handlers.Jobs 使用 sqlmock 連線，作業透過 handlers.Svc 執行。
+func useJobsDB(t *testing.T) sqlmock.Sqlmock {
+    t.Helper()
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock: %v", err) }
+    handlers.Jobs = jobs.NewManager(storage.NewJobRepository(db), func(ctx context.Context, opts containers.JobOptions) (containers.JobResult, error) {
+        return handlers.Svc.RunJob(ctx, opts)
+    }, jobs.Config{})
+    return mock
+}
+
+func TestJobs_RunRecordsHistory(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    mock := useJobsDB(t)
+    prov := containers.NewMockProvider()
+    prov.SetExecResponse("make test", containers.MockExecResponse{ExitCode: 2, Stdout: "FAIL\n"})
+    handlers.Svc = containers.NewServiceWith(prov, nil)
+
+    t.Setenv("JWT_SECRET", "devsecret")
+    r := gin.New()
+    r.POST("/login", handlers.Login)
+    v1 := r.Group("/v1")
+    v1.Use(middleware.Auth())
+    v1.POST("/jobs", handlers.RunJob)
+    hostDir := t.TempDir()
+
+    // 紀錄的 submitter 取自 JWT 的 sub
+    loginBody, _ := json.Marshal(map[string]string{"username": "admin", "password": "admin"})
+    wLogin := httptest.NewRecorder()
+    r.ServeHTTP(wLogin, httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(loginBody)))
+    var login struct{ Token string `json:"token"` }
+    _ = json.Unmarshal(wLogin.Body.Bytes(), &login)
+
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO jobs(id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, owner, status, created_at, started_at, heartbeat_at) VALUES($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$12)`)).
+        WithArgs(sqlmock.AnyArg(), "admin", "golang:1.24", `["make","test"]`, hostDir, "/workspace", "", "sync", sqlmock.AnyArg(), "running", sqlmock.AnyArg(), sqlmock.AnyArg()).
+        WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO job_attempts`)).WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE job_attempts`)).WithArgs("failed", int64(2), false, "FAIL\n", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE jobs SET status=$1, exit_code=$2, logs=$3, error=$4, finished_at=$5 WHERE id=$6`)).
+        WithArgs("failed", int64(2), "FAIL\n", "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+        WillReturnResult(sqlmock.NewResult(0, 1))
+
+    body, _ := json.Marshal(map[string]any{"image": "golang:1.24", "hostDir": hostDir, "containerDir": "/workspace", "cmd": []string{"make", "test"}})
+    req := httptest.NewRequest(http.MethodPost, "/v1/jobs", bytes.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Authorization", "Bearer "+login.Token)
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var resp struct {
+        ID     string `json:"id"`
+        Status string `json:"status"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &resp)
+    if resp.ID == "" || resp.Status != "failed" { t.Fatalf("unexpected body %s", w.Body.String()) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
+
+func TestJobs_ListAndGet(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    mock := useJobsDB(t)
+
+    r := gin.New()
+    r.GET("/v1/jobs", handlers.ListJobs)
+    r.GET("/v1/jobs/:id", handlers.GetJob)
+    get := func(url string) *httptest.ResponseRecorder {
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, url, nil))
+        return w
+    }
+
+    const listSQL = `SELECT id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, status, exit_code, logs, error, created_at, started_at, finished_at FROM jobs`
+    mock.ExpectQuery(regexp.QuoteMeta(listSQL+` WHERE submitter=$1 AND status=$2 ORDER BY created_at DESC, id DESC LIMIT $3`)).
+        WithArgs("alice", "succeeded", 2).
+        WillReturnRows(sqlmock.NewRows(jobCols).
+            AddRow("j2", "alice", "alpine", `["true"]`, "/data/a", "/w", "", "sync", "succeeded", 0, "", nil, 200, 200, 201).
+            AddRow("j1", "alice", "alpine", `["true"]`, "/data/a", "/w", "", "sync", "succeeded", 0, "", nil, 100, 100, 101))
+    w := get("/v1/jobs?submitter=alice&status=succeeded&limit=1")
+    if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var page struct {
+        Items []struct {
+            ID         string   `json:"id"`
+            Cmd        []string `json:"cmd"`
+            FinishedAt int64    `json:"finishedAt"`
+        } `json:"items"`
+        NextCursor string `json:"nextCursor"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &page)
+    if len(page.Items) != 1 || page.Items[0].ID != "j2" || page.Items[0].Cmd[0] != "true" || page.Items[0].FinishedAt != 201 || page.NextCursor == "" {
+        t.Fatalf("unexpected page: %s", w.Body.String())
+    }
+
+    mock.ExpectQuery(regexp.QuoteMeta(listSQL+` WHERE (created_at,id) < ($1,$2) ORDER BY created_at DESC, id DESC LIMIT $3`)).
+        WithArgs(int64(200), "j2", 51).
+        WillReturnRows(sqlmock.NewRows(jobCols).AddRow("j1", "alice", "alpine", `["true"]`, "/data/a", "/w", "", "sync", "succeeded", 0, "", nil, 100, 100, 101))
+    if w := get("/v1/jobs?cursor=" + page.NextCursor); w.Code != http.StatusOK { t.Fatalf("next page status=%d body=%s", w.Code, w.Body.String()) }
+
+    mock.ExpectQuery(regexp.QuoteMeta(listSQL + ` WHERE id=$1`)).WithArgs("j3").
+        WillReturnRows(sqlmock.NewRows(jobCols).AddRow("j3", "bob", "python:3.12", `["python","main.py"]`, "/data/b", "/w", "{}", "queued", "timed_out", -1, "partial", nil, 300, 300, 360))
+    // 每次執行（含重試）依序列在作業紀錄中
+    mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, job_id, attempt, status, exit_code, oom_killed, logs, error, started_at, finished_at FROM job_attempts WHERE job_id=$1 ORDER BY attempt`)).WithArgs("j3").
+        WillReturnRows(sqlmock.NewRows([]string{"id", "job_id", "attempt", "status", "exit_code", "oom_killed", "logs", "error", "started_at", "finished_at"}).
+            AddRow("a1", "j3", 1, "failed", 137, true, "killed", nil, 300, 310).
+            AddRow("a2", "j3", 2, "timed_out", -1, false, "partial", nil, 312, 360))
+    w = get("/v1/jobs/j3")
+    var job struct {
+        Status   string `json:"status"`
+        Logs     string `json:"logs"`
+        Attempts []struct {
+            Attempt   int    `json:"attempt"`
+            Status    string `json:"status"`
+            OOMKilled bool   `json:"oomKilled"`
+        } `json:"attempts"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &job)
+    if w.Code != http.StatusOK || job.Status != "timed_out" || job.Logs != "partial" { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    if len(job.Attempts) != 2 || !job.Attempts[0].OOMKilled || job.Attempts[1].Attempt != 2 || job.Attempts[1].Status != "timed_out" {
+        t.Fatalf("unexpected attempts: %s", w.Body.String())
+    }
+
+    mock.ExpectQuery(regexp.QuoteMeta(listSQL + ` WHERE id=$1`)).WithArgs("nope").WillReturnRows(sqlmock.NewRows(jobCols))
+    if w := get("/v1/jobs/nope"); w.Code != http.StatusNotFound { t.Fatalf("missing job status=%d", w.Code) }
+    if w := get("/v1/jobs?cursor=!!"); w.Code != http.StatusBadRequest { t.Fatalf("bad cursor status=%d", w.Code) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
+
+func TestJobs_AsyncSubmitQueuesJob(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    mock := useJobsDB(t)
+    svc := containers.NewServiceWith(containers.NewMockProvider(), nil)
+    svc.SetJobLimits(containers.JobLimits{MaxTimeout: time.Minute})
+    handlers.Svc = svc
+
+    r := gin.New()
+    r.POST("/v1/jobs", handlers.RunJob)
+    post := func(payload map[string]any) *httptest.ResponseRecorder {
+        payload["image"], payload["hostDir"], payload["containerDir"] = "alpine", "/srv/data/u1", "/workspace"
+        body, _ := json.Marshal(payload)
+        req := httptest.NewRequest(http.MethodPost, "/v1/jobs?async=true", bytes.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    // 排隊的作業保存已套用預設值的完整參數，由 worker 還原執行
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO jobs`)).
+        WithArgs(sqlmock.AnyArg(), "", "alpine", `["true"]`, "/srv/data/u1", "/workspace", sqlmock.AnyArg(), "queued", nil, "pending", sqlmock.AnyArg(), nil).
+        WillReturnResult(sqlmock.NewResult(1, 1))
+    w := post(map[string]any{"cmd": []string{"true"}, "env": map[string]string{"CI": "1"}})
+    if w.Code != http.StatusAccepted { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var resp struct {
+        ID     string `json:"id"`
+        Status string `json:"status"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &resp)
+    if resp.ID == "" || resp.Status != "pending" { t.Fatalf("unexpected body %s", w.Body.String()) }
+
+    // 參數錯誤在排隊前回報，不寫入紀錄
+    if w := post(map[string]any{"cmd": []string{"true"}, "timeoutSeconds": 3600}); w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("over max timeout status=%d body=%s", w.Code, w.Body.String())
+    }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
+
+func TestJobs_RetryPolicy(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    mock := useJobsDB(t)
+    prov := containers.NewMockProvider()
+    prov.FailNext("runjob", &containers.Error{Kind: containers.ErrImagePull, Op: "pull alpine"})
+    handlers.Svc = containers.NewServiceWith(prov, nil)
+
+    r := gin.New()
+    r.POST("/v1/jobs", handlers.RunJob)
+    post := func(retry map[string]any) *httptest.ResponseRecorder {
+        body, _ := json.Marshal(map[string]any{"image": "alpine", "hostDir": "/srv/data/u1", "containerDir": "/workspace", "cmd": []string{"true"}, "retry": retry})
+        req := httptest.NewRequest(http.MethodPost, "/v1/jobs", bytes.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    // 映像拉取失敗後重試成功，兩次執行分別記錄
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO jobs`)).WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO job_attempts`)).WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE job_attempts`)).WithArgs("failed", int64(-1), false, "", "pull alpine: image pull failed", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO job_attempts`)).WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE job_attempts`)).WithArgs("succeeded", int64(0), false, "", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE jobs SET status=$1`)).WithArgs("succeeded", int64(0), "", "", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+    w := post(map[string]any{"maxAttempts": 3, "initialBackoffSeconds": 0.01, "providerError": true})
+    if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    // 注入的拉取錯誤發生在 mock 記錄參數之前，只有第二次執行被記錄
+    if n := len(prov.Jobs()); n != 1 { t.Fatalf("runs=%d, want 1", n) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+
+    // 不合法的重試設定不執行作業
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO jobs`)).WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO job_attempts`)).WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE job_attempts`)).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE jobs SET status=$1`)).WillReturnResult(sqlmock.NewResult(0, 1))
+    if w := post(map[string]any{"maxAttempts": 3, "exitCodes": []int{0}}); w.Code != http.StatusBadRequest { t.Fatalf("zero exit code status=%d body=%s", w.Code, w.Body.String()) }
+    if w := post(map[string]any{"maxAttempts": -1}); w.Code != http.StatusBadRequest { t.Fatalf("negative attempts status=%d body=%s", w.Code, w.Body.String()) }
+    if n := len(prov.Jobs()); n != 1 { t.Fatalf("invalid retry policy ran the job") }
+}
+
+func TestJobs_MatrixFansOut(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    mock := useJobsDB(t)
+    svc := containers.NewServiceWith(containers.NewMockProvider(), nil)
+    svc.SetJobLimits(containers.JobLimits{MaxTimeout: time.Minute})
+    handlers.Svc = svc
+
+    r := gin.New()
+    r.POST("/v1/jobs", handlers.RunJob)
+    r.GET("/v1/jobs/:id", handlers.GetJob)
+    job := func(extra map[string]any) map[string]any {
+        payload := map[string]any{"image": "python:3.11", "hostDir": "/srv/data/u1", "containerDir": "/workspace",
+            "cmd": []string{"python", "train.py", "--lr", "${matrix.lr}", "${matrix.file}"}, "env": map[string]string{"RUN": "${matrix.file}"}}
+        for k, v := range extra { payload[k] = v }
+        return payload
+    }
+
+    // 每個組合替換 cmd 與 env 後成為排隊的子作業；數字維持原本的寫法
+    mock.ExpectBegin()
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO jobs(id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, status, max_parallel, created_at, started_at)`)).
+        WithArgs(sqlmock.AnyArg(), "", "python:3.11", `["python","train.py","--lr","${matrix.lr}","${matrix.file}"]`, "/srv/data/u1", "/workspace", sqlmock.AnyArg(), "matrix", "running", 2, sqlmock.AnyArg()).
+        WillReturnResult(sqlmock.NewResult(1, 1))
+    combos := []struct{ file, lr string }{{"a.csv", "0.1"}, {"a.csv", "1e-3"}, {"b.csv", "0.1"}, {"b.csv", "1e-3"}}
+    for i, cmb := range combos {
+        mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO jobs(id, submitter, image, cmd_json, host_dir, container_dir, options_json, mode, status, parent_id, matrix_index, matrix_values, created_at)`)).
+            WithArgs(sqlmock.AnyArg(), "", "python:3.11", `["python","train.py","--lr","`+cmb.lr+`","`+cmb.file+`"]`, "/srv/data/u1", "/workspace", sqlmock.AnyArg(), "queued", "pending", sqlmock.AnyArg(), i, `{"file":"`+cmb.file+`","lr":"`+cmb.lr+`"}`, sqlmock.AnyArg()).
+            WillReturnResult(sqlmock.NewResult(1, 1))
+    }
+    mock.ExpectCommit()
+    w := sendJSON(r, http.MethodPost, "/v1/jobs", job(map[string]any{"matrix": map[string]any{"lr": []any{0.1, json.RawMessage("1e-3")}, "file": []string{"a.csv", "b.csv"}}, "maxParallel": 2}))
+    if w.Code != http.StatusAccepted { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var resp struct {
+        ID     string `json:"id"`
+        Status string `json:"status"`
+        Jobs   []struct {
+            ID     string            `json:"id"`
+            Values map[string]string `json:"values"`
+        } `json:"jobs"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &resp)
+    if resp.ID == "" || resp.Status != "running" || len(resp.Jobs) != 4 || resp.Jobs[1].Values["lr"] != "1e-3" { t.Fatalf("unexpected body %s", w.Body.String()) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+
+    // 軸或組合無效時不建立任何作業
+    bad := []map[string]any{
+        job(map[string]any{"matrix": map[string]any{}}),
+        job(map[string]any{"matrix": map[string]any{"lr": []any{}}}),
+        job(map[string]any{"matrix": map[string]any{"lr": []any{map[string]any{"x": 1}}}}),
+        job(map[string]any{"matrix": map[string]any{"file": []string{"a.csv"}}}),
+        job(map[string]any{"matrix": map[string]any{"lr": []string{"1"}, "file": []string{"a"}}, "maxParallel": -1}),
+    }
+    for _, b := range bad {
+        if w := sendJSON(r, http.MethodPost, "/v1/jobs", b); w.Code != http.StatusBadRequest { t.Fatalf("payload %v status=%d body=%s", b["matrix"], w.Code, w.Body.String()) }
+    }
+    if w := sendJSON(r, http.MethodPost, "/v1/jobs", job(map[string]any{"matrix": map[string]any{"lr": []string{"1"}, "file": []string{"a"}}, "timeoutSeconds": 3600})); w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("over max timeout status=%d body=%s", w.Code, w.Body.String())
+    }
+
+    // 父作業彙整子作業的狀態與 exit code
+    mock.ExpectQuery(regexp.QuoteMeta(`FROM jobs WHERE id=$1`)).WithArgs("m1").
+        WillReturnRows(sqlmock.NewRows(jobCols).AddRow("m1", "", "python:3.11", `["train","${matrix.lr}"]`, "/srv/data/u1", "/workspace", `{"axes":{"lr":["0.1","0.01","0.001"]},"maxParallel":2,"job":{}}`, "matrix", "running", nil, nil, nil, 100, 100, nil))
+    mock.ExpectQuery(regexp.QuoteMeta(`FROM job_attempts WHERE job_id=$1`)).WithArgs("m1").WillReturnRows(sqlmock.NewRows([]string{"id", "job_id", "attempt", "status", "exit_code", "oom_killed", "logs", "error", "started_at", "finished_at"}))
+    mock.ExpectQuery(regexp.QuoteMeta(`SELECT matrix_index, matrix_values, id, status, exit_code, error FROM jobs WHERE parent_id=$1 ORDER BY matrix_index`)).WithArgs("m1").
+        WillReturnRows(sqlmock.NewRows([]string{"matrix_index", "matrix_values", "id", "status", "exit_code", "error"}).
+            AddRow(0, `{"lr":"0.1"}`, "c0", "succeeded", 0, nil).
+            AddRow(1, `{"lr":"0.01"}`, "c1", "failed", 3, nil).
+            AddRow(2, `{"lr":"0.001"}`, "c2", "running", nil, nil))
+    w = sendJSON(r, http.MethodGet, "/v1/jobs/m1", nil)
+    var view struct {
+        Mode   string `json:"mode"`
+        Matrix struct {
+            MaxParallel int              `json:"maxParallel"`
+            Total       int              `json:"total"`
+            Counts      map[string]int   `json:"counts"`
+            ExitCodes   map[string]int   `json:"exitCodes"`
+            Jobs        []struct {
+                ID     string            `json:"id"`
+                Values map[string]string `json:"values"`
+            } `json:"jobs"`
+        } `json:"matrix"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &view)
+    m := view.Matrix
+    if w.Code != http.StatusOK || view.Mode != "matrix" || m.MaxParallel != 2 || m.Total != 3 || m.Counts["running"] != 1 || m.Counts["failed"] != 1 {
+        t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+    }
+    if len(m.ExitCodes) != 2 || m.ExitCodes["3"] != 1 || m.Jobs[2].ID != "c2" || m.Jobs[2].Values["lr"] != "0.001" { t.Fatalf("unexpected summary %s", w.Body.String()) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
+
+func TestJobs_DetectDryRun(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    svc := containers.NewServiceWith(containers.NewMockProvider(), nil)
+    svc.SetJobLimits(containers.JobLimits{DefaultTimeout: time.Minute})
+    handlers.Svc = svc
+    handlers.Detectors = detect.Default()
+
+    r := gin.New()
+    r.POST("/v1/jobs/detect", handlers.DetectJob)
+    hostDir := t.TempDir()
+    for _, name := range []string{"package.json", "Makefile"} {
+        if err := os.WriteFile(filepath.Join(hostDir, name), []byte("{}"), 0o644); err != nil { t.Fatal(err) }
+    }
+
+    // 未指定 image 與 cmd：回報選用的規則與所有符合的規則，不建立作業
+    w := sendJSON(r, http.MethodPost, "/v1/jobs/detect", map[string]any{"hostDir": hostDir, "containerDir": "/workspace"})
+    if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var resp struct {
+        Image      string   `json:"image"`
+        Cmd        []string `json:"cmd"`
+        Network    string   `json:"network"`
+        Timeout    int64    `json:"timeoutSeconds"`
+        Detector   *struct{ Detector, File string } `json:"detector"`
+        Candidates []struct{ Detector string } `json:"candidates"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &resp)
+    if resp.Image != "node:20-alpine" || len(resp.Cmd) != 3 || resp.Cmd[2] != "cd /workspace && npm install && npm start" { t.Fatalf("unexpected body %s", w.Body.String()) }
+    if resp.Network != "none" || resp.Timeout != 60 { t.Fatalf("defaults not applied: %s", w.Body.String()) }
+    if resp.Detector == nil || resp.Detector.Detector != "node" || resp.Detector.File != "package.json" { t.Fatalf("detector %s", w.Body.String()) }
+    if len(resp.Candidates) != 2 || resp.Candidates[1].Detector != "make" { t.Fatalf("candidates %s", w.Body.String()) }
+
+    // 請求指定 image 與 cmd 時不使用偵測結果
+    w = sendJSON(r, http.MethodPost, "/v1/jobs/detect", map[string]any{"hostDir": hostDir, "containerDir": "/workspace", "image": "alpine", "cmd": []string{"true"}})
+    resp.Detector = nil
+    _ = json.Unmarshal(w.Body.Bytes(), &resp)
+    if w.Code != http.StatusOK || resp.Image != "alpine" || resp.Detector != nil { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    // 空白的 image 視同未指定，仍回報偵測結果
+    w = sendJSON(r, http.MethodPost, "/v1/jobs/detect", map[string]any{"hostDir": hostDir, "containerDir": "/workspace", "image": "  ", "cmd": []string{"true"}})
+    resp.Detector = nil
+    _ = json.Unmarshal(w.Body.Bytes(), &resp)
+    if w.Code != http.StatusOK || resp.Image != "node:20-alpine" || resp.Detector == nil || resp.Detector.Detector != "node" { t.Fatalf("blank image status=%d body=%s", w.Code, w.Body.String()) }
+
+    // 只有 Dockerfile 時先以上傳資料夾建置映像，映像名稱依使用者與批次產生
+    dataDir := t.TempDir()
+    t.Setenv("DATA_DIR", dataDir)
+    dockerDir := filepath.Join(dataDir, "u1", "batch1")
+    _ = os.MkdirAll(dockerDir, 0o755)
+    _ = os.WriteFile(filepath.Join(dockerDir, "Dockerfile"), []byte("FROM alpine\n"), 0o644)
+    w = sendJSON(r, http.MethodPost, "/v1/jobs/detect", map[string]any{"hostDir": dockerDir, "containerDir": "/workspace"})
+    var built struct {
+        Image string `json:"image"`
+        Build *struct{ ContextDir, Tag string } `json:"build"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &built)
+    if w.Code != http.StatusOK || built.Image != "container-manager/u1:batch1" || built.Build == nil || built.Build.ContextDir != dockerDir {
+        t.Fatalf("dockerfile status=%d body=%s", w.Code, w.Body.String())
+    }
+    // 建置 context 只允許 DATA_DIR 下的上傳資料夾
+    outside := t.TempDir()
+    _ = os.WriteFile(filepath.Join(outside, "Dockerfile"), []byte("FROM alpine\n"), 0o644)
+    if w := sendJSON(r, http.MethodPost, "/v1/jobs/detect", map[string]any{"hostDir": outside, "containerDir": "/workspace"}); w.Code != http.StatusBadRequest {
+        t.Fatalf("outside DATA_DIR status=%d body=%s", w.Code, w.Body.String())
+    }
+    // 與 POST /v1/jobs 相同的參數檢查
+    if w := sendJSON(r, http.MethodPost, "/v1/jobs/detect", map[string]any{"hostDir": hostDir, "containerDir": "/workspace", "timeoutSeconds": -1}); w.Code != http.StatusBadRequest {
+        t.Fatalf("negative timeout status=%d", w.Code)
+    }
+}
+
+func TestJobs_DetectPrefersDockerfile(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    svc := containers.NewServiceWith(containers.NewMockProvider(), nil)
+    svc.SetJobLimits(containers.JobLimits{DefaultTimeout: time.Minute})
+    handlers.Svc = svc
+    handlers.Detectors = detect.Default()
+
+    r := gin.New()
+    r.POST("/v1/jobs/detect", handlers.DetectJob)
+    dataDir := t.TempDir()
+    t.Setenv("DATA_DIR", dataDir)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    dir := filepath.Join(dataDir, "u1", "batch2")
+    _ = os.MkdirAll(dir, 0o755)
+    _ = os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM python:3.12\nCOPY app.py .\nCMD [\"python\", \"app.py\"]\n"), 0o644)
+    _ = os.WriteFile(filepath.Join(dir, "app.py"), []byte("print('hi')\n"), 0o644)
+
+    // 同時有 Dockerfile 與 app.py 時以 Dockerfile 建置，而非猜測 python 映像
+    w := sendJSON(r, http.MethodPost, "/v1/jobs/detect", map[string]any{"hostDir": dir, "containerDir": "/workspace"})
+    var resp struct {
+        Image      string                        `json:"image"`
+        Build      *struct{ ContextDir string }  `json:"build"`
+        Detector   *struct{ Detector string }    `json:"detector"`
+        Candidates []struct{ Detector string }   `json:"candidates"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &resp)
+    if w.Code != http.StatusOK || resp.Build == nil || resp.Build.ContextDir != dir || resp.Image != "container-manager/u1:batch2" {
+        t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+    }
+    if resp.Detector == nil || resp.Detector.Detector != "dockerfile" || len(resp.Candidates) != 2 || resp.Candidates[1].Detector != "python" {
+        t.Fatalf("detector %s", w.Body.String())
+    }
+}
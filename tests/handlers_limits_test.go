@@ -0,0 +1,104 @@
+package tests
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "regexp"
+    "testing"
+    "time"
+
+    sqlmock "github.com/DATA-DOG/go-sqlmock"
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/storage"
+)
+
+func TestCreateContainer_ResourceLimits(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock: %v", err) }
+    prov := containers.NewMockProvider()
+    svc := containers.NewServiceWith(prov, storage.NewContainerRepository(db))
+    svc.SetLimits(containers.Limits{MaxCPUs: 1, MaxMemory: 256 << 20, MaxPids: 100})
+    handlers.Svc = svc
+
+    r := gin.New()
+    r.POST("/v1/containers", handlers.CreateContainer)
+    post := func(payload map[string]any) *httptest.ResponseRecorder {
+        body, _ := json.Marshal(payload)
+        req := httptest.NewRequest(http.MethodPost, "/v1/containers", bytes.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    mock.ExpectExec(regexp.QuoteMeta("INSERT INTO containers")).WillReturnResult(sqlmock.NewResult(1, 1))
+    w := post(map[string]any{
+        "image":     "alpine:3.20",
+        "resources": map[string]any{"memory": 128 << 20, "ulimits": []map[string]any{{"name": "nofile", "soft": 1024, "hard": 2048}}},
+        "security":  map[string]any{"readOnlyRootfs": true, "capDrop": []string{"ALL"}, "noNewPrivileges": true, "user": "1000", "group": "1000"},
+    })
+    if w.Code != http.StatusCreated { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var ctr containers.Container
+    _ = json.Unmarshal(w.Body.Bytes(), &ctr)
+    opts, ok := prov.CreateOptions(ctr.ID)
+    if !ok { t.Fatalf("mock did not record options") }
+    if opts.Resources.Memory != 128<<20 || opts.Resources.CPUQuota != 100000 || opts.Resources.PidsLimit != 100 || len(opts.Resources.Ulimits) != 1 {
+        t.Fatalf("unexpected resources: %+v", opts.Resources)
+    }
+    if !opts.Security.ReadOnlyRootfs || !opts.Security.NoNewPrivileges || opts.Security.User != "1000" || opts.Security.CapDrop[0] != "ALL" {
+        t.Fatalf("unexpected security: %+v", opts.Security)
+    }
+
+    if w := post(map[string]any{"image": "alpine:3.20", "resources": map[string]any{"memory": 1 << 30}}); w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("over limit status=%d body=%s", w.Code, w.Body.String())
+    }
+    if w := post(map[string]any{"image": "alpine:3.20", "resources": map[string]any{"pidsLimit": -5}}); w.Code != http.StatusBadRequest {
+        t.Fatalf("invalid status=%d body=%s", w.Code, w.Body.String())
+    }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
+
+func TestRunJob_TimeoutAndOptions(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    prov := containers.NewMockProvider()
+    prov.SetExecResponse("sleep", containers.MockExecResponse{Stdout: "tick\n", Delay: time.Minute})
+    svc := containers.NewServiceWith(prov, nil)
+    svc.SetJobLimits(containers.JobLimits{MaxTimeout: 30 * time.Second})
+    handlers.Svc = svc
+
+    r := gin.New()
+    r.POST("/v1/jobs", handlers.RunJob)
+    post := func(payload map[string]any) *httptest.ResponseRecorder {
+        payload["image"], payload["hostDir"], payload["containerDir"] = "alpine", t.TempDir(), "/workspace"
+        body, _ := json.Marshal(payload)
+        req := httptest.NewRequest(http.MethodPost, "/v1/jobs", bytes.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    w := post(map[string]any{"cmd": []string{"sleep", "60"}, "timeoutSeconds": 1, "gracePeriodSeconds": 2, "env": map[string]string{"CI": "1"}})
+    if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var res containers.JobResult
+    _ = json.Unmarshal(w.Body.Bytes(), &res)
+    if res.Status != containers.JobTimedOut || res.Logs != "tick\n" { t.Fatalf("unexpected result %+v", res) }
+    job := prov.Jobs()[0]
+    if job.Timeout != time.Second || job.GracePeriod != 2*time.Second || job.Env["CI"] != "1" || job.Network != "" {
+        t.Fatalf("unexpected job options %+v", job)
+    }
+
+    if w := post(map[string]any{"cmd": []string{"true"}, "timeoutSeconds": 3600}); w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("over max timeout status=%d body=%s", w.Code, w.Body.String())
+    }
+    if w := post(map[string]any{"cmd": []string{"true"}, "timeoutSeconds": -1}); w.Code != http.StatusBadRequest {
+        t.Fatalf("negative timeout status=%d body=%s", w.Code, w.Body.String())
+    }
+}
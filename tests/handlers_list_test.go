@@ -0,0 +1,62 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+)
+
+func TestListAndGetContainer_Handler(t *testing.T) {
+    ctx := context.Background()
+    gin.SetMode(gin.TestMode)
+    prov := containers.NewMockProvider()
+    a, _ := prov.Create(ctx, containers.CreateOptions{Name: "web", Image: "nginx:1.27"})
+    b, _ := prov.Create(ctx, containers.CreateOptions{Name: "worker", Image: "alpine:3.20"})
+    _ = prov.Start(ctx, b.ID)
+    handlers.Svc = containers.NewServiceWith(prov, nil)
+
+    r := gin.New()
+    r.GET("/v1/containers", handlers.ListContainers)
+    r.GET("/v1/containers/:id", handlers.GetContainer)
+
+    var page struct {
+        Items []containers.Container `json:"items"`
+        Total int                    `json:"total"`
+    }
+    req := httptest.NewRequest(http.MethodGet, "/v1/containers?status=running", nil)
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    _ = json.Unmarshal(w.Body.Bytes(), &page)
+    if page.Total != 1 || len(page.Items) != 1 || page.Items[0].ID != b.ID { t.Fatalf("unexpected page: %+v", page) }
+
+    req = httptest.NewRequest(http.MethodGet, "/v1/containers?limit=1&offset=1", nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    _ = json.Unmarshal(w.Body.Bytes(), &page)
+    if page.Total != 2 || len(page.Items) != 1 { t.Fatalf("unexpected page: %+v", page) }
+
+    req = httptest.NewRequest(http.MethodGet, "/v1/containers?limit=0", nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusBadRequest { t.Fatalf("limit=0 status=%d", w.Code) }
+
+    req = httptest.NewRequest(http.MethodGet, "/v1/containers/"+a.ID, nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    var got containers.Container
+    _ = json.Unmarshal(w.Body.Bytes(), &got)
+    if w.Code != http.StatusOK || got.Name != "web" || got.Status != "created" { t.Fatalf("inspect status=%d body=%s", w.Code, w.Body.String()) }
+
+    req = httptest.NewRequest(http.MethodGet, "/v1/containers/missing", nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusNotFound { t.Fatalf("missing status=%d", w.Code) }
+}
@@ -0,0 +1,63 @@
+package tests
+
+import (
+    "context"
+    "bufio"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+)
+
+func TestContainerLogs_Handler(t *testing.T) {
+    ctx := context.Background()
+    gin.SetMode(gin.TestMode)
+    prov := containers.NewMockProvider()
+    ctr, _ := prov.Create(ctx, containers.CreateOptions{Name: "demo", Image: "alpine:3.20"})
+    prov.AppendLog(ctr.ID, "stdout", "one")
+    prov.AppendLog(ctr.ID, "stderr", "two")
+    prov.AppendLog(ctr.ID, "stdout", "three")
+    handlers.Svc = containers.NewServiceWith(prov, nil)
+
+    r := gin.New()
+    r.GET("/v1/containers/:id/logs", handlers.ContainerLogs)
+
+    // NDJSON, 只取 stdout 最後一行
+    req := httptest.NewRequest(http.MethodGet, "/v1/containers/"+ctr.ID+"/logs?stderr=false&tail=1", nil)
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusOK { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var lines []containers.LogLine
+    sc := bufio.NewScanner(strings.NewReader(w.Body.String()))
+    for sc.Scan() {
+        var l containers.LogLine
+        if err := json.Unmarshal(sc.Bytes(), &l); err != nil { t.Fatalf("decode %q: %v", sc.Text(), err) }
+        lines = append(lines, l)
+    }
+    if len(lines) != 1 || lines[0].Line != "three" || lines[0].Stream != "stdout" { t.Fatalf("unexpected lines: %+v", lines) }
+
+    // SSE：事件名稱為來源
+    req = httptest.NewRequest(http.MethodGet, "/v1/containers/"+ctr.ID+"/logs", nil)
+    req.Header.Set("Accept", "text/event-stream")
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    body := w.Body.String()
+    if !strings.Contains(w.Header().Get("Content-Type"), "text/event-stream") { t.Fatalf("content-type=%s", w.Header().Get("Content-Type")) }
+    if strings.Count(body, "event:stdout") != 2 || strings.Count(body, "event:stderr") != 1 { t.Fatalf("unexpected sse body: %s", body) }
+
+    req = httptest.NewRequest(http.MethodGet, "/v1/containers/missing/logs", nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusNotFound { t.Fatalf("missing status=%d", w.Code) }
+
+    req = httptest.NewRequest(http.MethodGet, "/v1/containers/"+ctr.ID+"/logs?tail=-3", nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusBadRequest { t.Fatalf("bad tail status=%d", w.Code) }
+}
@@ -0,0 +1,167 @@
+package tests
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "regexp"
+    "testing"
+    "time"
+
+    sqlmock "github.com/DATA-DOG/go-sqlmock"
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/pipelines"
+    "container-manager/internal/storage"
+)
+
+var pipelineCols = []string{"id", "name", "submitter", "host_dir", "container_dir", "on_failure", "status", "error", "created_at", "started_at", "finished_at"}
+
+// usePipelinesDB 讓 handlers.Pipelines 使用 sqlmock 連線，步驟交由 run 執行。
+func usePipelinesDB(t *testing.T, run pipelines.RunFunc) sqlmock.Sqlmock {
+    t.Helper()
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock: %v", err) }
+    handlers.Pipelines = pipelines.NewRunner(storage.NewPipelineRepository(db), run)
+    return mock
+}
+
+func pipelineRouter() *gin.Engine {
+    r := gin.New()
+    r.POST("/v1/pipelines", handlers.RunPipeline)
+    r.GET("/v1/pipelines", handlers.ListPipelines)
+    r.GET("/v1/pipelines/:id", handlers.GetPipeline)
+    return r
+}
+
+func TestPipelines_RunSharesWorkspace(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    handlers.Svc = containers.NewServiceWith(containers.NewMockProvider(), nil)
+    started := make(chan containers.JobOptions, 1)
+    mock := usePipelinesDB(t, func(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error) {
+        started <- opts
+        <-ctx.Done()
+        return "j1", containers.JobResult{}, ctx.Err()
+    })
+    r := pipelineRouter()
+
+    mock.ExpectBegin()
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO pipeline_runs(id, name, submitter, host_dir, container_dir, on_failure, status, created_at, started_at, heartbeat_at)`)).
+        WithArgs(sqlmock.AnyArg(), "ci", "", "/srv/data/u1", "/workspace", "fail-fast", "running", sqlmock.AnyArg()).
+        WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO pipeline_steps(run_id, name, position, depends_on_json, options_json, status)`)).
+        WithArgs(sqlmock.AnyArg(), "build", 0, "null", sqlmock.AnyArg(), "pending").WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO pipeline_steps(run_id, name, position, depends_on_json, options_json, status)`)).
+        WithArgs(sqlmock.AnyArg(), "test", 1, `["build"]`, sqlmock.AnyArg(), "pending").WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectCommit()
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE pipeline_steps SET status=$1, started_at=$2`)).WithArgs("running", sqlmock.AnyArg(), sqlmock.AnyArg(), "build").WillReturnResult(sqlmock.NewResult(0, 1))
+    w := sendJSON(r, http.MethodPost, "/v1/pipelines", map[string]any{
+        "name": "ci", "hostDir": "/srv/data/u1", "containerDir": "/workspace",
+        "steps": []map[string]any{
+            {"name": "build", "image": "golang:1.24", "cmd": []string{"go", "build", "./..."}},
+            {"name": "test", "dependsOn": []string{"build"}, "image": "golang:1.24", "cmd": []string{"go", "test", "./..."}},
+        },
+    })
+    if w.Code != http.StatusAccepted { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+
+    // 每個步驟掛載管線的資料夾
+    opts := <-started
+    if opts.HostDir != "/srv/data/u1" || opts.ContainerDir != "/workspace" || opts.Cmd[1] != "build" {
+        t.Fatalf("unexpected step options %+v", opts)
+    }
+    // 服務關閉：執行中的步驟取消、其餘略過，管線記錄為中斷
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE pipeline_steps SET status=$1, job_id=$2`)).WithArgs("cancelled", "j1", int64(-1), "cancelled: server stopped", sqlmock.AnyArg(), sqlmock.AnyArg(), "build").WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE pipeline_steps SET status=$1, job_id=$2`)).WithArgs("skipped", nil, int64(0), "skipped: server stopped", sqlmock.AnyArg(), sqlmock.AnyArg(), "test").WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE pipeline_runs SET status=$1, error=$2, finished_at=$3 WHERE id=$4`)).WithArgs("failed", "interrupted: server stopped", sqlmock.AnyArg(), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+    handlers.Pipelines.Close()
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
+
+func TestPipelines_RejectsInvalid(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    svc := containers.NewServiceWith(containers.NewMockProvider(), nil)
+    svc.SetJobLimits(containers.JobLimits{MaxTimeout: time.Minute})
+    handlers.Svc = svc
+    mock := usePipelinesDB(t, nil)
+    r := pipelineRouter()
+    step := func(name string, deps ...string) map[string]any {
+        return map[string]any{"name": name, "dependsOn": deps, "image": "alpine", "cmd": []string{"true"}}
+    }
+    base := func(steps ...map[string]any) map[string]any {
+        return map[string]any{"hostDir": "/srv/data/u1", "containerDir": "/workspace", "steps": steps}
+    }
+
+    bad := []map[string]any{
+        base(),
+        base(map[string]any{"image": "alpine", "cmd": []string{"true"}}),
+        base(step("a"), step("a")),
+        base(step("a", "missing")),
+        base(step("a", "b"), step("b", "a")),
+        {"hostDir": "/srv/data/u1", "containerDir": "/workspace", "onFailure": "ignore", "steps": []map[string]any{step("a")}},
+    }
+    for _, b := range bad {
+        if w := sendJSON(r, http.MethodPost, "/v1/pipelines", b); w.Code != http.StatusBadRequest { t.Fatalf("payload %v status=%d body=%s", b, w.Code, w.Body.String()) }
+    }
+    slow := step("a")
+    slow["timeoutSeconds"] = 3600
+    if w := sendJSON(r, http.MethodPost, "/v1/pipelines", base(slow)); w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("over max timeout status=%d body=%s", w.Code, w.Body.String())
+    }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
+
+func TestPipelines_GetAndList(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    mock := usePipelinesDB(t, nil)
+    r := pipelineRouter()
+
+    mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name, submitter, host_dir, container_dir, on_failure, status, error, created_at, started_at, finished_at FROM pipeline_runs WHERE id=$1`)).WithArgs("p1").
+        WillReturnRows(sqlmock.NewRows(pipelineCols).AddRow("p1", "ci", "alice", "/srv/data/u1", "/workspace", "continue", "failed", `step "test" failed`, 100, 100, 160))
+    mock.ExpectQuery(regexp.QuoteMeta(`FROM pipeline_steps WHERE run_id=$1 ORDER BY position`)).WithArgs("p1").
+        WillReturnRows(sqlmock.NewRows([]string{"name", "depends_on_json", "options_json", "status", "job_id", "exit_code", "error", "started_at", "finished_at"}).
+            AddRow("build", "null", `{"image":"golang:1.24","cmd":["go","build"]}`, "succeeded", "j1", 0, "", 100, 130).
+            AddRow("test", `["build"]`, `{"image":"golang:1.24","cmd":["go","test"]}`, "failed", "j2", 1, "", 130, 160).
+            AddRow("report", `["test"]`, `{"image":"alpine"}`, "skipped", nil, 0, `dependency "test" did not succeed`, nil, 160))
+    w := sendJSON(r, http.MethodGet, "/v1/pipelines/p1", nil)
+    var view struct {
+        Status string `json:"status"`
+        Steps  []struct {
+            Name      string   `json:"name"`
+            DependsOn []string `json:"dependsOn"`
+            Status    string   `json:"status"`
+            JobID     string   `json:"jobId"`
+            ExitCode  int64    `json:"exitCode"`
+        } `json:"steps"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &view)
+    if w.Code != http.StatusOK || view.Status != "failed" || len(view.Steps) != 3 { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    if view.Steps[1].JobID != "j2" || view.Steps[1].ExitCode != 1 || view.Steps[2].Status != "skipped" || view.Steps[2].DependsOn[0] != "test" {
+        t.Fatalf("unexpected steps %s", w.Body.String())
+    }
+
+    mock.ExpectQuery(regexp.QuoteMeta(`FROM pipeline_runs WHERE id=$1`)).WithArgs("nope").WillReturnRows(sqlmock.NewRows(pipelineCols))
+    if w := sendJSON(r, http.MethodGet, "/v1/pipelines/nope", nil); w.Code != http.StatusNotFound { t.Fatalf("missing status=%d", w.Code) }
+
+    mock.ExpectQuery(regexp.QuoteMeta(`FROM pipeline_runs WHERE status=$1 ORDER BY created_at DESC, id DESC LIMIT $2`)).WithArgs("running", 2).
+        WillReturnRows(sqlmock.NewRows(pipelineCols).
+            AddRow("p3", "", "bob", "/d", "/w", "fail-fast", "running", nil, 300, 300, nil).
+            AddRow("p2", "", "bob", "/d", "/w", "fail-fast", "running", nil, 200, 200, nil))
+    w = sendJSON(r, http.MethodGet, "/v1/pipelines?status=running&limit=1", nil)
+    var list struct {
+        Items []struct {
+            ID    string `json:"id"`
+            Steps []any  `json:"steps"`
+        } `json:"items"`
+        NextCursor string `json:"nextCursor"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &list)
+    if w.Code != http.StatusOK || len(list.Items) != 1 || list.Items[0].ID != "p3" || list.Items[0].Steps != nil || list.NextCursor == "" {
+        t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+    }
+    if w := sendJSON(r, http.MethodGet, "/v1/pipelines?cursor=!!", nil); w.Code != http.StatusBadRequest { t.Fatalf("bad cursor status=%d", w.Code) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
@@ -0,0 +1,86 @@
+package tests
+
+import (
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "regexp"
+    "testing"
+
+    sqlmock "github.com/DATA-DOG/go-sqlmock"
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/storage"
+)
+
+func TestCreateContainer_PortsAndNetwork(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock: %v", err) }
+    prov := containers.NewMockProvider()
+    handlers.Svc = containers.NewServiceWith(prov, storage.NewContainerRepository(db))
+
+    r := gin.New()
+    r.POST("/v1/containers", handlers.CreateContainer)
+    r.POST("/v1/containers/:id/start", handlers.StartContainer)
+    r.GET("/v1/containers/:id", handlers.GetContainer)
+    do := func(method, path string, payload any) *httptest.ResponseRecorder {
+        var body []byte
+        if payload != nil { body, _ = json.Marshal(payload) }
+        req := httptest.NewRequest(method, path, bytes.NewReader(body))
+        req.Header.Set("Content-Type", "application/json")
+        w := httptest.NewRecorder()
+        r.ServeHTTP(w, req)
+        return w
+    }
+
+    mock.ExpectExec(regexp.QuoteMeta("INSERT INTO containers")).WillReturnResult(sqlmock.NewResult(1, 1))
+    w := do(http.MethodPost, "/v1/containers", map[string]any{
+        "image": "nginx:1.27",
+        "ports": []map[string]any{
+            {"containerPort": 80},
+            {"containerPort": 53, "hostPort": 5353, "protocol": "udp", "hostIp": "127.0.0.1"},
+        },
+        "network": map[string]any{"network": "apps", "aliases": []string{"web"}, "dns": []string{"1.1.1.1"}, "dnsSearch": []string{"svc.local"}},
+    })
+    if w.Code != http.StatusCreated { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var ctr containers.Container
+    _ = json.Unmarshal(w.Body.Bytes(), &ctr)
+    opts, _ := prov.CreateOptions(ctr.ID)
+    if len(opts.Ports) != 2 || opts.Network.Network != "apps" || opts.Network.Aliases[0] != "web" || opts.Network.DNS[0] != "1.1.1.1" {
+        t.Fatalf("unexpected options: %+v", opts)
+    }
+
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE containers")).WillReturnResult(sqlmock.NewResult(0, 1))
+    if w := do(http.MethodPost, "/v1/containers/"+ctr.ID+"/start", nil); w.Code != http.StatusNoContent { t.Fatalf("start status=%d", w.Code) }
+
+    w = do(http.MethodGet, "/v1/containers/"+ctr.ID, nil)
+    if w.Code != http.StatusOK { t.Fatalf("inspect status=%d", w.Code) }
+    var got containers.Container
+    _ = json.Unmarshal(w.Body.Bytes(), &got)
+    if len(got.Ports) != 2 { t.Fatalf("expected 2 ports, got %+v", got.Ports) }
+    if p := got.Ports[0]; p.ContainerPort != 53 || p.HostPort != 5353 || p.Protocol != "udp" || p.HostIP != "127.0.0.1" {
+        t.Fatalf("unexpected fixed port: %+v", p)
+    }
+    if p := got.Ports[1]; p.ContainerPort != 80 || p.HostPort == 0 || p.Protocol != "tcp" {
+        t.Fatalf("expected auto-assigned port: %+v", p)
+    }
+
+    bad := []map[string]any{
+        {"image": "nginx", "ports": []map[string]any{{"containerPort": 70000}}},
+        {"image": "nginx", "ports": []map[string]any{{"containerPort": 80, "protocol": "icmp"}}},
+        {"image": "nginx", "ports": []map[string]any{{"containerPort": 80, "hostPort": 8080}, {"containerPort": 81, "hostPort": 8080}}},
+        {"image": "nginx", "ports": []map[string]any{{"containerPort": 80}}, "network": map[string]any{"network": "host"}},
+        {"image": "nginx", "network": map[string]any{"aliases": []string{"web"}}},
+        {"image": "nginx", "network": map[string]any{"dns": []string{"not-an-ip"}}},
+    }
+    for i, payload := range bad {
+        if w := do(http.MethodPost, "/v1/containers", payload); w.Code != http.StatusBadRequest {
+            t.Fatalf("case %d: status=%d body=%s", i, w.Code, w.Body.String())
+        }
+    }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "regexp"
+    "testing"
+    "time"
+
+    sqlmock "github.com/DATA-DOG/go-sqlmock"
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/schedules"
+    "container-manager/internal/storage"
+)
+
+var scheduleCols = []string{"id", "name", "cron_expr", "timezone", "options_json", "overlap", "enabled", "submitter", "created_at", "updated_at", "next_run_at", "last_run_at"}
+
+const scheduleSelectSQL = `SELECT id, name, cron_expr, timezone, options_json, overlap, enabled, submitter, created_at, updated_at, next_run_at, last_run_at FROM schedules WHERE id=$1`
+
+// useSchedulesDB 讓 handlers.Schedules 使用 sqlmock 連線；測試不啟動排程迴圈。
+func useSchedulesDB(t *testing.T) sqlmock.Sqlmock {
+    t.Helper()
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock: %v", err) }
+    handlers.Schedules = schedules.NewScheduler(storage.NewScheduleRepository(db), func(ctx context.Context, rec storage.JobRecord, opts containers.JobOptions) (string, containers.JobResult, error) {
+        return "", containers.JobResult{}, containers.ErrNotSupported
+    })
+    return mock
+}
+
+func scheduleRouter() *gin.Engine {
+    r := gin.New()
+    r.POST("/v1/schedules", handlers.CreateSchedule)
+    r.GET("/v1/schedules/:id", handlers.GetSchedule)
+    r.PUT("/v1/schedules/:id", handlers.UpdateSchedule)
+    r.DELETE("/v1/schedules/:id", handlers.DeleteSchedule)
+    r.GET("/v1/schedules/:id/runs", handlers.ListScheduleRuns)
+    return r
+}
+
+func sendJSON(r *gin.Engine, method, url string, payload any) *httptest.ResponseRecorder {
+    body, _ := json.Marshal(payload)
+    req := httptest.NewRequest(method, url, bytes.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    return w
+}
+
+func TestSchedules_CreateValidatesAndStoresTemplate(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    mock := useSchedulesDB(t)
+    svc := containers.NewServiceWith(containers.NewMockProvider(), nil)
+    svc.SetJobLimits(containers.JobLimits{MaxTimeout: time.Minute})
+    handlers.Svc = svc
+    r := scheduleRouter()
+    job := map[string]any{"image": "alpine", "hostDir": "/srv/data/u1", "containerDir": "/workspace", "cmd": []string{"make"}, "timeoutSeconds": 30}
+
+    // 範本不套用伺服器預設值，觸發時才套用
+    const template = `{"image":"alpine","hostDir":"/srv/data/u1","containerDir":"/workspace","cmd":["make"],"env":null,"network":"","resources":{},"security":{},"timeout":30000000000,"gracePeriod":0}`
+    mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schedules(id, name, cron_expr, timezone, options_json, overlap, enabled, submitter, created_at, updated_at, next_run_at)`)).
+        WithArgs(sqlmock.AnyArg(), "nightly", "0 2 * * *", "Asia/Taipei", template, "queue", true, "", sqlmock.AnyArg(), sqlmock.AnyArg()).
+        WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectQuery(regexp.QuoteMeta(scheduleSelectSQL)).
+        WillReturnRows(sqlmock.NewRows(scheduleCols).AddRow("s1", "nightly", "0 2 * * *", "Asia/Taipei", template, "queue", true, "", 100, 100, 1800, nil))
+    w := sendJSON(r, http.MethodPost, "/v1/schedules", map[string]any{"name": "nightly", "cron": "0 2 * * *", "timezone": "Asia/Taipei", "overlap": "queue", "job": job})
+    if w.Code != http.StatusCreated { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var view struct {
+        ID        string `json:"id"`
+        NextRunAt int64  `json:"nextRunAt"`
+        Job       struct {
+            Cmd     []string `json:"cmd"`
+            Timeout int      `json:"timeoutSeconds"`
+        } `json:"job"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &view)
+    if view.ID != "s1" || view.NextRunAt != 1800 || view.Job.Cmd[0] != "make" || view.Job.Timeout != 30 {
+        t.Fatalf("unexpected body %s", w.Body.String())
+    }
+
+    // 表示式、時區、策略與作業參數錯誤皆在寫入前回報
+    bad := []map[string]any{
+        {"cron": "every day", "job": job},
+        {"cron": "@daily", "timezone": "Nowhere/City", "job": job},
+        {"cron": "@daily", "overlap": "parallel", "job": job},
+        {"cron": "@daily", "job": map[string]any{"image": "alpine", "containerDir": "/workspace"}},
+    }
+    for _, b := range bad {
+        if w := sendJSON(r, http.MethodPost, "/v1/schedules", b); w.Code != http.StatusBadRequest { t.Fatalf("payload %v status=%d body=%s", b, w.Code, w.Body.String()) }
+    }
+    job["timeoutSeconds"] = 3600
+    if w := sendJSON(r, http.MethodPost, "/v1/schedules", map[string]any{"cron": "@daily", "job": job}); w.Code != http.StatusUnprocessableEntity {
+        t.Fatalf("over max timeout status=%d body=%s", w.Code, w.Body.String())
+    }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
+
+func TestSchedules_UpdateDeleteAndRuns(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    t.Setenv("HOST_DATA_DIR", t.TempDir())
+    mock := useSchedulesDB(t)
+    handlers.Svc = containers.NewServiceWith(containers.NewMockProvider(), nil)
+    r := scheduleRouter()
+    job := map[string]any{"image": "alpine", "hostDir": "/srv/data/u1", "containerDir": "/workspace", "cmd": []string{"true"}}
+
+    mock.ExpectExec(regexp.QuoteMeta(`UPDATE schedules SET name=$1, cron_expr=$2, timezone=$3, options_json=$4, overlap=$5, enabled=$6, updated_at=$7, next_run_at=$8 WHERE id=$9`)).
+        WithArgs("", "*/5 * * * *", "UTC", sqlmock.AnyArg(), "skip", false, sqlmock.AnyArg(), sqlmock.AnyArg(), "nope").
+        WillReturnResult(sqlmock.NewResult(0, 0))
+    if w := sendJSON(r, http.MethodPut, "/v1/schedules/nope", map[string]any{"cron": "*/5 * * * *", "enabled": false, "job": job}); w.Code != http.StatusNotFound {
+        t.Fatalf("update missing status=%d body=%s", w.Code, w.Body.String())
+    }
+
+    mock.ExpectQuery(regexp.QuoteMeta(scheduleSelectSQL)).WithArgs("s1").
+        WillReturnRows(sqlmock.NewRows(scheduleCols).AddRow("s1", "", "@hourly", "UTC", `{"image":"alpine"}`, "replace", true, "alice", 100, 100, 3600, 0))
+    mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, schedule_id, job_id, status, error, scheduled_at, started_at, finished_at FROM schedule_runs WHERE schedule_id=$1 ORDER BY scheduled_at DESC, id DESC LIMIT $2`)).
+        WithArgs("s1", 50).
+        WillReturnRows(sqlmock.NewRows([]string{"id", "schedule_id", "job_id", "status", "error", "scheduled_at", "started_at", "finished_at"}).
+            AddRow("r2", "s1", nil, "skipped", "previous run still in progress", 60, nil, 61).
+            AddRow("r1", "s1", "j1", "succeeded", nil, 0, 1, 30))
+    w := sendJSON(r, http.MethodGet, "/v1/schedules/s1/runs", nil)
+    var runs struct {
+        Items []struct {
+            ID     string `json:"id"`
+            JobID  string `json:"jobId"`
+            Status string `json:"status"`
+        } `json:"items"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &runs)
+    if w.Code != http.StatusOK || len(runs.Items) != 2 || runs.Items[0].Status != "skipped" || runs.Items[1].JobID != "j1" {
+        t.Fatalf("status=%d body=%s", w.Code, w.Body.String())
+    }
+
+    mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM schedules WHERE id=$1`)).WithArgs("s1").WillReturnResult(sqlmock.NewResult(0, 1))
+    if w := sendJSON(r, http.MethodDelete, "/v1/schedules/s1", nil); w.Code != http.StatusNoContent { t.Fatalf("delete status=%d", w.Code) }
+    mock.ExpectQuery(regexp.QuoteMeta(scheduleSelectSQL)).WithArgs("s1").WillReturnRows(sqlmock.NewRows(scheduleCols))
+    if w := sendJSON(r, http.MethodGet, "/v1/schedules/s1", nil); w.Code != http.StatusNotFound { t.Fatalf("deleted schedule status=%d", w.Code) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+    "context"
+    "bytes"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "regexp"
+    "testing"
+
+    sqlmock "github.com/DATA-DOG/go-sqlmock"
+    "github.com/gin-gonic/gin"
+
+    "container-manager/internal/api/handlers"
+    "container-manager/internal/containers"
+    "container-manager/internal/storage"
+    "container-manager/internal/tasks"
+)
+
+func TestAsyncExecAndTasks_Handler(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    db, mock, err := sqlmock.New()
+    if err != nil { t.Fatalf("sqlmock: %v", err) }
+    handlers.Svc = containers.NewServiceWith(execProviderMock{}, nil)
+    handlers.Tasks = tasks.NewManager(storage.NewTaskRepository(db), func(ctx context.Context, id string, cmd []string) (containers.ExecResult, error) {
+        return handlers.Svc.Exec(ctx, id, cmd)
+    }, 1, 1)
+
+    r := gin.New()
+    r.POST("/v1/containers/:id/exec", handlers.ExecInContainer)
+    r.GET("/v1/tasks/:id", handlers.GetTask)
+    r.POST("/v1/tasks/:id/cancel", handlers.CancelTask)
+
+    mock.ExpectExec(regexp.QuoteMeta("INSERT INTO container_tasks")).WillReturnResult(sqlmock.NewResult(1, 1))
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE container_tasks SET status=$1 WHERE id=$2 AND status=$3")).WillReturnResult(sqlmock.NewResult(0, 1))
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE container_tasks SET status=$1, exit_code=$2")).WillReturnResult(sqlmock.NewResult(0, 1))
+
+    body, _ := json.Marshal(map[string]any{"cmd": []string{"echo", "hi"}})
+    req := httptest.NewRequest(http.MethodPost, "/v1/containers/cid/exec?async=true", bytes.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    w := httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusAccepted { t.Fatalf("status=%d body=%s", w.Code, w.Body.String()) }
+    var accepted struct{ TaskID string `json:"taskId"` }
+    _ = json.Unmarshal(w.Body.Bytes(), &accepted)
+    if accepted.TaskID == "" { t.Fatalf("missing taskId: %s", w.Body.String()) }
+    handlers.Tasks.Close()
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+
+    cols := []string{"id", "container_id", "cmd_json", "status", "exit_code", "logs", "stdout", "stderr", "created_at", "finished_at"}
+    mock.ExpectQuery(regexp.QuoteMeta("FROM container_tasks WHERE id=$1")).WithArgs(accepted.TaskID).
+        WillReturnRows(sqlmock.NewRows(cols).AddRow(accepted.TaskID, "cid", `["echo","hi"]`, "succeeded", 0, "okwarn", "ok", "warn", 100, 101))
+    req = httptest.NewRequest(http.MethodGet, "/v1/tasks/"+accepted.TaskID, nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    var task struct {
+        Status string   `json:"status"`
+        Cmd    []string `json:"cmd"`
+        Stdout string   `json:"stdout"`
+    }
+    _ = json.Unmarshal(w.Body.Bytes(), &task)
+    if w.Code != http.StatusOK || task.Status != "succeeded" || task.Stdout != "ok" || len(task.Cmd) != 2 { t.Fatalf("get status=%d body=%s", w.Code, w.Body.String()) }
+
+    // 已結束的任務無法取消
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE container_tasks SET status=$1, finished_at=$2")).WillReturnResult(sqlmock.NewResult(0, 0))
+    mock.ExpectQuery(regexp.QuoteMeta("FROM container_tasks WHERE id=$1")).
+        WillReturnRows(sqlmock.NewRows(cols).AddRow(accepted.TaskID, "cid", `["echo","hi"]`, "succeeded", 0, "", "", "", 100, 101))
+    req = httptest.NewRequest(http.MethodPost, "/v1/tasks/"+accepted.TaskID+"/cancel", nil)
+    w = httptest.NewRecorder()
+    r.ServeHTTP(w, req)
+    if w.Code != http.StatusConflict { t.Fatalf("cancel status=%d body=%s", w.Code, w.Body.String()) }
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
@@ -1,18 +1,20 @@
 package tests
 
 import (
+    "context"
     "testing"
 
     "container-manager/internal/containers"
 )
 
 type execMock struct{ containers.Provider }
-func (e execMock) Exec(id string, cmd []string) (int, string, error) { return 0, "ok", nil }
+func (e execMock) Exec(ctx context.Context, id string, cmd []string) (containers.ExecResult, error) { return containers.ExecResult{Stdout: "ok"}, nil }
 
 func TestService_Exec(t *testing.T) {
+    ctx := context.Background()
     s := containers.NewServiceWith(execMock{}, nil)
-    code, logs, err := s.Exec("cid", []string{"echo","hi"})
-    if err != nil || code != 0 || logs != "ok" { t.Fatalf("unexpected: code=%d logs=%s err=%v", code, logs, err) }
+    res, err := s.Exec(ctx, "cid", []string{"echo","hi"})
+    if err != nil || res.ExitCode != 0 || res.Stdout != "ok" { t.Fatalf("unexpected: code=%d stdout=%s err=%v", res.ExitCode, res.Stdout, err) }
 }
 
 
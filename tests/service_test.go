@@ -1,8 +1,11 @@
 package tests
 
 import (
+    "context"
+    "errors"
     "regexp"
     "testing"
+    "time"
 
     "container-manager/internal/containers"
     "container-manager/internal/storage"
@@ -19,12 +22,13 @@ func newRepoWithMock(t *testing.T) (*storage.ContainerRepository, sqlmock.Sqlmoc
 }
 
 func TestService_Create_RecordsToRepo(t *testing.T) {
+    ctx := context.Background()
     repo, mock := newRepoWithMock(t)
     s := containers.NewServiceWith(containers.NewMockProvider(), repo)
 
     mock.ExpectExec(regexp.QuoteMeta("INSERT INTO containers(id,name,image,status,created_at) VALUES($1,$2,$3,$4,$5)")).WithArgs(sqlmock.AnyArg(), "demo", "alpine:3.20", "created", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
 
-    c, err := s.Create(containers.CreateOptions{Name: "demo", Image: "alpine:3.20"})
+    c, err := s.Create(ctx, containers.CreateOptions{Name: "demo", Image: "alpine:3.20"})
     if err != nil { t.Fatalf("create: %v", err) }
     if c.Image != "alpine:3.20" { t.Fatalf("unexpected image: %s", c.Image) }
 
@@ -32,23 +36,68 @@ func TestService_Create_RecordsToRepo(t *testing.T) {
 }
 
 func TestService_Start_Stop_Delete_UpdateRepo(t *testing.T) {
+    ctx := context.Background()
     repo, mock := newRepoWithMock(t)
     s := containers.NewServiceWith(containers.NewMockProvider(), repo)
 
     mock.ExpectExec(regexp.QuoteMeta("INSERT INTO containers(id,name,image,status,created_at) VALUES($1,$2,$3,$4,$5)")).WithArgs(sqlmock.AnyArg(), "demo", "alpine:3.20", "created", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
-    c, err := s.Create(containers.CreateOptions{Name: "demo", Image: "alpine:3.20"})
+    c, err := s.Create(ctx, containers.CreateOptions{Name: "demo", Image: "alpine:3.20"})
     if err != nil { t.Fatalf("create: %v", err) }
 
     mock.ExpectExec(regexp.QuoteMeta("UPDATE containers SET status=$1 WHERE id=$2")).WithArgs("running", c.ID).WillReturnResult(sqlmock.NewResult(1, 1))
-    if err := s.Start(c.ID); err != nil { t.Fatalf("start: %v", err) }
+    if err := s.Start(ctx, c.ID); err != nil { t.Fatalf("start: %v", err) }
 
     mock.ExpectExec(regexp.QuoteMeta("UPDATE containers SET status=$1 WHERE id=$2")).WithArgs("stopped", c.ID).WillReturnResult(sqlmock.NewResult(1, 1))
-    if err := s.Stop(c.ID); err != nil { t.Fatalf("stop: %v", err) }
+    if err := s.Stop(ctx, c.ID); err != nil { t.Fatalf("stop: %v", err) }
 
     mock.ExpectExec(regexp.QuoteMeta("UPDATE containers SET status=$1 WHERE id=$2")).WithArgs("deleted", c.ID).WillReturnResult(sqlmock.NewResult(1, 1))
-    if err := s.Delete(c.ID); err != nil { t.Fatalf("delete: %v", err) }
+    if err := s.Delete(ctx, c.ID); err != nil { t.Fatalf("delete: %v", err) }
 
     if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
 }
 
 
+
+func TestService_IllegalTransitions_DoNotUpdateRepo(t *testing.T) {
+    ctx := context.Background()
+    repo, mock := newRepoWithMock(t)
+    s := containers.NewServiceWith(containers.NewMockProvider(), repo)
+
+    mock.ExpectExec(regexp.QuoteMeta("INSERT INTO containers")).WillReturnResult(sqlmock.NewResult(1, 1))
+    c, err := s.Create(ctx, containers.CreateOptions{Name: "demo", Image: "alpine:3.20"})
+    if err != nil { t.Fatalf("create: %v", err) }
+
+    // 未啟動的容器不能 stop 或 exec，且不寫入資料庫
+    if err := s.Stop(ctx, c.ID); !errors.Is(err, containers.ErrInvalidState) { t.Fatalf("stop created: %v", err) }
+    if _, err := s.Exec(ctx, c.ID, []string{"ls"}); !errors.Is(err, containers.ErrNotRunning) { t.Fatalf("exec created: %v", err) }
+
+    mock.ExpectExec(regexp.QuoteMeta("UPDATE containers SET status=$1 WHERE id=$2")).WithArgs("running", c.ID).WillReturnResult(sqlmock.NewResult(1, 1))
+    if err := s.Start(ctx, c.ID); err != nil { t.Fatalf("start: %v", err) }
+    if err := s.Start(ctx, c.ID); !errors.Is(err, containers.ErrInvalidState) { t.Fatalf("double start: %v", err) }
+
+    if err := mock.ExpectationsWereMet(); err != nil { t.Fatalf("unmet expectations: %v", err) }
+}
+
+func TestService_RunJob_MockProvider(t *testing.T) {
+    ctx := context.Background()
+    prov := containers.NewMockProvider()
+    prov.SetExecResponse("python main.py", containers.MockExecResponse{ExitCode: 0, Stdout: "done\n"})
+    s := containers.NewServiceWith(prov, nil)
+    res, err := s.RunJob(ctx, containers.JobOptions{Image: "python:3.12", HostDir: "/data/u1", ContainerDir: "/workspace", Cmd: []string{"python", "main.py"}})
+    if err != nil || res.ExitCode != 0 || res.Logs != "done\n" || res.Status != containers.JobSucceeded { t.Fatalf("run job: %+v err=%v", res, err) }
+    // 未指定逾時時套用伺服器預設值
+    if jobs := prov.Jobs(); jobs[0].Timeout != containers.DefaultJobTimeout || jobs[0].GracePeriod != containers.DefaultJobGracePeriod { t.Fatalf("defaults not applied: %+v", jobs[0]) }
+}
+
+func TestService_RunJob_TimeoutLimits(t *testing.T) {
+    ctx := context.Background()
+    prov := containers.NewMockProvider()
+    prov.SetExecResponse("sleep", containers.MockExecResponse{Stdout: "partial\n", Delay: time.Minute})
+    s := containers.NewServiceWith(prov, nil)
+    s.SetJobLimits(containers.JobLimits{DefaultTimeout: 20 * time.Millisecond, MaxTimeout: time.Second})
+
+    res, err := s.RunJob(ctx, containers.JobOptions{Image: "alpine", Cmd: []string{"sleep", "60"}})
+    if err != nil || res.Status != containers.JobTimedOut || res.Logs != "partial\n" { t.Fatalf("expected timed out job, got %+v err=%v", res, err) }
+    if _, err := s.RunJob(ctx, containers.JobOptions{Image: "alpine", Timeout: time.Hour}); !errors.Is(err, containers.ErrLimitExceeded) { t.Fatalf("expected ErrLimitExceeded, got %v", err) }
+    if _, err := s.RunJob(ctx, containers.JobOptions{Image: "alpine", Env: map[string]string{"A=B": "x"}}); !errors.Is(err, containers.ErrInvalidOptions) { t.Fatalf("expected ErrInvalidOptions, got %v", err) }
+}